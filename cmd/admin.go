@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"theing/gin-template/common"
+	"theing/gin-template/model"
+)
+
+// runCreateAdmin 创建管理员账号，手机号已注册时更新密码（如有）并授予管理员角色
+func runCreateAdmin(args []string) error {
+	fs, dir := newFlagSet("create-admin", "create-admin [-config 配置目录] -telephone 手机号 [-name 用户名] [-password 密码]")
+	telephone := fs.String("telephone", "", "管理员手机号")
+	name := fs.String("name", "", "用户名，为空时随机生成")
+	password := fs.String("password", "", "登录密码，为空时从标准输入读取，避免密码留在 shell 历史中")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *telephone == "" {
+		return usageError(fs, "请指定 -telephone")
+	}
+	if *password == "" && !isTerminal(os.Stdin) {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("读取标准输入失败: %w", err)
+		}
+		*password = strings.TrimRight(string(data), "\r\n")
+	}
+
+	if _, _, err := loadConfig(*dir); err != nil {
+		return err
+	}
+	db, err := common.ConnectDB()
+	if err != nil {
+		return err
+	}
+	defer common.CloseDB()
+
+	if err := common.SeedRBAC(db); err != nil {
+		return fmt.Errorf("初始化角色权限失败: %w", err)
+	}
+	var count int64
+	if err := db.Model(&model.User{}).Where("telephone = ?", *telephone).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 && *password == "" {
+		return errors.New("新建管理员需要设置密码，请使用 -password 或通过标准输入提供")
+	}
+
+	// 与批量导入使用相同的校验规则和密码策略
+	report, err := common.ImportUsers(context.Background(), [][]string{
+		{"telephone", "name", "password", "roles"},
+		{*telephone, *name, *password, common.RoleAdmin},
+	}, common.UserImportOptions{Update: true, GrantRoles: true})
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return printImportReport(report)
+	}
+	if report.Created > 0 {
+		fmt.Printf("管理员 %s 已创建\n", *telephone)
+	} else {
+		fmt.Printf("已为 %s 授予管理员角色\n", *telephone)
+	}
+	return nil
+}
+
+// isTerminal 是否为交互式终端
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
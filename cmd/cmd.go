@@ -0,0 +1,121 @@
+// Package cmd 实现程序的子命令，main 只负责转发命令行参数
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"theing/gin-template/config"
+)
+
+// command 子命令
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// 参数错误或请求帮助，用法已经输出
+var (
+	errUsage = errors.New("参数错误")
+	errHelp  = errors.New("帮助")
+)
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"serve", "启动 HTTP 服务（默认）", runServe},
+		{"migrate", "数据库迁移：up、down、status", runMigrate},
+		{"seed", "初始化内置角色权限并导入示例数据", runSeed},
+		{"create-admin", "创建或更新管理员账号", runCreateAdmin},
+		{"routes", "列出所有路由", runRoutes},
+		{"config", "配置检查：validate", runConfig},
+		{"keys", "生成密钥，管理配置中的加密值", runKeys},
+	}
+}
+
+// Execute 执行 args 指定的子命令，返回进程退出码。没有参数时启动服务
+func Execute(args []string) int {
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+	name := args[0]
+	if name == "-h" || name == "--help" || name == "help" {
+		printUsage(os.Stdout)
+		return 0
+	}
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if err := c.run(args[1:]); err != nil {
+			if errors.Is(err, errHelp) {
+				return 0
+			}
+			if errors.Is(err, errUsage) {
+				return 2
+			}
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "未知的命令 %q\n\n", name)
+	printUsage(os.Stderr)
+	return 2
+}
+
+func printUsage(w *os.File) {
+	fmt.Fprintf(w, "用法: %s <命令> [参数]\n\n命令:\n", filepath.Base(os.Args[0]))
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w, "\n运行 <命令> -h 查看命令的参数")
+}
+
+// newFlagSet 创建子命令的参数解析器，-config 指定配置目录
+func newFlagSet(name, usage string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	dir := fs.String("config", "config", "配置目录")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: %s\n\n参数:\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs, dir
+}
+
+// parseFlags 解析参数，出错时 flag 包已经输出错误和用法
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errHelp
+		}
+		return errUsage
+	}
+	return nil
+}
+
+// loadConfig 按 APP_ENV 加载 dir 目录下的配置并设为当前配置
+func loadConfig(dir string) (*config.Config, string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	cfg, err := config.Load(abs, os.Getenv("APP_ENV"))
+	if err != nil {
+		return nil, "", err
+	}
+	config.Set(cfg)
+	return cfg, abs, nil
+}
+
+// usageError 输出用法并返回用法错误
+func usageError(fs *flag.FlagSet, format string, args ...interface{}) error {
+	fmt.Fprintf(fs.Output(), strings.TrimRight(format, "\n")+"\n\n", args...)
+	fs.Usage()
+	return errUsage
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"theing/gin-template/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runConfig 配置检查
+func runConfig(args []string) error {
+	fs, dir := newFlagSet("config", "config [-config 配置目录] [-env 运行环境] validate")
+	env := fs.String("env", os.Getenv("APP_ENV"), "运行环境，默认取 APP_ENV")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || fs.Arg(0) != "validate" {
+		return usageError(fs, "请指定 validate")
+	}
+
+	// 与启动时相同的加载和校验，所有问题一次列出
+	cfg, err := config.Load(*dir, *env)
+	if err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(config.Redacted(cfg))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("# 配置有效，运行环境: %s，密钥已隐藏\n%s", cfg.Env, out)
+	return nil
+}
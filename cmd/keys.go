@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"theing/gin-template/config"
+	"theing/gin-template/utils"
+)
+
+const secretsUsage = `用法: keys secrets <命令> [-config 配置目录] [参数]
+
+命令:
+  init               生成主密钥并写入配置目录下的 master.key（或 TPL_MASTER_KEY_FILE）
+  encrypt [明文]      加密一个值，输出可写入配置文件的 ${enc:...}，省略明文时从标准输入读取
+  decrypt <密文>      解密 ${enc:...}
+  rotate-master-key  生成新的主密钥，并把配置目录下所有 .yml 文件中的 ${enc:...} 重新加密
+`
+
+// runKeys 生成 JWT 密钥、数据库密码或非对称签名密钥对，secrets 子命令管理配置中的加密值
+func runKeys(args []string) error {
+	if len(args) > 0 && args[0] == "secrets" {
+		return runSecrets(args[1:])
+	}
+
+	fs, _ := newFlagSet("keys", "keys [-alg RS256|ES256|EdDSA -kid 密钥ID [-out 目录]] | keys secrets <命令>")
+	algorithm := fs.String("alg", "", "生成 JWT 非对称签名密钥对：RS256、ES256 或 EdDSA")
+	kid := fs.String("kid", "", "密钥 ID，用作文件名")
+	outDir := fs.String("out", "config/keys", "密钥对输出目录")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *algorithm != "" {
+		if *kid == "" {
+			return usageError(fs, "生成密钥对时必须指定 -kid")
+		}
+		return generateKeyPair(*algorithm, *kid, *outDir)
+	}
+
+	fmt.Println("=== 安全密钥生成工具 ===")
+
+	// 生成 JWT 密钥
+	jwtKey, err := utils.GenerateJWTKey()
+	if err != nil {
+		return fmt.Errorf("生成 JWT 密钥失败: %w", err)
+	}
+	fmt.Printf("JWT 密钥: %s\n", jwtKey)
+
+	// 生成数据库密码
+	dbPassword, err := utils.GenerateSecureKey(16)
+	if err != nil {
+		return fmt.Errorf("生成数据库密码失败: %w", err)
+	}
+	fmt.Printf("数据库密码: %s\n", dbPassword)
+
+	fmt.Println("\n=== 使用说明 ===")
+	fmt.Println("1. 将生成的 JWT 密钥设置到环境变量 TPL_JWT_SECRET")
+	fmt.Println("2. 将生成的数据库密码设置到相应的环境变量")
+	fmt.Println("3. 确保 .env 文件不要提交到版本控制系统")
+	fmt.Println("4. 使用 -alg RS256|ES256|EdDSA -kid <kid> 生成非对称签名密钥对")
+	fmt.Println("5. 使用 secrets 子命令加密配置中的密钥，运行 keys secrets -h 查看用法")
+	return nil
+}
+
+// generateKeyPair 生成 JWT 非对称签名密钥对并写入 PEM 文件
+func generateKeyPair(algorithm, kid, outDir string) error {
+	privatePEM, publicPEM, err := utils.GenerateSigningKeyPair(algorithm)
+	if err != nil {
+		return fmt.Errorf("生成密钥对失败: %w", err)
+	}
+
+	if err := os.MkdirAll(outDir, 0o700); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	privatePath := filepath.Join(outDir, kid+".pem")
+	publicPath := filepath.Join(outDir, kid+".pub.pem")
+	if err := os.WriteFile(privatePath, privatePEM, 0o600); err != nil {
+		return fmt.Errorf("写入私钥失败: %w", err)
+	}
+	if err := os.WriteFile(publicPath, publicPEM, 0o644); err != nil {
+		return fmt.Errorf("写入公钥失败: %w", err)
+	}
+
+	fmt.Printf("私钥: %s\n公钥: %s\n", privatePath, publicPath)
+	fmt.Println("\n在 config/application.yml 中配置：")
+	fmt.Printf("jwt:\n  algorithm: %s\n  active_kid: %q\n  keys:\n    - kid: %q\n      private_key_file: %q\n      public_key_file: %q\n",
+		algorithm, kid, kid, privatePath, publicPath)
+	return nil
+}
+
+// runSecrets 管理配置中的加密值
+func runSecrets(args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Print(secretsUsage)
+		return nil
+	}
+
+	fs, dir := newFlagSet("keys secrets "+args[0], "keys secrets "+args[0]+" [-config 配置目录] [参数]")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "init":
+		return initMasterKey(*dir)
+	case "encrypt":
+		return encryptSecret(*dir, fs.Arg(0))
+	case "decrypt":
+		if fs.NArg() == 0 {
+			return usageError(fs, "请指定要解密的值")
+		}
+		key, err := config.LoadMasterKey(*dir)
+		if err != nil {
+			return err
+		}
+		plaintext, err := config.DecryptSecret(key, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Println(plaintext)
+		return nil
+	case "rotate-master-key":
+		return rotateMasterKey(*dir)
+	}
+	fmt.Fprint(os.Stderr, secretsUsage)
+	return errUsage
+}
+
+// initMasterKey 生成主密钥文件，已存在时不覆盖
+func initMasterKey(dir string) error {
+	file := config.MasterKeyFile(dir)
+	if _, err := os.Stat(file); err == nil {
+		return fmt.Errorf("主密钥文件 %s 已存在，如需更换请使用 rotate-master-key", file)
+	}
+	key, err := config.GenerateMasterKey()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(file, []byte(key+"\n"), 0o600); err != nil {
+		return fmt.Errorf("写入主密钥失败: %w", err)
+	}
+	fmt.Printf("主密钥已写入 %s，请妥善备份，不要提交到版本控制系统\n", file)
+	return nil
+}
+
+func encryptSecret(dir, plaintext string) error {
+	if plaintext == "" {
+		// 从标准输入读取，避免明文留在 shell 历史中
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("读取标准输入失败: %w", err)
+		}
+		plaintext = strings.TrimRight(string(data), "\r\n")
+	}
+	if plaintext == "" {
+		return errors.New("请指定要加密的值")
+	}
+	key, err := config.LoadMasterKey(dir)
+	if err != nil {
+		return err
+	}
+	encrypted, err := config.EncryptSecret(key, plaintext)
+	if err != nil {
+		return err
+	}
+	fmt.Println(encrypted)
+	return nil
+}
+
+// rotateMasterKey 用新主密钥重新加密配置目录下的所有加密值，旧主密钥文件保留为 .old 备份
+func rotateMasterKey(dir string) error {
+	oldKey, err := config.LoadMasterKey(dir)
+	if err != nil {
+		return err
+	}
+	encoded, err := config.GenerateMasterKey()
+	if err != nil {
+		return err
+	}
+	newKey, _ := config.ParseMasterKey(encoded)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.yml"))
+	if err != nil {
+		return err
+	}
+	// 先全部重新加密，任一文件失败时不修改任何文件
+	rotated := map[string][]byte{}
+	total := 0
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %w", file, err)
+		}
+		result, count, err := config.ReencryptSecrets(content, oldKey, newKey)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if count > 0 {
+			rotated[file] = result
+			total += count
+		}
+	}
+
+	fromEnv := os.Getenv(config.MasterKeyEnv) != ""
+	keyFile := config.MasterKeyFile(dir)
+	if !fromEnv {
+		if err := copyFile(keyFile, keyFile+".old"); err != nil {
+			return fmt.Errorf("备份旧主密钥失败: %w", err)
+		}
+	}
+	for file, content := range rotated {
+		if err := writeFileAtomic(file, content, 0o644); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", file, err)
+		}
+		fmt.Printf("%s: 已重新加密\n", file)
+	}
+	fmt.Printf("共重新加密 %d 个值\n", total)
+
+	if fromEnv {
+		fmt.Printf("主密钥来自环境变量 %s，请更新为新的主密钥:\n%s\n", config.MasterKeyEnv, encoded)
+		return nil
+	}
+	if err := writeFileAtomic(keyFile, []byte(encoded+"\n"), 0o600); err != nil {
+		return fmt.Errorf("写入新主密钥失败，旧主密钥备份在 %s.old: %w", keyFile, err)
+	}
+	fmt.Printf("新主密钥已写入 %s，旧主密钥备份在 %s.old，确认服务正常后请删除备份\n", keyFile, keyFile)
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(dst, data, 0o600)
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免中途失败留下不完整的文件
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Join(err, os.Remove(tmp))
+	}
+	return nil
+}
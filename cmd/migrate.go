@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"theing/gin-template/common"
+)
+
+// runMigrate 管理数据库迁移
+func runMigrate(args []string) error {
+	fs, dir := newFlagSet("migrate", "migrate [-config 配置目录] [-steps 回滚数量] up|down|status")
+	steps := fs.Int("steps", 1, "down 回滚的迁移数量")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, "请指定 up、down 或 status")
+	}
+	action := fs.Arg(0)
+	if action != "up" && action != "down" && action != "status" {
+		return usageError(fs, "未知的迁移操作 %q", action)
+	}
+	if action == "down" && *steps < 1 {
+		return usageError(fs, "-steps 必须大于 0")
+	}
+
+	if _, _, err := loadConfig(*dir); err != nil {
+		return err
+	}
+	db, err := common.ConnectDB()
+	if err != nil {
+		return err
+	}
+	defer common.CloseDB()
+
+	switch action {
+	case "up":
+		done, err := common.MigrateUp(db)
+		printMigrations("已执行", done)
+		if err == nil && len(done) == 0 {
+			fmt.Println("没有需要执行的迁移")
+		}
+		return err
+	case "down":
+		done, err := common.MigrateDown(db, *steps)
+		printMigrations("已回滚", done)
+		if err == nil && len(done) == 0 {
+			fmt.Println("没有可以回滚的迁移")
+		}
+		return err
+	}
+
+	states, err := common.MigrationStatus(db)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "迁移\t状态\t执行时间\t说明")
+	for _, state := range states {
+		status, appliedAt := "未执行", "-"
+		if state.AppliedAt != nil {
+			status, appliedAt = "已执行", state.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", state.ID, status, appliedAt, state.Description)
+	}
+	return w.Flush()
+}
+
+func printMigrations(action string, ids []string) {
+	for _, id := range ids {
+		fmt.Printf("%s %s\n", action, id)
+	}
+}
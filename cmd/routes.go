@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"theing/gin-template/routers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runRoutes 输出 routers.CollectRoute 注册的路由，不连接数据库
+func runRoutes(args []string) error {
+	fs, dir := newFlagSet("routes", "routes [-config 配置目录]")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if _, _, err := loadConfig(*dir); err != nil {
+		return err
+	}
+
+	// 不输出 gin 调试模式下的路由注册日志
+	gin.SetMode(gin.ReleaseMode)
+	routes := routers.CollectRoute(gin.New()).Routes()
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tPATH\tHANDLER")
+	for _, route := range routes {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", route.Method, route.Path, route.Handler)
+	}
+	return w.Flush()
+}
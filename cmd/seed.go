@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"theing/gin-template/common"
+	"theing/gin-template/config"
+)
+
+// 默认的示例数据，相对于配置目录，格式与用户批量导入相同
+const defaultFixtures = "fixtures/users.csv"
+
+// runSeed 初始化内置角色与权限，并按用户导入的格式导入示例用户。
+// 示例用户的密码公开在仓库中，生产环境需要 -force 才会执行
+func runSeed(args []string) error {
+	fs, dir := newFlagSet("seed", "seed [-config 配置目录] [-file 示例数据] [-dry-run] [-force]")
+	file := fs.String("file", "", "示例用户文件（CSV 或 XLSX，列为 telephone、name、password、roles），默认为配置目录下的 "+defaultFixtures)
+	dryRun := fs.Bool("dry-run", false, "只校验示例用户，不写入")
+	force := fs.Bool("force", false, "允许在生产环境执行")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	cfg, absDir, err := loadConfig(*dir)
+	if err != nil {
+		return err
+	}
+	if cfg.Env == config.EnvProduction && !*force {
+		return errors.New("生产环境不导入示例数据，确需执行请加 -force")
+	}
+	if *file == "" {
+		*file = filepath.Join(absDir, defaultFixtures)
+	}
+	db, err := common.ConnectDB()
+	if err != nil {
+		return err
+	}
+	defer common.CloseDB()
+
+	if err := common.SeedRBAC(db); err != nil {
+		return fmt.Errorf("初始化角色权限失败: %w", err)
+	}
+	fmt.Println("内置角色与权限已初始化")
+
+	rows, err := readUserFile(*file)
+	if err != nil {
+		return err
+	}
+	// 已存在的用户保持不变，重复执行不会覆盖修改过的密码
+	report, err := common.ImportUsers(context.Background(), rows, common.UserImportOptions{DryRun: *dryRun, GrantRoles: true})
+	if err != nil {
+		return err
+	}
+	return printImportReport(report)
+}
+
+// readUserFile 读取用户导入格式的文件
+func readUserFile(path string) ([][]string, error) {
+	format, err := common.UserFileFormat("", path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return common.ReadUserImportFile(file, info.Size(), format)
+}
+
+// printImportReport 输出导入结果，有失败的行时返回错误
+func printImportReport(report *common.UserImportReport) error {
+	prefix := ""
+	if report.DryRun {
+		prefix = "[dry-run] "
+	}
+	fmt.Printf("%s共 %d 个用户：新建 %d，更新 %d，跳过 %d，失败 %d\n", prefix, report.Total, report.Created, report.Updated, report.Skipped, report.Failed)
+	for _, e := range report.Errors {
+		field := ""
+		if e.Field != "" {
+			field = " " + e.Field
+		}
+		fmt.Printf("  第 %d 行 %s%s: %s\n", e.Row, e.Telephone, field, e.Message)
+	}
+	if report.Failed > 0 {
+		return errors.New("部分用户导入失败")
+	}
+	return nil
+}
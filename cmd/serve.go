@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"theing/gin-template/common"
+	"theing/gin-template/config"
+	"theing/gin-template/routers"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runServe 启动 HTTP 服务，直到收到退出信号
+func runServe(args []string) error {
+	fs, dir := newFlagSet("serve", "serve [-config 配置目录]")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	cfg, configDir, err := loadConfig(*dir) // 项目开始的时候就应该读取配置文件
+	if err != nil {
+		return err
+	}
+	gin.SetMode(cfg.Server.Mode)
+	if err := common.InitLogging(cfg.Logging); err != nil {
+		return err
+	}
+	// 加载 JWT 签名密钥，配置错误时直接退出
+	if err := common.InitJWTKeys(); err != nil {
+		return err
+	}
+	// 加载授权策略，策略文件错误时直接退出
+	if err := common.InitPolicies(); err != nil {
+		return err
+	}
+
+	// 按启动顺序登记需要关闭的组件，退出时逆序关闭
+	lifecycle := common.NewLifecycle()
+
+	common.InitDB() // 初始化数据库
+	lifecycle.Register("数据库", func(context.Context) error { return common.CloseDB() })
+
+	// 初始化缓存
+	if err := common.InitCache(); err != nil {
+		fmt.Printf("缓存初始化失败: %v\n", err)
+		// 缓存初始化失败不影响服务启动
+	} else {
+		lifecycle.Register("缓存", func(context.Context) error { return common.CloseCache() })
+	}
+
+	// 后台处理个人数据导出与删除申请，退出时等待正在处理的申请完成
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	workerDone := common.StartPrivacyWorker(workerCtx)
+	lifecycle.Register("个人数据处理任务", func(ctx context.Context) error {
+		stopWorker()
+		select {
+		case <-workerDone:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	// 配置文件变化或收到 SIGHUP 时重新加载配置
+	common.SubscribeConfigChanges()
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	config.Watch(watchCtx, configDir)
+	lifecycle.Register("配置监听", func(context.Context) error {
+		stopWatch()
+		return nil
+	})
+
+	// 访问日志与 panic 恢复由 routers.CollectRoute 注册的中间件处理
+	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.Security.TrustedProxies); err != nil {
+		return err
+	}
+	r = routers.CollectRoute(r) // 路由中的collectroute，是一个gin的引擎，返回的也是一个引擎，可以说是代理服务。
+
+	if err := serve(r, cfg.Server, lifecycle); err != nil {
+		return fmt.Errorf("服务退出时出错: %w", err)
+	}
+	return nil
+}
+
+// serve 启动 HTTP 服务，收到 SIGINT 或 SIGTERM 后依次将就绪检查置为不可用、
+// 等待进行中的请求处理完毕、逆序关闭各组件，整个过程不超过
+// shutdown_delay + shutdown_timeout + component_shutdown_timeout
+func serve(handler http.Handler, cfg config.ServerConfig, lifecycle *common.Lifecycle) error {
+	srv := &http.Server{
+		Addr:           cfg.Address(),
+		Handler:        handler,
+		ReadTimeout:    time.Duration(cfg.ReadTimeout) * time.Second,
+		WriteTimeout:   time.Duration(cfg.WriteTimeout) * time.Second,
+		IdleTimeout:    time.Duration(cfg.IdleTimeout) * time.Second,
+		MaxHeaderBytes: cfg.MaxHeaderBytes,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+	common.SetReady(true)
+	log.Printf("服务已启动: %s", srv.Addr)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	var errs []error
+	select {
+	case err := <-serveErr:
+		// 监听失败，如端口被占用
+		common.SetReady(false)
+		errs = append(errs, err)
+	case sig := <-quit:
+		log.Printf("收到 %s，开始退出", sig)
+		common.SetReady(false)
+		time.Sleep(time.Duration(cfg.ShutdownDelay) * time.Second)
+	}
+
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout)*time.Second)
+	defer cancelDrain()
+	if err := srv.Shutdown(drainCtx); err != nil {
+		errs = append(errs, fmt.Errorf("等待进行中的请求超时: %w", err))
+	}
+	// 关闭组件使用单独的时限，请求处理超时后数据库和缓存连接仍会被关闭
+	closeCtx, cancelClose := context.WithTimeout(context.Background(), time.Duration(cfg.ComponentShutdownTimeout)*time.Second)
+	defer cancelClose()
+	if err := lifecycle.Shutdown(closeCtx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	log.Println("服务已退出")
+	return nil
+}
@@ -0,0 +1,78 @@
+package common
+
+// 账号自助管理：更换手机号
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"theing/gin-template/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// phoneChangedNotice 更换手机号后发送到原手机号的通知
+const phoneChangedNotice = "您的账号绑定手机号已更换为 %s，如非本人操作请立即联系客服。"
+
+// RequestPhoneChange 向新手机号发送验证码，新手机号不能已被其他账号使用
+func RequestPhoneChange(ctx context.Context, user model.User, newPhone string) error {
+	if newPhone == user.Telephone {
+		return NewAppError(CodeInvalidParams, "新手机号与当前手机号相同", "")
+	}
+	taken, err := isPhoneTaken(GetDB(), newPhone)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return NewAppError(CodeUserExists, "该手机号已被其他账号使用", "")
+	}
+	return SendVerificationCode(ctx, VerifyPurposeChangePhone, newPhone)
+}
+
+// ChangeUserPhone 校验新手机号的验证码后更换手机号，并通知原手机号
+func ChangeUserPhone(ctx context.Context, user model.User, newPhone, code string) error {
+	if err := VerifyCode(ctx, VerifyPurposeChangePhone, newPhone, code); err != nil {
+		return err
+	}
+
+	err := GetDB().Transaction(func(tx *gorm.DB) error {
+		// 发送验证码后手机号可能已被其他账号注册
+		taken, err := isPhoneTaken(tx, newPhone)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return NewAppError(CodeUserExists, "该手机号已被其他账号使用", "")
+		}
+		return tx.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"telephone":         newPhone,
+			"phone_verified_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if user.Telephone != "" {
+		if err := GetSMSSender().Send(ctx, user.Telephone, fmt.Sprintf(phoneChangedNotice, maskPhone(newPhone))); err != nil {
+			log.Printf("发送手机号变更通知失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// isPhoneTaken 手机号是否已被使用
+func isPhoneTaken(db *gorm.DB, phone string) (bool, error) {
+	var count int64
+	err := db.Model(&model.User{}).Where("telephone = ?", phone).Count(&count).Error
+	return count > 0, err
+}
+
+// maskPhone 隐藏手机号中间四位
+func maskPhone(phone string) string {
+	if len(phone) != 11 {
+		return phone
+	}
+	return phone[:3] + "****" + phone[7:]
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"theing/gin-template/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeUserPhone(t *testing.T) {
+	db := useTestDB(t)
+	sender := useCaptureSender(t)
+	ctx := context.Background()
+	user := createTestUser(t, db, "13970000001")
+	createTestUser(t, db, "13970000002")
+	telephone := func() model.User {
+		var current model.User
+		require.NoError(t, db.First(&current, user.ID).Error)
+		return current
+	}
+
+	assertAppErrorCode(t, RequestPhoneChange(ctx, user, user.Telephone), CodeInvalidParams)
+	assertAppErrorCode(t, RequestPhoneChange(ctx, user, "13970000002"), CodeUserExists)
+
+	require.NoError(t, RequestPhoneChange(ctx, user, "13970000003"))
+	code := sender.code(t, "13970000003")
+
+	// 验证码错误时不更换
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	assert.Equal(t, ErrVerificationCodeInvalid, ChangeUserPhone(ctx, user, "13970000003", wrong))
+	assert.Equal(t, "13970000001", telephone().Telephone)
+	// 验证码只对接收它的手机号有效
+	assert.Equal(t, ErrVerificationCodeInvalid, ChangeUserPhone(ctx, user, "13970000004", code))
+
+	require.NoError(t, ChangeUserPhone(ctx, user, "13970000003", code))
+	updated := telephone()
+	assert.Equal(t, "13970000003", updated.Telephone)
+	assert.NotNil(t, updated.PhoneVerifiedAt)
+	// 原手机号收到变更通知，新号码中间四位隐藏
+	assert.Contains(t, sender.messages["13970000001"], "139****0003")
+
+	// 验证码只能使用一次
+	assert.Equal(t, ErrVerificationCodeInvalid, ChangeUserPhone(ctx, updated, "13970000003", code))
+}
+
+func TestChangeUserPhone_TakenAfterRequest(t *testing.T) {
+	db := useTestDB(t)
+	sender := useCaptureSender(t)
+	ctx := context.Background()
+	user := createTestUser(t, db, "13970000011")
+
+	require.NoError(t, RequestPhoneChange(ctx, user, "13970000012"))
+	code := sender.code(t, "13970000012")
+
+	// 发送验证码后手机号被其他账号注册
+	createTestUser(t, db, "13970000012")
+	assertAppErrorCode(t, ChangeUserPhone(ctx, user, "13970000012", code), CodeUserExists)
+}
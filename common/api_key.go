@@ -0,0 +1,203 @@
+package common
+
+// 个人 API 密钥的创建、校验与撤销
+
+import (
+	"errors"
+	"strings"
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+	"theing/gin-template/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// API 密钥格式为 gtk_<8位前缀>_<随机串>，前缀明文保存用于辨认
+const (
+	apiKeyScheme    = "gtk_"
+	apiKeyPrefixLen = len(apiKeyScheme) + 8
+)
+
+// AMRAPIKey 使用 API 密钥认证时写入 claims 的认证方式
+const AMRAPIKey = "apikey"
+
+// apiKeyTouchInterval 最近使用时间的更新间隔，避免每个请求都写库
+const apiKeyTouchInterval = time.Minute
+
+// 获取每个用户最多可以持有的有效密钥数量
+func getAPIKeyMaxPerUser() int {
+	limit := config.Get().APIKey.MaxPerUser
+	if limit <= 0 {
+		limit = 10
+	}
+	return limit
+}
+
+// CreateAPIKey 为用户创建 API 密钥，scopes 必须是用户当前拥有的权限，ttl 为 0 表示长期有效。
+// 返回的明文密钥只出现这一次。
+func CreateAPIKey(user model.User, name string, scopes []string, ttl time.Duration) (*model.APIKey, string, error) {
+	if err := checkAPIKeyScopes(user, scopes); err != nil {
+		return nil, "", err
+	}
+
+	db := GetDB()
+	var count int64
+	if err := db.Model(&model.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&count).Error; err != nil {
+		return nil, "", err
+	}
+	if int(count) >= getAPIKeyMaxPerUser() {
+		return nil, "", NewAppError(CodeBusinessError, "API密钥数量已达上限", "")
+	}
+
+	random, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	rawKey := apiKeyScheme + random[:apiKeyPrefixLen-len(apiKeyScheme)] + "_" + random[apiKeyPrefixLen-len(apiKeyScheme):]
+
+	key := model.APIKey{
+		UserID:  user.ID,
+		Name:    name,
+		Prefix:  rawKey[:apiKeyPrefixLen],
+		KeyHash: utils.HashToken(rawKey),
+		Scopes:  strings.Join(scopes, ","),
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		key.ExpiresAt = &expiresAt
+	}
+	if err := db.Create(&key).Error; err != nil {
+		return nil, "", err
+	}
+	return &key, rawKey, nil
+}
+
+// ListAPIKeys 查询用户的全部密钥，包括已撤销的
+func ListAPIKeys(userID uint) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	err := GetDB().Where("user_id = ?", userID).Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// UpdateAPIKey 修改密钥名称或授权范围，参数为 nil 表示不修改
+func UpdateAPIKey(user model.User, keyID uint, name *string, scopes []string) (*model.APIKey, error) {
+	key, err := findActiveAPIKey(user.ID, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if name != nil {
+		updates["name"] = *name
+	}
+	if scopes != nil {
+		if err := checkAPIKeyScopes(user, scopes); err != nil {
+			return nil, err
+		}
+		updates["scopes"] = strings.Join(scopes, ",")
+	}
+	if len(updates) > 0 {
+		if err := GetDB().Model(key).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// RevokeAPIKey 撤销用户的某个密钥
+func RevokeAPIKey(userID, keyID uint) error {
+	key, err := findActiveAPIKey(userID, keyID)
+	if err != nil {
+		return err
+	}
+	return GetDB().Model(key).Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserAPIKeys 撤销用户的全部密钥，用于重置密码等场景
+func RevokeUserAPIKeys(userID uint) error {
+	return GetDB().Model(&model.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// AuthenticateAPIKey 校验明文密钥并记录最近一次使用的时间和 IP
+func AuthenticateAPIKey(rawKey, ip string) (*model.APIKey, error) {
+	if !strings.HasPrefix(rawKey, apiKeyScheme) || len(rawKey) <= apiKeyPrefixLen {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	db := GetDB()
+	var key model.APIKey
+	if err := db.Where("key_hash = ?", utils.HashToken(rawKey)).Limit(1).Find(&key).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if key.ID == 0 || key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	// 距上次记录超过间隔或 IP 变化时才更新
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval || key.LastUsedIP != ip {
+		if err := db.Model(&key).UpdateColumns(map[string]interface{}{"last_used_at": now, "last_used_ip": ip}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &key, nil
+}
+
+// APIKeyClaims 为通过 API 密钥认证的请求构造 claims，角色取自 user.Roles，调用前需要预加载
+func APIKeyClaims(user model.User, key *model.APIKey) *Claims {
+	return &Claims{
+		UserId:   user.ID,
+		Roles:    RoleNames(user),
+		AMR:      []string{AMRAPIKey},
+		Scopes:   SplitScopes(key.Scopes),
+		APIKeyID: key.ID,
+	}
+}
+
+// SplitScopes 解析逗号分隔的授权范围
+func SplitScopes(scopes string) []string {
+	result := []string{}
+	for _, scope := range strings.Split(scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			result = append(result, scope)
+		}
+	}
+	return result
+}
+
+// checkAPIKeyScopes 授权范围不能超出用户当前拥有的权限
+func checkAPIKeyScopes(user model.User, scopes []string) error {
+	if len(scopes) == 0 {
+		return nil
+	}
+	granted, err := GetRolePermissions(RoleNames(user))
+	if err != nil {
+		return err
+	}
+	set := make(map[string]struct{}, len(granted))
+	for _, permission := range granted {
+		set[permission] = struct{}{}
+	}
+	for _, scope := range scopes {
+		if _, ok := set[scope]; !ok {
+			return NewAppError(CodeForbidden, "授权范围超出当前用户的权限", scope)
+		}
+	}
+	return nil
+}
+
+// findActiveAPIKey 查询用户未撤销的密钥
+func findActiveAPIKey(userID, keyID uint) (*model.APIKey, error) {
+	var key model.APIKey
+	err := GetDB().Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, NewAppError(CodeDataNotFound, "API密钥不存在", "")
+		}
+		return nil, err
+	}
+	return &key, nil
+}
@@ -0,0 +1,76 @@
+package common
+
+// 审计日志
+
+import (
+	"encoding/json"
+	"theing/gin-template/model"
+)
+
+// 审计操作类型
+const (
+	AuditImpersonationStart   = "impersonation.start"   // 签发代登录令牌
+	AuditImpersonationRequest = "impersonation.request" // 使用代登录令牌访问接口
+)
+
+// AuditEntry 一条待写入的审计记录
+type AuditEntry struct {
+	ActorID   uint
+	UserID    uint
+	Action    string
+	Detail    map[string]interface{}
+	Client    ClientInfo
+	RequestID string
+}
+
+// AuditLogQuery 审计日志查询条件，为零值的条件不生效
+type AuditLogQuery struct {
+	ActorID  uint   `form:"actor_id"`
+	UserID   uint   `form:"user_id"`
+	Action   string `form:"action"`
+	Page     int    `form:"-"`
+	PageSize int    `form:"-"`
+}
+
+// RecordAudit 写入审计日志
+func RecordAudit(entry AuditEntry) error {
+	detail := ""
+	if len(entry.Detail) > 0 {
+		data, err := json.Marshal(entry.Detail)
+		if err != nil {
+			return err
+		}
+		detail = string(data)
+	}
+	return GetDB().Create(&model.AuditLog{
+		ActorID:   entry.ActorID,
+		UserID:    entry.UserID,
+		Action:    entry.Action,
+		Detail:    detail,
+		IP:        entry.Client.IP,
+		UserAgent: truncateString(entry.Client.UserAgent, 255),
+		RequestID: entry.RequestID,
+	}).Error
+}
+
+// ListAuditLogs 按条件分页查询审计日志，最新的在前
+func ListAuditLogs(q AuditLogQuery) ([]model.AuditLog, int64, error) {
+	query := GetDB().Model(&model.AuditLog{})
+	if q.ActorID != 0 {
+		query = query.Where("actor_id = ?", q.ActorID)
+	}
+	if q.UserID != 0 {
+		query = query.Where("user_id = ?", q.UserID)
+	}
+	if q.Action != "" {
+		query = query.Where("action = ?", q.Action)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var logs []model.AuditLog
+	err := query.Order("id DESC").Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).Find(&logs).Error
+	return logs, total, err
+}
@@ -6,8 +6,9 @@ import (
 	"fmt"
 	"time"
 
+	"theing/gin-template/config"
+
 	"github.com/go-redis/redis/v8"
-	"github.com/spf13/viper"
 )
 
 // CacheClient 缓存客户端接口
@@ -17,6 +18,8 @@ type CacheClient interface {
 	Delete(ctx context.Context, key string) error
 	Exists(ctx context.Context, key string) (bool, error)
 	Flush(ctx context.Context) error
+	// Incr 原子地把计数加一并返回新值，键不存在时从 0 开始并设置过期时间，之后的递增不改变过期时间
+	Incr(ctx context.Context, key string, expiration time.Duration) (int64, error)
 }
 
 // RedisCache Redis缓存实现
@@ -45,12 +48,13 @@ var (
 // InitCache 初始化缓存连接
 func InitCache() error {
 	// 从配置文件读取缓存配置
+	cfg := config.Get()
 	cacheConfig = CacheConfig{
-		Host:     viper.GetString("cache_host"),
-		Port:     viper.GetInt("cache_port"),
-		Password: viper.GetString("cache_password"),
-		DB:       viper.GetInt("cache_db"),
-		Prefix:   viper.GetString("cache_prefix"),
+		Host:     cfg.Redis.Host,
+		Port:     cfg.Redis.Port,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+		Prefix:   cfg.Cache.Prefix,
 	}
 
 	// 设置默认值
@@ -66,9 +70,14 @@ func InitCache() error {
 
 	// 创建 Redis 客户端
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%d", cacheConfig.Host, cacheConfig.Port),
-		Password: cacheConfig.Password,
-		DB:       cacheConfig.DB,
+		Addr:         fmt.Sprintf("%s:%d", cacheConfig.Host, cacheConfig.Port),
+		Password:     cacheConfig.Password,
+		DB:           cacheConfig.DB,
+		PoolSize:     cfg.Redis.PoolSize,
+		MinIdleConns: cfg.Redis.MinIdleConns,
+		DialTimeout:  time.Duration(cfg.Redis.DialTimeout) * time.Second,
+		ReadTimeout:  time.Duration(cfg.Redis.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(cfg.Redis.WriteTimeout) * time.Second,
 	})
 
 	// 测试连接
@@ -85,6 +94,14 @@ func InitCache() error {
 	return nil
 }
 
+// CloseCache 关闭 Redis 连接，未连接 Redis 时不做任何事
+func CloseCache() error {
+	if redisCache, ok := Cache.(*RedisCache); ok {
+		return redisCache.client.Close()
+	}
+	return nil
+}
+
 // Get 获取缓存值
 func (r *RedisCache) Get(ctx context.Context, key string) (string, error) {
 	fullKey := r.getFullKey(key)
@@ -110,6 +127,20 @@ func (r *RedisCache) Exists(ctx context.Context, key string) (bool, error) {
 	return result > 0, err
 }
 
+// incrScript 递增并只在第一次递增时设置过期时间，两步在 Redis 中原子执行
+var incrScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n`)
+
+// Incr 递增计数
+func (r *RedisCache) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	fullKey := r.getFullKey(key)
+	return incrScript.Run(ctx, r.client, []string{fullKey}, expiration.Milliseconds()).Int64()
+}
+
 // Flush 清空所有缓存
 func (r *RedisCache) Flush(ctx context.Context) error {
 	// 只清空当前应用的缓存
@@ -125,6 +156,11 @@ func (r *RedisCache) Flush(ctx context.Context) error {
 	return nil
 }
 
+// IsCacheMiss 判断错误是否表示缓存不存在
+func IsCacheMiss(err error) bool {
+	return err == redis.Nil || err == ErrCacheNotFound
+}
+
 // getFullKey 获取完整的缓存键
 func (r *RedisCache) getFullKey(key string) string {
 	return cacheConfig.Prefix + key
@@ -34,6 +34,11 @@ func (m *MockCacheClient) Exists(ctx context.Context, key string) (bool, error)
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockCacheClient) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	args := m.Called(ctx, key, expiration)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (m *MockCacheClient) Flush(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
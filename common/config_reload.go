@@ -0,0 +1,52 @@
+package common
+
+import (
+	"log"
+	"reflect"
+	"theing/gin-template/config"
+)
+
+// SubscribeConfigChanges 注册公共组件的配置变更回调，配置重新加载后刷新依赖配置的缓存状态
+func SubscribeConfigChanges() {
+	config.Subscribe("logging", func(old, cfg *config.Config) {
+		if old.Logging == cfg.Logging {
+			return
+		}
+		if err := InitLogging(cfg.Logging); err != nil {
+			log.Printf("切换日志配置失败，继续使用原有配置: %v", err)
+		}
+	})
+
+	config.Subscribe("oidc", func(old, cfg *config.Config) {
+		if !reflect.DeepEqual(old.OIDC, cfg.OIDC) {
+			resetOIDCClients()
+		}
+	})
+
+	config.Subscribe("sms", func(old, cfg *config.Config) {
+		if old.SMS == cfg.SMS {
+			return
+		}
+		// 只替换按配置创建的内置发送器，通过 SetSMSSender 注册的发送器保持不变
+		smsSenderMu.Lock()
+		switch smsSender.(type) {
+		case LogSMSSender, *FileSMSSender:
+			smsSender = nil
+		}
+		smsSenderMu.Unlock()
+	})
+
+	config.Subscribe("policy", func(_, _ *config.Config) {
+		// 策略文件内容可能变化，每次重新加载都重新读取
+		if err := InitPolicies(); err != nil {
+			log.Printf("重新加载策略失败，继续使用原有策略: %v", err)
+		}
+	})
+
+	config.Subscribe("database_pool", func(old, cfg *config.Config) {
+		if DB == nil || old.Database == cfg.Database {
+			return
+		}
+		configureConnectionPool(DB, cfg.Database)
+	})
+}
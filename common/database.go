@@ -4,10 +4,11 @@ package common
 
 import (
 	"fmt"
+	"net/url"
+	"theing/gin-template/config"
 	"theing/gin-template/model"
 	"time"
 
-	"github.com/spf13/viper"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -15,77 +16,117 @@ import (
 
 var DB *gorm.DB
 
+// InitDB 连接数据库，按 database.auto_migrate 执行未完成的迁移，并初始化内置角色与权限，失败时 panic
 func InitDB() *gorm.DB {
-	driverName := viper.GetString("db_name")
+	db, err := ConnectDB()
+	if err != nil {
+		panic("数据库连接失败: " + err.Error())
+	}
+
+	// 执行未完成的迁移
+	if config.Get().Database.AutoMigrate {
+		if _, err := MigrateUp(db); err != nil {
+			panic("数据库迁移失败: " + err.Error())
+		}
+	}
+
+	// 初始化内置角色与权限
+	if err := SeedRBAC(db); err != nil {
+		panic("初始化角色权限失败: " + err.Error())
+	}
+	return db
+}
+
+// ConnectDB 按配置连接数据库并配置连接池，不执行迁移
+func ConnectDB() (*gorm.DB, error) {
+	cfg := config.Get().Database
+	driverName := cfg.Driver
 	fmt.Println("driverName: ", driverName)
 
 	var db *gorm.DB
 	var err error
 
 	if driverName == "mysql" {
-		db, err = initMySQLDB()
+		db, err = initMySQLDB(cfg)
 	} else if driverName == "postgres" {
-		db, err = initPostgresDB()
+		db, err = initPostgresDB(cfg)
 	} else {
-		panic("不支持的数据库类型: " + driverName)
+		return nil, fmt.Errorf("不支持的数据库类型: %s", driverName)
 	}
-
 	if err != nil {
-		panic("数据库连接失败: " + err.Error())
+		return nil, err
 	}
 
 	// 配置连接池
-	configureConnectionPool(db)
+	configureConnectionPool(db, cfg)
 
-	// 自动迁移
-	if err := db.AutoMigrate(&model.User{}); err != nil {
-		panic("数据库迁移失败: " + err.Error())
+	DB = db
+	return db, nil
+}
+
+// migrateUsers 迁移旧版本的用户表，可以重复执行：
+//  1. 旧表中 tel 与 telephone 重复保存手机号，telephone 为空时用 tel 补齐，然后删除 tel 列
+//  2. 旧数据没有创建时间，用迁移时间补齐，便于按创建时间筛选
+//  3. 状态为空的旧用户视为正常
+func migrateUsers(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if migrator.HasColumn(&model.User{}, "tel") {
+		if err := db.Exec("UPDATE users SET telephone = tel WHERE (telephone IS NULL OR telephone = '') AND tel IS NOT NULL AND tel <> ''").Error; err != nil {
+			return err
+		}
+		if err := migrator.DropColumn(&model.User{}, "tel"); err != nil {
+			return err
+		}
 	}
 
-	DB = db
-	return db
+	now := time.Now()
+	backfill := []struct {
+		condition string
+		column    string
+		value     interface{}
+	}{
+		{"created_at IS NULL", "created_at", now},
+		{"updated_at IS NULL", "updated_at", now},
+		{"status IS NULL OR status = ''", "status", UserStatusActive},
+	}
+	for _, item := range backfill {
+		if err := db.Model(&model.User{}).Unscoped().Where(item.condition).UpdateColumn(item.column, item.value).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // initMySQLDB 初始化 MySQL 数据库连接
-func initMySQLDB() (*gorm.DB, error) {
-	host := viper.GetString("mysql_host")
-	port := viper.GetString("mysql_port")
-	database := viper.GetString("mysql_database")
-	username := viper.GetString("mysql_username")
-	password := viper.GetString("mysql_password")
-	charset := viper.GetString("mysql_charset")
-
-	args := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=true",
-		username,
-		password,
-		host,
-		port,
-		database,
-		charset)
+func initMySQLDB(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	args := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=true&loc=%s",
+		cfg.User,
+		cfg.Password,
+		cfg.Host,
+		cfg.Port,
+		cfg.Name,
+		cfg.Charset,
+		url.QueryEscape(cfg.TimeZone))
 
 	return gorm.Open(mysql.Open(args), &gorm.Config{})
 }
 
 // initPostgresDB 初始化 PostgreSQL 数据库连接
-func initPostgresDB() (*gorm.DB, error) {
-	host := viper.GetString("postgres_host")
-	port := viper.GetString("postgres_port")
-	database := viper.GetString("postgres_database")
-	username := viper.GetString("postgres_username")
-	password := viper.GetString("postgres_password")
-
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Shanghai",
-		host,
-		username,
-		password,
-		database,
-		port)
+func initPostgresDB(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=%s",
+		cfg.Host,
+		cfg.User,
+		cfg.Password,
+		cfg.Name,
+		cfg.Port,
+		cfg.SSLMode,
+		cfg.TimeZone)
 
 	return gorm.Open(postgres.Open(dsn), &gorm.Config{})
 }
 
 // configureConnectionPool 配置数据库连接池
-func configureConnectionPool(db *gorm.DB) {
+func configureConnectionPool(db *gorm.DB, cfg config.DatabaseConfig) {
 	sqlDB, err := db.DB()
 	if err != nil {
 		fmt.Printf("获取底层数据库连接失败: %v\n", err)
@@ -93,19 +134,27 @@ func configureConnectionPool(db *gorm.DB) {
 	}
 
 	// 设置连接池参数
-	sqlDB.SetMaxIdleConns(10)           // 设置空闲连接池中连接的最大数量
-	sqlDB.SetMaxOpenConns(100)          // 设置打开数据库连接的最大数量
-	sqlDB.SetConnMaxLifetime(time.Hour) // 设置连接可复用的最大时间
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)                                    // 设置空闲连接池中连接的最大数量
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)                                    // 设置打开数据库连接的最大数量
+	sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second) // 设置连接可复用的最大时间
+}
+
+// CloseDB 关闭数据库连接池
+func CloseDB() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
 }
 
 // 定义一个方法来获取DB实例，需要在controller中引入
 func GetDB() *gorm.DB {
-	isPrintSql := viper.GetString("is_print_sql") // 判定是否打印sql的一个debug模式
-	if isPrintSql == "true" {
+	if config.Get().Database.LogSQL { // 判定是否打印sql的一个debug模式
 		return DB.Debug()
-	} else if isPrintSql == "false" {
-		return DB
-	} else {
-		return DB
 	}
+	return DB
 }
@@ -0,0 +1,40 @@
+package common
+
+import (
+	"path/filepath"
+	"testing"
+
+	"theing/gin-template/model"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useTestDB 使用临时的 SQLite 数据库替换 DB，建好全部数据表并初始化内置角色，测试结束后恢复
+func useTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(schemaModels...))
+	require.NoError(t, SeedRBAC(db))
+
+	previous := DB
+	DB = db
+	t.Cleanup(func() {
+		DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// createTestUser 创建正常状态的用户
+func createTestUser(t *testing.T, db *gorm.DB, telephone string) model.User {
+	user := model.User{Username: "user" + telephone[len(telephone)-4:], Telephone: telephone, Password: "hash", Status: UserStatusActive}
+	require.NoError(t, db.Create(&user).Error)
+	return user
+}
@@ -28,6 +28,34 @@ const (
 	CodeTooManyRequests  ErrorCode = 1012 // 请求过于频繁
 	CodeValidationFailed ErrorCode = 1013 // 验证失败
 
+	// 认证相关错误 1100-1199
+	CodeRefreshTokenInvalid ErrorCode = 1101 // 刷新令牌无效
+	CodeRefreshTokenReused  ErrorCode = 1102 // 刷新令牌被重复使用
+	CodeTokenRevoked        ErrorCode = 1103 // Token已被撤销
+
+	// 两步验证相关
+	CodeMFARequired       ErrorCode = 1104 // 需要完成两步验证
+	CodeMFAInvalidCode    ErrorCode = 1105 // 两步验证码错误
+	CodeMFAAlreadyEnabled ErrorCode = 1106 // 已开启两步验证
+	CodeMFANotEnabled     ErrorCode = 1107 // 未开启两步验证
+
+	// 登录保护相关
+	CodeInvalidCredentials ErrorCode = 1108 // 账号或密码错误
+	CodeAccountLocked      ErrorCode = 1109 // 账号已被临时锁定
+
+	// 短信验证相关
+	CodeVerificationCodeInvalid ErrorCode = 1110 // 验证码错误或已过期
+
+	// API 密钥相关
+	CodeAPIKeyInvalid ErrorCode = 1111 // API密钥无效
+
+	// 第三方登录相关
+	CodeExternalLoginFailed ErrorCode = 1112 // 第三方登录失败
+
+	// 账号状态相关
+	CodeAccountInactive ErrorCode = 1113 // 账号未激活、已停用或已注销
+	CodePasswordExpired ErrorCode = 1114 // 密码已过期或被要求重置
+
 	// 业务逻辑错误 2000-2999
 	CodeBusinessError ErrorCode = 2001 // 业务逻辑错误
 	CodeDataExists    ErrorCode = 2002 // 数据已存在
@@ -46,6 +74,8 @@ type AppError struct {
 	Message    string    `json:"message"`           // 错误信息
 	Details    string    `json:"details,omitempty"` // 详细信息
 	HTTPStatus int       `json:"-"`                 // HTTP状态码，不返回给前端
+
+	Data interface{} `json:"data,omitempty"` // 结构化的错误数据，如逐条的校验结果
 }
 
 // Error 实现error接口
@@ -56,6 +86,13 @@ func (e *AppError) Error() string {
 	return fmt.Sprintf("[%d] %s", e.Code, e.Message)
 }
 
+// WithData 返回附带结构化数据的副本，预定义错误可以安全使用
+func (e *AppError) WithData(data interface{}) *AppError {
+	copied := *e
+	copied.Data = data
+	return &copied
+}
+
 // NewAppError 创建应用错误
 func NewAppError(code ErrorCode, message string, details string) *AppError {
 	httpStatus := getHTTPStatusByCode(code)
@@ -99,6 +136,31 @@ var (
 	ErrDatabaseError      = NewAppError(CodeDatabaseError, "数据库错误", "")
 	ErrNetworkError       = NewAppError(CodeNetworkError, "网络错误", "")
 	ErrServiceUnavailable = NewAppError(CodeServiceUnavailable, "服务不可用", "")
+
+	// 认证相关
+	ErrRefreshTokenInvalid = NewAppError(CodeRefreshTokenInvalid, "刷新令牌无效或已过期", "")
+	ErrRefreshTokenReused  = NewAppError(CodeRefreshTokenReused, "刷新令牌已被使用，该登录下的令牌已全部撤销", "")
+	ErrTokenRevoked        = NewAppError(CodeTokenRevoked, "Token已被撤销", "")
+
+	// 两步验证相关
+	ErrMFARequired       = NewAppError(CodeMFARequired, "需要完成两步验证", "")
+	ErrMFAInvalidCode    = NewAppError(CodeMFAInvalidCode, "验证码错误或已使用", "")
+	ErrMFAAlreadyEnabled = NewAppError(CodeMFAAlreadyEnabled, "已开启两步验证", "")
+	ErrMFANotEnabled     = NewAppError(CodeMFANotEnabled, "未开启两步验证", "")
+
+	// 登录保护相关
+	ErrInvalidCredentials = NewAppError(CodeInvalidCredentials, "手机号或密码错误", "")
+
+	// 短信验证相关
+	ErrVerificationCodeInvalid = NewAppError(CodeVerificationCodeInvalid, "验证码错误或已过期", "")
+
+	// API 密钥相关
+	ErrAPIKeyInvalid = NewAppError(CodeAPIKeyInvalid, "API密钥无效、已过期或已撤销", "")
+
+	// 账号状态相关
+	ErrAccountPending   = NewAppError(CodeAccountInactive, "账号尚未激活，请通过短信验证码设置密码", "").WithData(map[string]string{"status": UserStatusPending})
+	ErrAccountSuspended = NewAppError(CodeAccountInactive, "账号已被停用，请联系管理员", "").WithData(map[string]string{"status": UserStatusSuspended})
+	ErrAccountDeleted   = NewAppError(CodeAccountInactive, "账号已注销", "").WithData(map[string]string{"status": UserStatusDeleted})
 )
 
 // GetErrorMessage 根据错误码获取错误信息
@@ -125,6 +187,34 @@ func GetErrorMessage(code ErrorCode) string {
 		CodeDatabaseError:      "数据库错误",
 		CodeNetworkError:       "网络错误",
 		CodeServiceUnavailable: "服务不可用",
+
+		// 认证相关
+		CodeRefreshTokenInvalid: "刷新令牌无效",
+		CodeRefreshTokenReused:  "刷新令牌被重复使用",
+		CodeTokenRevoked:        "Token已被撤销",
+
+		// 两步验证相关
+		CodeMFARequired:       "需要完成两步验证",
+		CodeMFAInvalidCode:    "两步验证码错误",
+		CodeMFAAlreadyEnabled: "已开启两步验证",
+		CodeMFANotEnabled:     "未开启两步验证",
+
+		// 登录保护相关
+		CodeInvalidCredentials: "账号或密码错误",
+		CodeAccountLocked:      "账号已被临时锁定",
+
+		// 短信验证相关
+		CodeVerificationCodeInvalid: "验证码错误或已过期",
+
+		// API 密钥相关
+		CodeAPIKeyInvalid: "API密钥无效",
+
+		// 第三方登录相关
+		CodeExternalLoginFailed: "第三方登录失败",
+
+		// 账号状态相关
+		CodeAccountInactive: "账号不可用",
+		CodePasswordExpired: "密码已过期",
 	}
 
 	if msg, exists := messages[code]; exists {
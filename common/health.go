@@ -144,20 +144,31 @@ func checkServicesHealth(status *HealthStatus) {
 
 // checkJWTService 检查JWT服务状态
 func checkJWTService() map[string]interface{} {
-	// 简单检查JWT配置是否正确
-	jwtSecret := getJWTKey()
+	// 检查签名密钥是否能够按配置加载
+	ks, err := GetKeySet()
+	if err != nil {
+		return map[string]interface{}{
+			"status": "unhealthy",
+			"error":  fmt.Sprintf("JWT密钥加载失败: %v", err),
+		}
+	}
 
-	if jwtSecret == nil || len(jwtSecret) == 0 {
+	if ks.Active == nil && len(getJWTKey()) == 0 {
 		return map[string]interface{}{
 			"status": "unhealthy",
 			"error":  "JWT密钥未配置",
 		}
 	}
 
-	return map[string]interface{}{
-		"status": "healthy",
-		"config": "configured",
+	status := map[string]interface{}{
+		"status":    "healthy",
+		"config":    "configured",
+		"algorithm": ks.Algorithm,
 	}
+	if ks.Active != nil {
+		status["kid"] = ks.Active.Kid
+	}
+	return status
 }
 
 // checkCacheService 检查缓存服务状态
@@ -0,0 +1,142 @@
+package common
+
+// 管理员代登录：以用户身份查看页面，令牌同时记录操作人和被代登录的用户
+
+import (
+	"slices"
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+	"time"
+)
+
+// ImpersonationRequest 代登录申请
+type ImpersonationRequest struct {
+	Actor       model.User // 发起代登录的管理员
+	ActorClaims *Claims    // 发起申请所用的令牌，代登录令牌不能再发起代登录
+	SubjectID   uint       // 被代登录的用户
+	Reason      string     // 代登录原因，写入审计日志
+	Minutes     int        // 令牌有效期，0 使用默认值，不能超过配置的上限
+	Client      ClientInfo
+	RequestID   string
+}
+
+// ImpersonationToken 代登录令牌
+type ImpersonationToken struct {
+	Token     string
+	ExpiresAt time.Time
+	Subject   model.User
+}
+
+// GetImpersonationMaxDuration 代登录令牌的最长有效期
+func GetImpersonationMaxDuration() time.Duration {
+	if minutes := config.Get().Impersonation.MaxMinutes; minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return 30 * time.Minute
+}
+
+// getImpersonationDefaultDuration 未指定时代登录令牌的有效期
+func getImpersonationDefaultDuration() time.Duration {
+	ttl := 15 * time.Minute
+	if minutes := config.Get().Impersonation.DefaultMinutes; minutes > 0 {
+		ttl = time.Duration(minutes) * time.Minute
+	}
+	if max := GetImpersonationMaxDuration(); ttl > max {
+		return max
+	}
+	return ttl
+}
+
+// StartImpersonation 为用户签发代登录令牌。不能代登录自己、管理员或非正常状态的用户，也不能在代登录期间再次代登录；
+// 审计日志写入成功后才返回令牌。令牌没有刷新令牌和会话，到期后需要重新申请。
+func StartImpersonation(req ImpersonationRequest) (*ImpersonationToken, error) {
+	if IsImpersonation(req.ActorClaims) {
+		return nil, NewAppError(CodeForbidden, "代登录期间不能再次代登录", "")
+	}
+	if req.SubjectID == req.Actor.ID {
+		return nil, NewAppError(CodeForbidden, "不能代登录自己的账号", "")
+	}
+	if err := CheckImpersonator(req.Actor.ID); err != nil {
+		return nil, err
+	}
+	var subject model.User
+	if err := GetDB().Preload("Roles").Limit(1).Find(&subject, req.SubjectID).Error; err != nil {
+		return nil, err
+	}
+	if subject.ID == 0 {
+		return nil, ErrUserNotFound
+	}
+	if HasRole(RoleNames(subject), RoleAdmin) {
+		return nil, NewAppError(CodeForbidden, "不能代登录管理员账号", "")
+	}
+	if err := CheckUserActive(subject); err != nil {
+		return nil, err
+	}
+
+	ttl := getImpersonationDefaultDuration()
+	if req.Minutes > 0 {
+		ttl = time.Duration(req.Minutes) * time.Minute
+	}
+	if max := GetImpersonationMaxDuration(); ttl > max {
+		return nil, NewAppError(CodeInvalidParams, "有效期超过上限", max.String())
+	}
+
+	claims := &Claims{
+		UserId:  subject.ID,
+		Roles:   RoleNames(subject),
+		ActorID: req.Actor.ID,
+	}
+	token, err := releaseToken(claims, ttl)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+
+	if err := RecordAudit(AuditEntry{
+		ActorID: req.Actor.ID,
+		UserID:  subject.ID,
+		Action:  AuditImpersonationStart,
+		Detail: map[string]interface{}{
+			"reason":     req.Reason,
+			"jti":        claims.Id,
+			"expires_at": expiresAt,
+		},
+		Client:    req.Client,
+		RequestID: req.RequestID,
+	}); err != nil {
+		return nil, err
+	}
+	return &ImpersonationToken{Token: token, ExpiresAt: expiresAt, Subject: subject}, nil
+}
+
+// CheckImpersonator 校验代登录的发起人：账号正常、仍是管理员并拥有 user:impersonate 权限。
+// 代登录令牌的每个请求都重新校验，发起人的角色或权限被撤销后进行中的代登录随即失效
+func CheckImpersonator(actorID uint) error {
+	var actor model.User
+	if err := GetDB().Preload("Roles").Limit(1).Find(&actor, actorID).Error; err != nil {
+		return err
+	}
+	if actor.ID == 0 {
+		return ErrUserNotFound
+	}
+	if err := CheckUserActive(actor); err != nil {
+		return err
+	}
+	roles := RoleNames(actor)
+	if !HasRole(roles, RoleAdmin) {
+		return NewAppError(CodeForbidden, "没有代登录权限", "")
+	}
+	permissions, err := GetRolePermissions(roles)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(permissions, PermUserImpersonate) {
+		return NewAppError(CodeForbidden, "没有代登录权限", "")
+	}
+	return nil
+}
+
+// IsImpersonation 令牌是否为代登录令牌
+func IsImpersonation(claims *Claims) bool {
+	return claims != nil && claims.ActorID != 0
+}
@@ -0,0 +1,119 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestImpersonationClaims(t *testing.T) {
+	useKeys(t, "HS256", "")
+
+	tokenString, err := releaseToken(&Claims{UserId: 7, Roles: []string{RoleUser}, ActorID: 1}, time.Minute)
+	require.NoError(t, err)
+	_, claims, err := ParseToken(tokenString)
+	require.NoError(t, err)
+	assert.True(t, IsImpersonation(claims))
+	assert.Equal(t, uint(7), claims.UserId)
+	assert.Equal(t, uint(1), claims.ActorID)
+
+	assert.False(t, IsImpersonation(&Claims{UserId: 7}))
+	assert.False(t, IsImpersonation(nil))
+}
+
+func TestImpersonationDuration(t *testing.T) {
+	assert.Equal(t, 15*time.Minute, getImpersonationDefaultDuration())
+	assert.Equal(t, 30*time.Minute, GetImpersonationMaxDuration())
+
+	useConfig(t, func(cfg *config.Config) {
+		cfg.Impersonation.DefaultMinutes = 60
+		cfg.Impersonation.MaxMinutes = 20
+	})
+	assert.Equal(t, 20*time.Minute, getImpersonationDefaultDuration())
+}
+
+// createImpersonator 创建拥有 user:impersonate 权限的管理员
+func createImpersonator(t *testing.T, db *gorm.DB, telephone string) model.User {
+	actor := createTestUser(t, db, telephone)
+	require.NoError(t, AssignRole(actor.ID, RoleAdmin))
+	if _, err := CreateRole("impersonator", "", []string{PermUserImpersonate}); err != nil {
+		require.Equal(t, CodeDataExists, err.(*AppError).Code)
+	}
+	require.NoError(t, AssignRole(actor.ID, "impersonator"))
+	return actor
+}
+
+func TestStartImpersonation_Restrictions(t *testing.T) {
+	useKeys(t, "HS256", "")
+	db := useTestDB(t)
+	actor := createImpersonator(t, db, "13910000001")
+	admin := createTestUser(t, db, "13910000002")
+	require.NoError(t, AssignRole(admin.ID, RoleAdmin))
+	subject := createTestUser(t, db, "13910000003")
+	start := func(actor model.User, subjectID uint, claims *Claims) error {
+		_, err := StartImpersonation(ImpersonationRequest{Actor: actor, ActorClaims: claims, SubjectID: subjectID, Reason: "排查问题"})
+		return err
+	}
+	actorClaims := &Claims{UserId: actor.ID, Roles: []string{RoleAdmin, "impersonator"}}
+
+	assert.ErrorContains(t, start(actor, actor.ID, actorClaims), "不能代登录自己的账号")
+	assert.ErrorContains(t, start(actor, admin.ID, actorClaims), "不能代登录管理员账号")
+	// 代登录令牌不能再发起代登录
+	assert.ErrorContains(t, start(actor, subject.ID, &Claims{UserId: actor.ID, ActorID: admin.ID}), "代登录期间不能再次代登录")
+	// 只有管理员角色、没有 user:impersonate 权限
+	assert.ErrorContains(t, start(admin, subject.ID, &Claims{UserId: admin.ID, Roles: []string{RoleAdmin}}), "没有代登录权限")
+
+	token, err := StartImpersonation(ImpersonationRequest{Actor: actor, ActorClaims: actorClaims, SubjectID: subject.ID, Reason: "排查问题"})
+	require.NoError(t, err)
+	_, claims, err := ParseToken(token.Token)
+	require.NoError(t, err)
+	assert.Equal(t, subject.ID, claims.UserId)
+	assert.Equal(t, actor.ID, claims.ActorID)
+}
+
+func TestCheckImpersonator_PermissionRevoked(t *testing.T) {
+	db := useTestDB(t)
+	actor := createImpersonator(t, db, "13910000004")
+	require.NoError(t, CheckImpersonator(actor.ID))
+
+	// 撤销权限后进行中的代登录随即失效
+	require.NoError(t, RemoveRole(actor.ID, "impersonator"))
+	assert.ErrorContains(t, CheckImpersonator(actor.ID), "没有代登录权限")
+
+	require.NoError(t, AssignRole(actor.ID, "impersonator"))
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", actor.ID).Update("status", UserStatusSuspended).Error)
+	assert.Error(t, CheckImpersonator(actor.ID))
+}
+
+func TestImpersonation_RevocationOutlivesToken(t *testing.T) {
+	useKeys(t, "HS256", "")
+	db := useTestDB(t)
+	useConfig(t, func(cfg *config.Config) {
+		cfg.JWT.AccessExpireMinutes = 15
+		cfg.OAuth.AccessExpireMinutes = 10
+		cfg.Impersonation.MaxMinutes = 60
+	})
+	actor := createImpersonator(t, db, "13910000005")
+	subject := createTestUser(t, db, "13910000006")
+	issued, err := StartImpersonation(ImpersonationRequest{Actor: actor, SubjectID: subject.ID, Minutes: 60, Reason: "排查问题"})
+	require.NoError(t, err)
+
+	// 调整角色时撤销用户令牌，撤销记录要保留到代登录令牌过期，否则令牌会带着旧角色重新生效
+	require.NoError(t, RevokeUserTokens(context.Background(), subject.ID))
+	var record model.TokenRevocation
+	require.NoError(t, db.Where("token_key = ?", userRevocationKey(subject.ID)).First(&record).Error)
+	assert.False(t, record.ExpiresAt.Before(issued.ExpiresAt))
+
+	_, claims, err := ParseToken(issued.Token)
+	require.NoError(t, err)
+	revoked, err := IsTokenRevoked(context.Background(), claims)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
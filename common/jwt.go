@@ -1,16 +1,17 @@
 package common
 
 import (
+	"theing/gin-template/config"
 	"theing/gin-template/model"
+	"theing/gin-template/utils"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
-	"github.com/spf13/viper"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 // 获取 JWT 密钥
 func getJWTKey() []byte {
-	secret := viper.GetString("jwt.secret")
+	secret := config.Get().JWT.Secret
 	if secret == "" {
 		// 如果配置中没有设置，使用默认密钥（仅用于开发环境）
 		secret = "default-jwt-secret-for-development-only"
@@ -18,9 +19,12 @@ func getJWTKey() []byte {
 	return []byte(secret)
 }
 
-// 获取 JWT 过期时间
+// 获取 JWT 过期时间，优先使用访问令牌的分钟级配置
 func getJWTExpirationTime() time.Duration {
-	hours := viper.GetInt("jwt.expire_hours")
+	if minutes := config.Get().JWT.AccessExpireMinutes; minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	hours := config.Get().JWT.ExpireHours
 	if hours <= 0 {
 		hours = 168 // 默认 7 天
 	}
@@ -29,34 +33,86 @@ func getJWTExpirationTime() time.Duration {
 
 // 获取 JWT 签发者
 func getJWTIssuer() string {
-	issuer := viper.GetString("jwt.issuer")
+	issuer := config.Get().JWT.Issuer
 	if issuer == "" {
 		issuer = "gin-template"
 	}
 	return issuer
 }
 
-// 定义token 的 claims
+// 令牌类型，访问令牌不设置
+const (
+	TokenTypeMFAPending  = "mfa_pending"  // 密码验证通过、等待两步验证的临时令牌
+	TokenTypeOAuthAccess = "oauth_access" // 签发给第三方应用的访问令牌
+)
+
+// 认证方式（RFC 8176 amr）
+const (
+	AMRPassword = "pwd" // 密码
+	AMROTP      = "otp" // 一次性密码，包括 TOTP 和恢复码
+)
+
+// 定义token 的 claims，StandardClaims.Id 即 jti，用于撤销单个 token
 type Claims struct {
-	UserId uint
+	UserId    uint
+	Roles     []string `json:"roles,omitempty"` // 用户角色
+	TokenType string   `json:"typ,omitempty"`   // 令牌类型，访问令牌为空
+	AMR       []string `json:"amr,omitempty"`   // 本次登录使用的认证方式
+	SessionID string   `json:"sid,omitempty"`   // 所属会话，与刷新令牌族相同
+	Scopes    []string `json:"-"`               // API 密钥的授权范围
+	APIKeyID  uint     `json:"-"`               // 通过 API 密钥认证时的密钥 ID，不会签发到令牌中
+
+	// 管理员代登录时为发起代登录的管理员，UserId 为被代登录的用户
+	ActorID uint `json:"actor_id,omitempty"`
+
+	// 第三方应用的访问令牌
+	ClientID string `json:"client_id,omitempty"` // 令牌所属的客户端
+	Scope    string `json:"scope,omitempty"`     // 授权范围，空格分隔
 	jwt.StandardClaims
 }
 
-// 调用这个方法发放token
-func ReleaseToken(user model.User) (string, error) {
-	expirationTime := time.Now().Add(getJWTExpirationTime()) // 从配置获取过期时间
-	claims := &Claims{
+// 调用这个方法发放token，角色取自 user.Roles，调用前需要预加载
+func ReleaseToken(user model.User, amr ...string) (string, error) {
+	return releaseToken(&Claims{
 		UserId: user.ID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-			IssuedAt:  time.Now().Unix(), // token 发放的时间
-			Issuer:    getJWTIssuer(),    // 从配置获取签发者
-			Subject:   "user token",      // token 的主题
-		},
+		Roles:  RoleNames(user),
+		AMR:    amr,
+	}, getJWTExpirationTime())
+}
+
+// ReleaseMFAPendingToken 第一步认证通过后发放短期令牌，只能用于提交两步验证码，amr 为空时视为密码登录
+func ReleaseMFAPendingToken(user model.User, amr ...string) (string, error) {
+	if len(amr) == 0 {
+		amr = []string{AMRPassword}
+	}
+	return releaseToken(&Claims{
+		UserId:    user.ID,
+		TokenType: TokenTypeMFAPending,
+		AMR:       amr,
+	}, getMFAPendingExpirationTime())
+}
+
+// releaseToken 补全标准字段并签名
+func releaseToken(claims *Claims, ttl time.Duration) (string, error) {
+	jti, err := utils.GenerateOpaqueToken(16)
+	if err != nil {
+		return "生成token错误", err
+	}
+	expirationTime := time.Now().Add(ttl) // 从配置获取过期时间
+	claims.StandardClaims = jwt.StandardClaims{
+		Id:        jti,
+		ExpiresAt: expirationTime.Unix(),
+		IssuedAt:  time.Now().Unix(), // token 发放的时间
+		Issuer:    getJWTIssuer(),    // 从配置获取签发者
+		Subject:   "user token",      // token 的主题
+		Audience:  claims.Audience,   // 第三方应用的访问令牌为 client_id
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims) // 加密方式应该是hs256
-	tokenString, err := token.SignedString(getJWTKey())        // 从配置获取密钥
+	ks, err := GetKeySet() // 按配置选择 HS256 或非对称密钥
+	if err != nil {
+		return "生成token错误", err
+	}
+	tokenString, err := ks.sign(claims)
 	if err != nil {
 		return "生成token错误", err
 	}
@@ -66,8 +122,11 @@ func ReleaseToken(user model.User) (string, error) {
 // 解析 token 的方法
 func ParseToken(tokenstring string) (*jwt.Token, *Claims, error) { // '*' 号表示通过指针修改的内容，返回的也是token 的内存地址的内容。
 	claims := &Claims{} // 格式
-	token, err := jwt.ParseWithClaims(tokenstring, claims, func(token *jwt.Token) (i interface{}, err error) {
-		return getJWTKey(), nil
-	})
+	ks, err := GetKeySet()
+	if err != nil {
+		return nil, claims, err
+	}
+	// 根据 kid 选择密钥，并拒绝与密钥不匹配的 alg
+	token, err := jwt.ParseWithClaims(tokenstring, claims, ks.verificationKey)
 	return token, claims, err // 解析出claims 然会返回
 }
@@ -0,0 +1,327 @@
+package common
+
+// JWT 签名密钥管理：支持 HS256 共享密钥，以及 RS256/ES256/EdDSA 非对称密钥和密钥轮换
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"theing/gin-template/config"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// SigningKey 一把签名密钥
+type SigningKey struct {
+	Kid        string            // 密钥 ID，写入 token 头部的 kid
+	Method     jwt.SigningMethod // 签名算法
+	PrivateKey crypto.PrivateKey // 私钥，仅用于验证的密钥可以为空
+	PublicKey  crypto.PublicKey  // 公钥，发布到 JWKS
+	RetiredAt  time.Time         // 退役时间，之后不再签名，但在其签发的 token 过期前仍可验证
+}
+
+// KeySet 当前加载的签名密钥集合
+type KeySet struct {
+	Algorithm string                 // 配置的默认算法
+	Active    *SigningKey            // 当前用于签名的密钥，HS256 模式下为空
+	Keys      map[string]*SigningKey // 所有可用于验证的非对称密钥
+}
+
+// keyConfig 配置文件中的密钥定义
+type keyConfig = config.JWTKey
+
+// JWK JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var (
+	keySet   *KeySet
+	keySetMu sync.Mutex
+)
+
+// InitJWTKeys 根据配置加载签名密钥，启动时调用以便尽早发现配置错误
+func InitJWTKeys() error {
+	ks, err := loadKeySet()
+	if err != nil {
+		return err
+	}
+	keySetMu.Lock()
+	keySet = ks
+	keySetMu.Unlock()
+	return nil
+}
+
+// GetKeySet 获取签名密钥集合，尚未加载时按配置加载
+func GetKeySet() (*KeySet, error) {
+	keySetMu.Lock()
+	defer keySetMu.Unlock()
+	if keySet == nil {
+		ks, err := loadKeySet()
+		if err != nil {
+			return nil, err
+		}
+		keySet = ks
+	}
+	return keySet, nil
+}
+
+// loadKeySet 从配置读取算法与密钥文件
+func loadKeySet() (*KeySet, error) {
+	jwtConfig := config.Get().JWT
+	algorithm := jwtConfig.Algorithm
+	if algorithm == "" {
+		algorithm = jwt.SigningMethodHS256.Alg()
+	}
+	ks := &KeySet{Algorithm: algorithm, Keys: make(map[string]*SigningKey)}
+	if algorithm == jwt.SigningMethodHS256.Alg() {
+		return ks, nil
+	}
+
+	for _, cfg := range jwtConfig.Keys {
+		key, err := loadSigningKey(cfg, algorithm)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := ks.Keys[key.Kid]; exists {
+			return nil, fmt.Errorf("jwt 密钥 kid 重复: %s", key.Kid)
+		}
+		ks.Keys[key.Kid] = key
+	}
+
+	activeKid := jwtConfig.ActiveKid
+	active, ok := ks.Keys[activeKid]
+	if !ok {
+		return nil, fmt.Errorf("jwt.active_kid 未配置或找不到对应的密钥: %q", activeKid)
+	}
+	if active.PrivateKey == nil {
+		return nil, fmt.Errorf("当前签名密钥 %s 缺少私钥", activeKid)
+	}
+	if !active.RetiredAt.IsZero() {
+		return nil, fmt.Errorf("当前签名密钥 %s 已退役", activeKid)
+	}
+	ks.Active = active
+	return ks, nil
+}
+
+// loadSigningKey 从 PEM 文件加载一把密钥
+func loadSigningKey(cfg keyConfig, defaultAlgorithm string) (*SigningKey, error) {
+	if cfg.Kid == "" {
+		return nil, fmt.Errorf("jwt 密钥缺少 kid")
+	}
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = defaultAlgorithm
+	}
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("密钥 %s 使用了不支持的算法: %s", cfg.Kid, algorithm)
+	}
+
+	key := &SigningKey{Kid: cfg.Kid, Method: method}
+	if cfg.RetiredAt != "" {
+		retiredAt, err := time.Parse(time.RFC3339, cfg.RetiredAt)
+		if err != nil {
+			return nil, fmt.Errorf("密钥 %s 的 retired_at 格式错误，应为 RFC3339: %v", cfg.Kid, err)
+		}
+		key.RetiredAt = retiredAt
+	}
+
+	if cfg.PrivateKeyFile != "" {
+		data, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取密钥 %s 的私钥失败: %v", cfg.Kid, err)
+		}
+		if err := parsePrivateKey(key, data); err != nil {
+			return nil, fmt.Errorf("解析密钥 %s 的私钥失败: %v", cfg.Kid, err)
+		}
+	}
+	if cfg.PublicKeyFile != "" {
+		data, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取密钥 %s 的公钥失败: %v", cfg.Kid, err)
+		}
+		if err := parsePublicKey(key, data); err != nil {
+			return nil, fmt.Errorf("解析密钥 %s 的公钥失败: %v", cfg.Kid, err)
+		}
+	}
+	if key.PublicKey == nil {
+		return nil, fmt.Errorf("密钥 %s 至少需要配置私钥或公钥文件", cfg.Kid)
+	}
+	return key, nil
+}
+
+// parsePrivateKey 按算法解析私钥，并从私钥推导公钥
+func parsePrivateKey(key *SigningKey, data []byte) error {
+	switch key.Method.(type) {
+	case *jwt.SigningMethodRSA:
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return err
+		}
+		key.PrivateKey, key.PublicKey = privateKey, &privateKey.PublicKey
+	case *jwt.SigningMethodECDSA:
+		privateKey, err := jwt.ParseECPrivateKeyFromPEM(data)
+		if err != nil {
+			return err
+		}
+		if err := checkCurve(key.Method, privateKey.Curve); err != nil {
+			return err
+		}
+		key.PrivateKey, key.PublicKey = privateKey, &privateKey.PublicKey
+	case *jwt.SigningMethodEd25519:
+		privateKey, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return err
+		}
+		edKey := privateKey.(ed25519.PrivateKey)
+		key.PrivateKey, key.PublicKey = edKey, edKey.Public()
+	default:
+		return fmt.Errorf("算法 %s 不是非对称算法", key.Method.Alg())
+	}
+	return nil
+}
+
+// parsePublicKey 按算法解析公钥
+func parsePublicKey(key *SigningKey, data []byte) error {
+	switch key.Method.(type) {
+	case *jwt.SigningMethodRSA:
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return err
+		}
+		key.PublicKey = publicKey
+	case *jwt.SigningMethodECDSA:
+		publicKey, err := jwt.ParseECPublicKeyFromPEM(data)
+		if err != nil {
+			return err
+		}
+		if err := checkCurve(key.Method, publicKey.Curve); err != nil {
+			return err
+		}
+		key.PublicKey = publicKey
+	case *jwt.SigningMethodEd25519:
+		publicKey, err := jwt.ParseEdPublicKeyFromPEM(data)
+		if err != nil {
+			return err
+		}
+		key.PublicKey = publicKey
+	default:
+		return fmt.Errorf("算法 %s 不是非对称算法", key.Method.Alg())
+	}
+	return nil
+}
+
+// checkCurve 检查 ECDSA 曲线与算法是否匹配
+func checkCurve(method jwt.SigningMethod, curve elliptic.Curve) error {
+	expected := map[string]elliptic.Curve{
+		"ES256": elliptic.P256(),
+		"ES384": elliptic.P384(),
+		"ES512": elliptic.P521(),
+	}[method.Alg()]
+	if curve != expected {
+		return fmt.Errorf("算法 %s 与曲线 %s 不匹配", method.Alg(), curve.Params().Name)
+	}
+	return nil
+}
+
+// verificationKey 根据 token 头部的 kid 与 alg 选出验证用的密钥
+func (ks *KeySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	alg := token.Method.Alg()
+
+	if ks.Active == nil {
+		// HS256 模式只接受 HS256 签名
+		if alg != jwt.SigningMethodHS256.Alg() {
+			return nil, fmt.Errorf("不接受的签名算法: %s", alg)
+		}
+		return getJWTKey(), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.Keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("未知的密钥 kid: %q", kid)
+	}
+	if alg != key.Method.Alg() {
+		return nil, fmt.Errorf("密钥 %s 不接受算法 %s", kid, alg)
+	}
+	if !key.RetiredAt.IsZero() && time.Now().After(key.RetiredAt.Add(maxAccessTokenTTL())) {
+		return nil, fmt.Errorf("密钥 %s 已退役且其签发的 token 均已过期", kid)
+	}
+	return key.PublicKey, nil
+}
+
+// sign 使用当前签名密钥签发 token
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	if ks.Active == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString(getJWTKey())
+	}
+	token := jwt.NewWithClaims(ks.Active.Method, claims)
+	token.Header["kid"] = ks.Active.Kid
+	return token.SignedString(ks.Active.PrivateKey)
+}
+
+// GetJWKS 返回所有仍可用于验证的公钥
+func GetJWKS() (JWKS, error) {
+	jwks := JWKS{Keys: []JWK{}}
+	ks, err := GetKeySet()
+	if err != nil {
+		return jwks, err
+	}
+
+	for _, key := range ks.Keys {
+		if !key.RetiredAt.IsZero() && time.Now().After(key.RetiredAt.Add(maxAccessTokenTTL())) {
+			continue
+		}
+		jwk := JWK{Kid: key.Kid, Use: "sig", Alg: key.Method.Alg()}
+		switch publicKey := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64URL(publicKey.N.Bytes())
+			jwk.E = base64URL(big.NewInt(int64(publicKey.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (publicKey.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = publicKey.Curve.Params().Name
+			jwk.X = base64URL(publicKey.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64URL(publicKey.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64URL(publicKey)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks, nil
+}
+
+// base64URL 无填充的 base64url 编码
+func base64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,181 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+	"theing/gin-template/utils"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useConfig 修改当前配置，测试结束后恢复
+func useConfig(t *testing.T, update func(cfg *config.Config)) {
+	previous := config.Get()
+	cfg := *previous
+	update(&cfg)
+	config.Set(&cfg)
+	t.Cleanup(func() { config.Set(previous) })
+}
+
+// writeKeyPair 在临时目录生成密钥对，返回密钥配置
+func writeKeyPair(t *testing.T, algorithm, kid string) config.JWTKey {
+	privatePEM, publicPEM, err := utils.GenerateSigningKeyPair(algorithm)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, kid+".pem")
+	publicPath := filepath.Join(dir, kid+".pub.pem")
+	require.NoError(t, os.WriteFile(privatePath, privatePEM, 0o600))
+	require.NoError(t, os.WriteFile(publicPath, publicPEM, 0o644))
+
+	return config.JWTKey{
+		Kid:            kid,
+		Algorithm:      algorithm,
+		PrivateKeyFile: privatePath,
+		PublicKeyFile:  publicPath,
+	}
+}
+
+// useKeys 以指定密钥配置重新加载签名密钥
+func useKeys(t *testing.T, algorithm, activeKid string, keys ...config.JWTKey) {
+	t.Cleanup(func() { keySet = nil })
+	useConfig(t, func(cfg *config.Config) {
+		cfg.JWT.Algorithm = algorithm
+		cfg.JWT.ActiveKid = activeKid
+		cfg.JWT.Keys = keys
+	})
+	require.NoError(t, InitJWTKeys())
+}
+
+func TestReleaseToken_AsymmetricAlgorithms(t *testing.T) {
+	for _, algorithm := range []string{"RS256", "ES256", "EdDSA"} {
+		t.Run(algorithm, func(t *testing.T) {
+			useKeys(t, algorithm, "k1", writeKeyPair(t, algorithm, "k1"))
+
+			tokenString, err := ReleaseToken(model.User{ID: 42})
+			require.NoError(t, err)
+
+			token, claims, err := ParseToken(tokenString)
+			require.NoError(t, err)
+			assert.True(t, token.Valid)
+			assert.Equal(t, "k1", token.Header["kid"])
+			assert.Equal(t, algorithm, token.Method.Alg())
+			assert.Equal(t, uint(42), claims.UserId)
+			assert.NotEmpty(t, claims.Id)
+		})
+	}
+}
+
+func TestParseToken_RejectsUnexpectedAlgorithm(t *testing.T) {
+	useKeys(t, "RS256", "k1", writeKeyPair(t, "RS256", "k1"))
+
+	// 伪造一个使用 HS256 但声明 kid 的 token
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserId: 1})
+	forged.Header["kid"] = "k1"
+	tokenString, err := forged.SignedString([]byte("guess"))
+	require.NoError(t, err)
+
+	_, _, err = ParseToken(tokenString)
+	assert.Error(t, err)
+
+	// alg=none 同样必须拒绝
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, &Claims{UserId: 1})
+	none.Header["kid"] = "k1"
+	tokenString, err = none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	_, _, err = ParseToken(tokenString)
+	assert.Error(t, err)
+}
+
+func TestParseToken_HS256RejectsAsymmetricToken(t *testing.T) {
+	useKeys(t, "RS256", "k1", writeKeyPair(t, "RS256", "k1"))
+	rsToken, err := ReleaseToken(model.User{ID: 1})
+	require.NoError(t, err)
+
+	useConfig(t, func(cfg *config.Config) { cfg.JWT.Algorithm = "HS256" })
+	require.NoError(t, InitJWTKeys())
+
+	_, _, err = ParseToken(rsToken)
+	assert.Error(t, err)
+}
+
+func TestKeyRotation_RetiredKeyStillVerifies(t *testing.T) {
+	oldKey := writeKeyPair(t, "ES256", "old")
+	newKey := writeKeyPair(t, "EdDSA", "new")
+	useKeys(t, "ES256", "old", oldKey)
+
+	oldToken, err := ReleaseToken(model.User{ID: 7})
+	require.NoError(t, err)
+
+	// 轮换：启用新密钥，旧密钥刚刚退役
+	oldKey.RetiredAt = time.Now().Format(time.RFC3339)
+	useConfig(t, func(cfg *config.Config) {
+		cfg.JWT.ActiveKid = "new"
+		cfg.JWT.Keys = []config.JWTKey{oldKey, newKey}
+	})
+	require.NoError(t, InitJWTKeys())
+
+	_, claims, err := ParseToken(oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), claims.UserId)
+
+	newToken, err := ReleaseToken(model.User{ID: 8})
+	require.NoError(t, err)
+	token, _, err := ParseToken(newToken)
+	require.NoError(t, err)
+	assert.Equal(t, "new", token.Header["kid"])
+
+	jwks, err := GetJWKS()
+	require.NoError(t, err)
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "EC", jwks.Keys[1].Kty)
+
+	// 用户访问令牌较短时，旧密钥仍要保留到第三方应用的访问令牌也过期
+	oldKey.RetiredAt = time.Now().Add(-30 * time.Minute).Format(time.RFC3339)
+	useConfig(t, func(cfg *config.Config) {
+		cfg.JWT.AccessExpireMinutes = 15
+		cfg.OAuth.AccessExpireMinutes = 60
+		cfg.JWT.Keys = []config.JWTKey{oldKey, newKey}
+	})
+	require.NoError(t, InitJWTKeys())
+	_, _, err = ParseToken(oldToken)
+	require.NoError(t, err)
+
+	// 退役时间早于 token 最长有效期时，旧密钥不再可用
+	oldKey.RetiredAt = time.Now().Add(-2 * maxAccessTokenTTL()).Format(time.RFC3339)
+	useConfig(t, func(cfg *config.Config) { cfg.JWT.Keys = []config.JWTKey{oldKey, newKey} })
+	require.NoError(t, InitJWTKeys())
+
+	_, _, err = ParseToken(oldToken)
+	assert.Error(t, err)
+	jwks, err = GetJWKS()
+	require.NoError(t, err)
+	assert.Len(t, jwks.Keys, 1)
+}
+
+func TestInitJWTKeys_InvalidConfig(t *testing.T) {
+	t.Cleanup(func() { keySet = nil })
+	useConfig(t, func(cfg *config.Config) {
+		cfg.JWT.Algorithm = "RS256"
+		cfg.JWT.ActiveKid = "missing"
+	})
+	assert.Error(t, InitJWTKeys())
+
+	// ES256 算法不能使用 Ed25519 密钥
+	key := writeKeyPair(t, "EdDSA", "k1")
+	key.Algorithm = "ES256"
+	useConfig(t, func(cfg *config.Config) {
+		cfg.JWT.ActiveKid = "k1"
+		cfg.JWT.Keys = []config.JWTKey{key}
+	})
+	assert.Error(t, InitJWTKeys())
+}
@@ -0,0 +1,70 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// ready 服务是否可以接收流量，启动完成后置为 true，开始退出时置为 false
+var ready atomic.Bool
+
+// SetReady 设置就绪状态
+func SetReady(value bool) {
+	ready.Store(value)
+}
+
+// IsReady 服务是否就绪
+func IsReady() bool {
+	return ready.Load()
+}
+
+// Lifecycle 按启动顺序登记需要关闭的组件，退出时逆序关闭
+type Lifecycle struct {
+	mu         sync.Mutex
+	components []component
+}
+
+type component struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+// NewLifecycle 创建组件生命周期管理器
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{}
+}
+
+// Register 登记组件的关闭函数，应在组件启动成功后调用
+func (l *Lifecycle) Register(name string, close func(ctx context.Context) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.components = append(l.components, component{name: name, close: close})
+}
+
+// Shutdown 逆序关闭所有组件，后启动的组件可能依赖先启动的组件。
+// 单个组件关闭失败不影响其他组件，ctx 到期后不再关闭剩余组件，返回所有错误
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	components := l.components
+	l.components = nil
+	l.mu.Unlock()
+
+	var errs []error
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: 未关闭: %w", c.name, err))
+			continue
+		}
+		if err := c.close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+			continue
+		}
+		log.Printf("%s 已关闭", c.name)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifecycle_ShutdownInReverseOrder(t *testing.T) {
+	lifecycle := NewLifecycle()
+	var closed []string
+	for _, name := range []string{"db", "cache", "worker"} {
+		name := name
+		lifecycle.Register(name, func(context.Context) error {
+			closed = append(closed, name)
+			if name == "cache" {
+				return errors.New("boom")
+			}
+			return nil
+		})
+	}
+
+	err := lifecycle.Shutdown(context.Background())
+	assert.Equal(t, []string{"worker", "cache", "db"}, closed)
+	assert.EqualError(t, err, "cache: boom")
+
+	// 已关闭的组件不会再次关闭
+	assert.NoError(t, lifecycle.Shutdown(context.Background()))
+	assert.Len(t, closed, 3)
+}
+
+func TestLifecycle_ShutdownStopsAtDeadline(t *testing.T) {
+	lifecycle := NewLifecycle()
+	ctx, cancel := context.WithCancel(context.Background())
+	dbClosed := false
+	lifecycle.Register("db", func(context.Context) error { dbClosed = true; return nil })
+	lifecycle.Register("worker", func(ctx context.Context) error {
+		cancel() // 模拟等待任务退出时超时
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := lifecycle.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), "db: 未关闭")
+	assert.False(t, dbClosed)
+}
@@ -0,0 +1,260 @@
+package common
+
+// 登录失败计数、渐进延迟与临时锁定
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+	"time"
+)
+
+// 锁定范围
+const (
+	LockoutScopeAccount = "account" // 按手机号计数
+	LockoutScopeIP      = "ip"      // 按来源 IP 计数
+)
+
+// 缓存中的登录保护记录，按范围和标识分别保存。失败次数原子递增，并发的猜测不会少计
+const (
+	loginFailuresKind = "failures" // 窗口内的失败次数
+	loginDelayKind    = "delay"    // 渐进延迟：此前的尝试直接拒绝，值为截止时间
+	loginLockedKind   = "locked"   // 临时锁定，值为截止时间
+)
+
+// lockoutConfig 登录保护配置
+type lockoutConfig struct {
+	maxAccountFailures int           // 同一账号连续失败多少次后锁定
+	maxIPFailures      int           // 同一 IP 连续失败多少次后锁定
+	delayAfter         int           // 失败多少次后开始渐进延迟
+	maxDelay           time.Duration // 单次延迟上限
+	window             time.Duration // 失败计数的保留时间
+	lockoutDuration    time.Duration // 锁定时长
+}
+
+var (
+	dummyPasswordHash     string
+	dummyPasswordHashOnce sync.Once
+)
+
+// getLockoutConfig 读取登录保护配置
+func getLockoutConfig() lockoutConfig {
+	settings := config.Get().Lockout
+	cfg := lockoutConfig{
+		maxAccountFailures: settings.MaxAccountFailures,
+		maxIPFailures:      settings.MaxIPFailures,
+		delayAfter:         settings.DelayAfter,
+		maxDelay:           time.Duration(settings.MaxDelaySeconds) * time.Second,
+		window:             time.Duration(settings.WindowMinutes) * time.Minute,
+		lockoutDuration:    time.Duration(settings.LockoutMinutes) * time.Minute,
+	}
+	if cfg.maxAccountFailures <= 0 {
+		cfg.maxAccountFailures = 5
+	}
+	if cfg.maxIPFailures <= 0 {
+		cfg.maxIPFailures = 20
+	}
+	if cfg.delayAfter <= 0 {
+		cfg.delayAfter = 3
+	}
+	if cfg.maxDelay <= 0 {
+		cfg.maxDelay = 30 * time.Second
+	}
+	if cfg.window <= 0 {
+		cfg.window = 15 * time.Minute
+	}
+	if cfg.lockoutDuration <= 0 {
+		cfg.lockoutDuration = 15 * time.Minute
+	}
+	return cfg
+}
+
+// maxFailures 某个范围的锁定阈值
+func (c lockoutConfig) maxFailures(scope string) int {
+	if scope == LockoutScopeIP {
+		return c.maxIPFailures
+	}
+	return c.maxAccountFailures
+}
+
+// delayFor 第 failures 次失败后需要等待的时间，从 1 秒开始逐次翻倍
+func (c lockoutConfig) delayFor(failures int) time.Duration {
+	if failures < c.delayAfter {
+		return 0
+	}
+	delay := time.Duration(math.Pow(2, float64(failures-c.delayAfter))) * time.Second
+	if delay > c.maxDelay || delay <= 0 {
+		return c.maxDelay
+	}
+	return delay
+}
+
+// CheckLoginAllowed 登录前检查账号和 IP 是否处于锁定或延迟中。
+// 返回的错误 Details 为需要等待的秒数，HTTP 状态码为 429。
+// 缓存故障时无法判断是否锁定，拒绝登录并返回 503，避免在故障期间失去暴力破解保护
+func CheckLoginAllowed(ctx context.Context, account, ip string) *AppError {
+	now := time.Now()
+	for _, target := range loginTargets(account, ip) {
+		lockedUntil, err := loadLoginDeadline(ctx, loginLockedKind, target)
+		if err == nil && now.Before(lockedUntil) {
+			return throttleError(CodeAccountLocked, "登录失败次数过多，已被临时锁定", lockedUntil.Sub(now))
+		}
+		var nextAttemptAt time.Time
+		if err == nil {
+			nextAttemptAt, err = loadLoginDeadline(ctx, loginDelayKind, target)
+		}
+		if err != nil {
+			log.Printf("读取登录失败记录失败: %v", err)
+			appErr := NewAppError(CodeServiceUnavailable, "登录保护暂不可用，请稍后再试", "")
+			appErr.HTTPStatus = http.StatusServiceUnavailable
+			return appErr
+		}
+		if now.Before(nextAttemptAt) {
+			GetMetrics().RecordLoginFailure("throttled")
+			return throttleError(CodeTooManyRequests, "尝试过于频繁", nextAttemptAt.Sub(now))
+		}
+	}
+	return nil
+}
+
+// RecordLoginFailure 记录一次失败，达到阈值时锁定并写入锁定记录
+func RecordLoginFailure(ctx context.Context, account, ip, reason string) {
+	GetMetrics().RecordLoginFailure(reason)
+	cfg := getLockoutConfig()
+	cache := GetCache()
+	now := time.Now()
+
+	for _, target := range loginTargets(account, ip) {
+		count, err := cache.Incr(ctx, loginKey(loginFailuresKind, target), cfg.window)
+		if err != nil {
+			log.Printf("记录登录失败失败: %v", err)
+			continue
+		}
+		failures := int(count)
+		if delay := cfg.delayFor(failures); delay > 0 {
+			if err := saveLoginDeadline(ctx, loginDelayKind, target, now.Add(delay)); err != nil {
+				log.Printf("保存登录延迟失败: %v", err)
+			}
+		}
+		if failures < cfg.maxFailures(target.scope) {
+			continue
+		}
+
+		lockedUntil := now.Add(cfg.lockoutDuration)
+		if err := saveLoginDeadline(ctx, loginLockedKind, target, lockedUntil); err != nil {
+			log.Printf("保存登录锁定失败: %v", err)
+			continue
+		}
+		// 锁定期满后重新计数，并发请求中只有恰好达到阈值的一次写入锁定记录
+		if err := cache.Delete(ctx, loginKey(loginFailuresKind, target)); err != nil {
+			log.Printf("重置登录失败次数失败: %v", err)
+		}
+		if failures == cfg.maxFailures(target.scope) {
+			recordLockout(target.scope, target.identifier, ip, failures, lockedUntil)
+		}
+	}
+}
+
+// RecordLoginSuccess 登录成功后清除账号的失败记录，IP 的记录保留到过期
+func RecordLoginSuccess(ctx context.Context, account string) {
+	target := loginTarget{LockoutScopeAccount, account}
+	for _, kind := range []string{loginFailuresKind, loginDelayKind} {
+		if err := GetCache().Delete(ctx, loginKey(kind, target)); err != nil {
+			log.Printf("清除登录失败记录失败: %v", err)
+		}
+	}
+}
+
+// UnlockLogin 管理员手动解除锁定
+func UnlockLogin(ctx context.Context, scope, identifier string) error {
+	target := loginTarget{scope, identifier}
+	for _, kind := range []string{loginFailuresKind, loginDelayKind, loginLockedKind} {
+		if err := GetCache().Delete(ctx, loginKey(kind, target)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EqualizePasswordCheck 用户不存在时执行一次等价的哈希比较，避免通过响应时间判断手机号是否注册
+func EqualizePasswordCheck(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = HashPassword("equalize-password-check")
+	})
+	_, _, _ = VerifyPassword(dummyPasswordHash, password)
+}
+
+// loginTarget 需要计数的对象
+type loginTarget struct {
+	scope      string
+	identifier string
+}
+
+// loginTargets 一次登录同时按账号和 IP 计数
+func loginTargets(account, ip string) []loginTarget {
+	targets := make([]loginTarget, 0, 2)
+	if account != "" {
+		targets = append(targets, loginTarget{LockoutScopeAccount, account})
+	}
+	if ip != "" {
+		targets = append(targets, loginTarget{LockoutScopeIP, ip})
+	}
+	return targets
+}
+
+// loginKey 登录保护记录的缓存键
+func loginKey(kind string, target loginTarget) string {
+	return fmt.Sprintf("login:%s:%s:%s", kind, target.scope, target.identifier)
+}
+
+// loadLoginDeadline 读取延迟或锁定的截止时间，不存在时返回零值
+func loadLoginDeadline(ctx context.Context, kind string, target loginTarget) (time.Time, error) {
+	value, err := GetCache().Get(ctx, loginKey(kind, target))
+	if IsCacheMiss(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	unixMilli, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("无效的登录保护记录 %s: %v", loginKey(kind, target), err)
+	}
+	return time.UnixMilli(unixMilli), nil
+}
+
+// saveLoginDeadline 保存延迟或锁定的截止时间，记录在截止时间后自动过期
+func saveLoginDeadline(ctx context.Context, kind string, target loginTarget, deadline time.Time) error {
+	return GetCache().Set(ctx, loginKey(kind, target), strconv.FormatInt(deadline.UnixMilli(), 10), time.Until(deadline))
+}
+
+// recordLockout 记录锁定指标并写入锁定记录
+func recordLockout(scope, identifier, ip string, failures int, lockedUntil time.Time) {
+	GetMetrics().RecordLoginLockout(scope)
+	log.Printf("登录保护：%s %s 连续失败 %d 次，锁定至 %s", scope, identifier, failures, lockedUntil.Format(time.RFC3339))
+
+	event := model.LockoutEvent{
+		Scope:       scope,
+		Identifier:  identifier,
+		IP:          ip,
+		Failures:    failures,
+		LockedUntil: lockedUntil,
+	}
+	if err := GetDB().Create(&event).Error; err != nil {
+		log.Printf("保存锁定记录失败: %v", err)
+	}
+}
+
+// throttleError 构造带等待时间的 429 错误
+func throttleError(code ErrorCode, message string, wait time.Duration) *AppError {
+	seconds := int(math.Ceil(wait.Seconds()))
+	appErr := NewAppError(code, fmt.Sprintf("%s，请 %d 秒后再试", message, seconds), strconv.Itoa(seconds))
+	appErr.HTTPStatus = http.StatusTooManyRequests
+	return appErr
+}
@@ -0,0 +1,95 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"theing/gin-template/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockoutConfig_DelayFor(t *testing.T) {
+	cfg := lockoutConfig{delayAfter: 3, maxDelay: 10 * time.Second}
+
+	assert.Equal(t, time.Duration(0), cfg.delayFor(2))
+	assert.Equal(t, 1*time.Second, cfg.delayFor(3))
+	assert.Equal(t, 2*time.Second, cfg.delayFor(4))
+	assert.Equal(t, 8*time.Second, cfg.delayFor(6))
+	assert.Equal(t, 10*time.Second, cfg.delayFor(7))
+	assert.Equal(t, 10*time.Second, cfg.delayFor(200))
+}
+
+func TestLoginThrottle_ProgressiveDelay(t *testing.T) {
+	useConfig(t, func(cfg *config.Config) {
+		cfg.Lockout.DelayAfter = 2
+		cfg.Lockout.MaxAccountFailures = 100
+		cfg.Lockout.MaxIPFailures = 100
+	})
+	ctx := context.Background()
+	account, ip := "13812345678", "203.0.113.9"
+
+	require.Nil(t, CheckLoginAllowed(ctx, account, ip))
+	RecordLoginFailure(ctx, account, ip, "password")
+	assert.Nil(t, CheckLoginAllowed(ctx, account, ip), "第一次失败后不延迟")
+
+	RecordLoginFailure(ctx, account, ip, "password")
+	appErr := CheckLoginAllowed(ctx, account, ip)
+	require.NotNil(t, appErr)
+	assert.Equal(t, CodeTooManyRequests, appErr.Code)
+	assert.Equal(t, http.StatusTooManyRequests, appErr.HTTPStatus)
+	assert.Equal(t, "1", appErr.Details)
+
+	// 登录成功只清除账号的记录，同一 IP 仍需等待
+	RecordLoginSuccess(ctx, account)
+	assert.NotNil(t, CheckLoginAllowed(ctx, account, ip))
+	assert.Nil(t, CheckLoginAllowed(ctx, account, "198.51.100.7"))
+}
+
+func TestLoginThrottle_ConcurrentFailures(t *testing.T) {
+	useConfig(t, func(cfg *config.Config) {
+		cfg.Lockout.DelayAfter = 10
+		cfg.Lockout.MaxAccountFailures = 100
+		cfg.Lockout.MaxIPFailures = 100
+	})
+	ctx := context.Background()
+	account := "13812345679"
+
+	// 并发的失败都被计数，不会因为读改写互相覆盖而少计
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RecordLoginFailure(ctx, account, "", "password")
+		}()
+	}
+	wg.Wait()
+	failures, err := GetCache().Get(ctx, loginKey(loginFailuresKind, loginTarget{LockoutScopeAccount, account}))
+	require.NoError(t, err)
+	assert.Equal(t, "10", failures)
+	appErr := CheckLoginAllowed(ctx, account, "")
+	require.NotNil(t, appErr)
+	assert.Equal(t, CodeTooManyRequests, appErr.Code)
+
+	require.NoError(t, UnlockLogin(ctx, LockoutScopeAccount, account))
+	assert.Nil(t, CheckLoginAllowed(ctx, account, ""))
+}
+
+func TestCheckLoginAllowed_CacheErrorFailsClosed(t *testing.T) {
+	mockClient := new(MockCacheClient)
+	mockClient.On("Get", mock.Anything, mock.Anything).Return("", errors.New("connection refused"))
+	Cache = mockClient
+	t.Cleanup(func() { Cache = nil })
+
+	appErr := CheckLoginAllowed(context.Background(), "13812345670", "203.0.113.10")
+	require.NotNil(t, appErr)
+	assert.Equal(t, CodeServiceUnavailable, appErr.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, appErr.HTTPStatus)
+}
@@ -0,0 +1,81 @@
+package common
+
+// 日志输出：按 logging 配置设置级别、格式和输出位置，标准库 log 的输出按 info 级别写入同一处
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"theing/gin-template/config"
+)
+
+var (
+	logLevel = new(slog.LevelVar)
+	logMu    sync.Mutex
+	logFile  *os.File // output 为 file 时打开的日志文件，切换输出后关闭
+)
+
+// InitLogging 按配置设置默认日志，配置重新加载后再次调用即可切换级别、格式和输出位置
+func InitLogging(cfg config.LoggingConfig) error {
+	level, err := parseLogLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	var out io.Writer
+	var file *os.File
+	switch cfg.Output {
+	case "stderr":
+		out = os.Stderr
+	case "file":
+		if err := os.MkdirAll(filepath.Dir(cfg.File), 0o755); err != nil {
+			return fmt.Errorf("创建日志目录失败: %w", err)
+		}
+		file, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("打开日志文件失败: %w", err)
+		}
+		out = file
+	default:
+		out = os.Stdout
+	}
+
+	options := &slog.HandlerOptions{Level: logLevel}
+	var handler slog.Handler
+	if cfg.Format == "text" {
+		handler = slog.NewTextHandler(out, options)
+	} else {
+		handler = slog.NewJSONHandler(out, options)
+	}
+	logLevel.Set(level)
+	slog.SetDefault(slog.New(handler))
+
+	if logFile != nil {
+		_ = logFile.Close()
+	}
+	logFile = file
+	return nil
+}
+
+// parseLogLevel 解析配置中的日志级别，fatal 只输出比 error 更严重的日志
+func parseLogLevel(level string) (slog.Level, error) {
+	switch level {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	case "fatal":
+		return slog.LevelError + 4, nil
+	}
+	return 0, fmt.Errorf("不支持的日志级别: %s", level)
+}
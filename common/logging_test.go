@@ -0,0 +1,49 @@
+package common
+
+import (
+	"encoding/json"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"theing/gin-template/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitLogging(t *testing.T) {
+	defaultLogger, writer, flags := slog.Default(), log.Writer(), log.Flags()
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+	})
+
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	require.NoError(t, InitLogging(config.LoggingConfig{Level: "warn", Format: "json", Output: "file", File: path}))
+	slog.Info("dropped")
+	slog.Warn("kept", "user_id", 1)
+
+	// 级别可以在重新加载时调整，标准库 log 按 info 级别输出
+	require.NoError(t, InitLogging(config.LoggingConfig{Level: "info", Format: "json", Output: "file", File: path}))
+	log.Printf("from log")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "kept", record["msg"])
+	assert.Equal(t, float64(1), record["user_id"])
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "from log", record["msg"])
+
+	assert.Error(t, InitLogging(config.LoggingConfig{Level: "verbose"}))
+}
@@ -0,0 +1,134 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryCache 进程内缓存实现，Redis 不可用时使用，数据不会在多个实例间共享
+type MemoryCache struct {
+	mu    sync.Mutex
+	items map[string]memoryCacheItem
+	sets  int // 写入次数，用于定期清理过期条目
+}
+
+// memoryCacheItem 进程内缓存条目
+type memoryCacheItem struct {
+	value     string
+	expiresAt time.Time // 零值表示永不过期
+}
+
+var (
+	memoryCache     *MemoryCache
+	memoryCacheOnce sync.Once
+)
+
+// NewMemoryCache 创建进程内缓存
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{items: make(map[string]memoryCacheItem)}
+}
+
+// GetCache 获取缓存客户端，Redis 未初始化时退回到进程内缓存
+func GetCache() CacheClient {
+	if Cache != nil {
+		return Cache
+	}
+	memoryCacheOnce.Do(func() {
+		memoryCache = NewMemoryCache()
+	})
+	return memoryCache
+}
+
+// Get 获取缓存值
+func (m *MemoryCache) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok || item.expired(time.Now()) {
+		delete(m.items, key)
+		return "", ErrCacheNotFound
+	}
+	return item.value, nil
+}
+
+// Set 设置缓存值
+func (m *MemoryCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	item := memoryCacheItem{value: fmt.Sprint(value)}
+	if expiration > 0 {
+		item.expiresAt = time.Now().Add(expiration)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[key] = item
+	m.sets++
+	if m.sets%256 == 0 {
+		m.evictExpired()
+	}
+	return nil
+}
+
+// Delete 删除缓存
+func (m *MemoryCache) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, key)
+	return nil
+}
+
+// Exists 检查缓存是否存在
+func (m *MemoryCache) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := m.Get(ctx, key)
+	if IsCacheMiss(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Incr 递增计数
+func (m *MemoryCache) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	item, ok := m.items[key]
+	if !ok || item.expired(now) {
+		item = memoryCacheItem{value: "0"}
+		if expiration > 0 {
+			item.expiresAt = now.Add(expiration)
+		}
+	}
+	count, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("缓存 %s 的值不是整数", key)
+	}
+	count++
+	item.value = strconv.FormatInt(count, 10)
+	m.items[key] = item
+	return count, nil
+}
+
+// Flush 清空所有缓存
+func (m *MemoryCache) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items = make(map[string]memoryCacheItem)
+	return nil
+}
+
+// evictExpired 清理已过期的条目，调用方需持有锁
+func (m *MemoryCache) evictExpired() {
+	now := time.Now()
+	for key, item := range m.items {
+		if item.expired(now) {
+			delete(m.items, key)
+		}
+	}
+}
+
+// expired 判断条目是否已过期
+func (i memoryCacheItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && now.After(i.expiresAt)
+}
@@ -40,6 +40,10 @@ type Metrics struct {
 	UserRegistrations *prometheus.CounterVec
 	UserLogins        *prometheus.CounterVec
 	ActiveSessions    *prometheus.GaugeVec
+
+	// 安全指标
+	LoginFailures *prometheus.CounterVec
+	LoginLockouts *prometheus.CounterVec
 }
 
 var (
@@ -231,6 +235,24 @@ func InitMetrics() *Metrics {
 			},
 			[]string{"user_type"},
 		),
+
+		// 安全指标
+		LoginFailures: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "login_failures_total",
+				Help:        "Total number of failed login attempts",
+				ConstLabels: constLabels,
+			},
+			[]string{"reason"}, // password, mfa, throttled
+		),
+		LoginLockouts: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "login_lockouts_total",
+				Help:        "Total number of temporary login lockouts",
+				ConstLabels: constLabels,
+			},
+			[]string{"scope"}, // account, ip
+		),
 	}
 
 	return metrics
@@ -332,6 +354,16 @@ func (m *Metrics) UpdateActiveSessions(userType string, count int) {
 	m.ActiveSessions.WithLabelValues(userType).Set(float64(count))
 }
 
+// RecordLoginFailure 记录登录失败
+func (m *Metrics) RecordLoginFailure(reason string) {
+	m.LoginFailures.WithLabelValues(reason).Inc()
+}
+
+// RecordLoginLockout 记录登录锁定
+func (m *Metrics) RecordLoginLockout(scope string) {
+	m.LoginLockouts.WithLabelValues(scope).Inc()
+}
+
 // GetStatusCodeGroup 获取状态码分组
 func GetStatusCodeGroup(statusCode int) string {
 	switch {
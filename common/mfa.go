@@ -0,0 +1,300 @@
+package common
+
+// 两步验证（TOTP）的启用、校验与两步登录
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+	"theing/gin-template/utils"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount 每次生成的恢复码数量
+const recoveryCodeCount = 10
+
+// MFAEnrollment 开始启用两步验证时返回给客户端的信息
+type MFAEnrollment struct {
+	Secret string `json:"secret"`      // Base32 密钥，供无法扫码时手动输入
+	URI    string `json:"otpauth_uri"` // otpauth:// 地址
+	QRCode string `json:"qr_code"`     // 二维码 PNG，data URI 格式
+}
+
+// MFAChallenge 密码验证通过但需要两步验证时返回给客户端的信息
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"` // 固定为 true
+	MFAToken    string `json:"mfa_token"`    // 只能用于提交验证码的临时令牌
+	ExpiresIn   int64  `json:"expires_in"`   // 临时令牌有效期（秒）
+}
+
+// 获取两步验证临时令牌的过期时间
+func getMFAPendingExpirationTime() time.Duration {
+	minutes := config.Get().MFA.PendingExpireMinutes
+	if minutes <= 0 {
+		minutes = 5
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// 获取验证器应用中显示的签发者名称
+func getMFAIssuer() string {
+	if issuer := config.Get().MFA.Issuer; issuer != "" {
+		return issuer
+	}
+	return getJWTIssuer()
+}
+
+// 获取允许的时钟偏差（时间步数）
+func getMFASkew() int {
+	return config.Get().MFA.Skew
+}
+
+// AdminMFARequired 管理员路由是否要求两步验证
+func AdminMFARequired() bool {
+	return config.Get().MFA.RequireAdmin
+}
+
+// HasAMR 判断 claims 中是否包含指定的认证方式
+func HasAMR(claims *Claims, method string) bool {
+	for _, amr := range claims.AMR {
+		if amr == method {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMFAEnabled 判断用户是否已开启两步验证
+func IsMFAEnabled(userID uint) (bool, error) {
+	var count int64
+	err := GetDB().Model(&model.UserMFA{}).Where("user_id = ? AND enabled = ?", userID, true).Count(&count).Error
+	return count > 0, err
+}
+
+// IssueMFAChallenge 发放两步验证临时令牌，amr 为第一步使用的认证方式
+func IssueMFAChallenge(user model.User, amr ...string) (*MFAChallenge, error) {
+	token, err := ReleaseMFAPendingToken(user, amr...)
+	if err != nil {
+		return nil, err
+	}
+	return &MFAChallenge{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int64(getMFAPendingExpirationTime().Seconds()),
+	}, nil
+}
+
+// BeginMFAEnrollment 生成新的 TOTP 密钥，首次验证通过前不会启用
+func BeginMFAEnrollment(user model.User) (*MFAEnrollment, error) {
+	enabled, err := IsMFAEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	db := GetDB()
+	if err := db.Where("user_id = ?", user.ID).Delete(&model.UserMFA{}).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Create(&model.UserMFA{UserID: user.ID, Secret: secret}).Error; err != nil {
+		return nil, err
+	}
+
+	uri := utils.TOTPProvisioningURI(getMFAIssuer(), user.Telephone, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return nil, err
+	}
+	return &MFAEnrollment{
+		Secret: secret,
+		URI:    uri,
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// ActivateMFA 校验首个验证码并启用两步验证，返回一次性恢复码
+func ActivateMFA(userID uint, code string) ([]string, error) {
+	var mfa model.UserMFA
+	if err := GetDB().Where("user_id = ?", userID).Limit(1).Find(&mfa).Error; err != nil {
+		return nil, err
+	}
+	if mfa.ID == 0 {
+		return nil, ErrMFANotEnabled
+	}
+	if mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if err := consumeTOTP(mfa, code); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := GetDB().Model(&mfa).Updates(map[string]interface{}{"enabled": true, "enabled_at": now}).Error; err != nil {
+		return nil, err
+	}
+	return RegenerateRecoveryCodes(userID)
+}
+
+// VerifyMFACode 校验已启用用户的 TOTP 验证码，同一验证码不能使用两次
+func VerifyMFACode(userID uint, code string) error {
+	var mfa model.UserMFA
+	if err := GetDB().Where("user_id = ? AND enabled = ?", userID, true).Limit(1).Find(&mfa).Error; err != nil {
+		return err
+	}
+	if mfa.ID == 0 {
+		return ErrMFANotEnabled
+	}
+	return consumeTOTP(mfa, code)
+}
+
+// consumeTOTP 校验验证码并记录其时间步
+func consumeTOTP(mfa model.UserMFA, code string) error {
+	counter, ok := utils.ValidateTOTPCode(mfa.Secret, code, time.Now(), getMFASkew())
+	if !ok {
+		return ErrMFAInvalidCode
+	}
+	// 带条件更新，时间步不大于上次使用值的验证码视为重放
+	result := GetDB().Model(&model.UserMFA{}).
+		Where("id = ? AND last_counter < ?", mfa.ID, counter).
+		Update("last_counter", counter)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMFAInvalidCode
+	}
+	return nil
+}
+
+// UseRecoveryCode 使用一次性恢复码
+func UseRecoveryCode(userID uint, code string) error {
+	result := GetDB().Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMFAInvalidCode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes 作废旧的恢复码并生成新的一组，明文只返回这一次
+func RegenerateRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]model.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, model.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	}
+
+	err := GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableMFA 关闭两步验证并删除恢复码
+func DisableMFA(userID uint) error {
+	return GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.UserMFA{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+	})
+}
+
+// CompleteMFALogin 用临时令牌和验证码（或恢复码）换取正式的令牌对，临时令牌随即作废。
+// 验证码错误计入登录失败次数。
+func CompleteMFALogin(ctx context.Context, mfaToken, code, recoveryCode string, client ClientInfo) (*TokenPair, error) {
+	token, claims, err := ParseToken(mfaToken)
+	if err != nil || !token.Valid || claims.TokenType != TokenTypeMFAPending {
+		GetMetrics().RecordJWTValidationError("mfa_token_invalid")
+		return nil, ErrTokenInvalid
+	}
+	revoked, err := IsTokenRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	var user model.User
+	if err := GetDB().Preload("Roles").First(&user, claims.UserId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if appErr := CheckLoginAllowed(ctx, user.Telephone, client.IP); appErr != nil {
+		return nil, appErr
+	}
+
+	switch {
+	case code != "":
+		err = VerifyMFACode(user.ID, code)
+	case recoveryCode != "":
+		err = UseRecoveryCode(user.ID, recoveryCode)
+	default:
+		err = ErrMFAInvalidCode
+	}
+	if err == ErrMFAInvalidCode {
+		RecordLoginFailure(ctx, user.Telephone, client.IP, "mfa")
+	}
+	if err != nil {
+		return nil, err
+	}
+	RecordLoginSuccess(ctx, user.Telephone)
+	if err := CheckUserActive(user); err != nil {
+		return nil, err
+	}
+
+	if err := RevokeToken(ctx, claims); err != nil {
+		return nil, err
+	}
+	// 沿用第一步的认证方式（密码或第三方登录），再加上验证码
+	amr := append(append([]string{}, claims.AMR...), AMROTP)
+	return IssueTokenPair(user, client, amr...)
+}
+
+// generateRecoveryCode 生成 xxxx-xxxx 格式的恢复码
+func generateRecoveryCode() (string, error) {
+	bytes := make([]byte, 5)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	encoded := strings.ToLower(base32.StdEncoding.EncodeToString(bytes))
+	return encoded[:4] + "-" + encoded[4:8], nil
+}
+
+// hashRecoveryCode 忽略大小写和分隔符后计算哈希
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return utils.HashToken(normalized)
+}
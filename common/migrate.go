@@ -0,0 +1,182 @@
+package common
+
+// 数据库迁移：按 ID 顺序执行，已执行的迁移记录在 schema_migrations 表中
+
+import (
+	"fmt"
+	"sort"
+	"theing/gin-template/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 一次数据库结构变更。已发布的迁移不要再修改，模型变化时追加新的迁移，
+// 如 Up 中调用 tx.AutoMigrate(&model.User{}) 补充新增的列
+type Migration struct {
+	ID          string
+	Description string
+	Up          func(tx *gorm.DB) error
+	Down        func(tx *gorm.DB) error // 为 nil 时不可回滚
+}
+
+// MigrationState 迁移的执行状态，未执行时 AppliedAt 为 nil
+type MigrationState struct {
+	ID          string
+	Description string
+	AppliedAt   *time.Time
+}
+
+// schemaModels 0001 迁移创建的数据表
+var schemaModels = []interface{}{
+	&model.User{},
+	&model.Role{},
+	&model.Permission{},
+	&model.RefreshToken{},
+	&model.TokenRevocation{},
+	&model.UserMFA{},
+	&model.RecoveryCode{},
+	&model.LockoutEvent{},
+	&model.APIKey{},
+	&model.Session{},
+	&model.ExternalIdentity{},
+	&model.OAuthClient{},
+	&model.OAuthAuthorizationCode{},
+	&model.OAuthRefreshToken{},
+	&model.OAuthConsent{},
+	&model.PasswordHistory{},
+	&model.PrivacyRequest{},
+	&model.AuditLog{},
+}
+
+// migrations 按 ID 排序的全部迁移
+var migrations = []Migration{
+	{
+		ID:          "0001_create_tables",
+		Description: "创建数据表",
+		Up: func(tx *gorm.DB) error {
+			// 引入迁移之前由启动时的 AutoMigrate 建表，已有的表只会补充缺少的列和索引
+			return tx.AutoMigrate(schemaModels...)
+		},
+		Down: func(tx *gorm.DB) error {
+			tables := []interface{}{"user_roles", "role_permissions"}
+			for i := len(schemaModels) - 1; i >= 0; i-- {
+				tables = append(tables, schemaModels[i])
+			}
+			return tx.Migrator().DropTable(tables...)
+		},
+	},
+	{
+		ID:          "0002_migrate_legacy_users",
+		Description: "合并旧用户表的 tel 列，补齐创建时间和状态",
+		Up:          migrateUsers,
+		// 只修正数据，回滚时不恢复旧的 tel 列
+		Down: func(tx *gorm.DB) error { return nil },
+	},
+}
+
+// MigrateUp 依次执行未完成的迁移，每个迁移在单独的事务中执行，返回本次执行的迁移 ID
+func MigrateUp(db *gorm.DB) ([]string, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	var done []string
+	for _, m := range migrations {
+		if _, ok := applied[m.ID]; ok {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&model.SchemaMigration{ID: m.ID, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("执行迁移 %s 失败: %w", m.ID, err)
+		}
+		done = append(done, m.ID)
+	}
+	return done, nil
+}
+
+// MigrateDown 按执行的逆序回滚最近 steps 个迁移，返回本次回滚的迁移 ID
+func MigrateDown(db *gorm.DB, steps int) ([]string, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(applied))
+	for id := range applied {
+		ids = append(ids, id)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+	var done []string
+	for _, id := range ids {
+		if len(done) >= steps {
+			break
+		}
+		m, ok := findMigration(id)
+		if !ok {
+			return done, fmt.Errorf("数据库中记录的迁移 %s 不存在，无法回滚", id)
+		}
+		if m.Down == nil {
+			return done, fmt.Errorf("迁移 %s 不可回滚", id)
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			// 0001 回滚后记录表本身仍然保留
+			return tx.Delete(&model.SchemaMigration{ID: id}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("回滚迁移 %s 失败: %w", id, err)
+		}
+		done = append(done, id)
+	}
+	return done, nil
+}
+
+// MigrationStatus 各迁移的执行状态
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{ID: m.ID, Description: m.Description}
+		if at, ok := applied[m.ID]; ok {
+			state.AppliedAt = &at
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// appliedMigrations 已执行的迁移及执行时间，记录表不存在时创建
+func appliedMigrations(db *gorm.DB) (map[string]time.Time, error) {
+	if err := db.AutoMigrate(&model.SchemaMigration{}); err != nil {
+		return nil, err
+	}
+	var records []model.SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[string]time.Time, len(records))
+	for _, record := range records {
+		applied[record.ID] = record.AppliedAt
+	}
+	return applied, nil
+}
+
+func findMigration(id string) (Migration, bool) {
+	for _, m := range migrations {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
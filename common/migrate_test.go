@@ -0,0 +1,21 @@
+package common
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrations_OrderedAndUnique(t *testing.T) {
+	ids := make([]string, 0, len(migrations))
+	seen := make(map[string]bool)
+	for _, m := range migrations {
+		assert.False(t, seen[m.ID], "迁移 ID 重复: %s", m.ID)
+		assert.NotNil(t, m.Up, m.ID)
+		assert.NotEmpty(t, m.Description, m.ID)
+		seen[m.ID] = true
+		ids = append(ids, m.ID)
+	}
+	assert.True(t, sort.StringsAreSorted(ids), "迁移需要按 ID 排序")
+}
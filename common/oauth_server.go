@@ -0,0 +1,771 @@
+package common
+
+// OAuth2 授权服务：客户端登记、授权码 + PKCE、客户端凭证、刷新令牌、令牌自省与撤销
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+	"theing/gin-template/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 授权方式
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantClientCredentials = "client_credentials"
+	GrantRefreshToken      = "refresh_token"
+)
+
+// 内置范围，客户端还可以登记由资源服务自行解释的范围
+const (
+	OAuthScopeProfile = "profile" // 昵称
+	OAuthScopePhone   = "phone"   // 手机号
+)
+
+// oauthScopeDescriptions 授权确认页展示的范围说明
+var oauthScopeDescriptions = map[string]string{
+	OAuthScopeProfile: "读取您的昵称",
+	OAuthScopePhone:   "读取您的手机号",
+}
+
+// supportedGrantTypes 支持的授权方式
+var supportedGrantTypes = map[string]bool{
+	GrantAuthorizationCode: true,
+	GrantClientCredentials: true,
+	GrantRefreshToken:      true,
+}
+
+// OAuthError RFC 6749 格式的错误，授权和令牌接口按协议格式返回
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	Status      int    `json:"-"`
+}
+
+// Error 实现error接口
+func (e *OAuthError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// newOAuthError 创建协议错误，客户端认证失败返回 401，其余返回 400
+func newOAuthError(code, description string) *OAuthError {
+	status := http.StatusBadRequest
+	if code == "invalid_client" {
+		status = http.StatusUnauthorized
+	}
+	return &OAuthError{Code: code, Description: description, Status: status}
+}
+
+// OAuthScope 授权确认页展示的范围
+type OAuthScope struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// OAuthTokenResponse 令牌接口的响应（RFC 6749 5.1）
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// AuthorizeRequest 授权请求参数
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+}
+
+// OAuthClientRegistration 登记客户端的参数
+type OAuthClientRegistration struct {
+	Name         string
+	RedirectURIs []string
+	GrantTypes   []string
+	Scopes       []string
+	Public       bool
+	SkipConsent  bool
+}
+
+// 获取授权码有效期
+func getOAuthCodeTTL() time.Duration {
+	seconds := config.Get().OAuth.CodeTTLSeconds
+	if seconds <= 0 {
+		seconds = 300
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// 获取第三方应用访问令牌有效期，未配置时与用户访问令牌一致
+func getOAuthAccessTTL() time.Duration {
+	if minutes := config.Get().OAuth.AccessExpireMinutes; minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return getJWTExpirationTime()
+}
+
+// 获取第三方应用刷新令牌有效期
+func getOAuthRefreshTTL() time.Duration {
+	hours := config.Get().OAuth.RefreshExpireHours
+	if hours <= 0 {
+		hours = 720
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// RegisterOAuthClient 登记客户端，返回的明文密钥只出现这一次，公开客户端没有密钥
+func RegisterOAuthClient(reg OAuthClientRegistration, createdBy uint) (*model.OAuthClient, string, error) {
+	if len(reg.GrantTypes) == 0 {
+		reg.GrantTypes = []string{GrantAuthorizationCode, GrantRefreshToken}
+	}
+	for _, grant := range reg.GrantTypes {
+		if !supportedGrantTypes[grant] {
+			return nil, "", NewAppError(CodeInvalidParams, "不支持的授权方式", grant)
+		}
+		if grant == GrantClientCredentials && reg.Public {
+			return nil, "", NewAppError(CodeInvalidParams, "公开客户端不能使用 client_credentials", "")
+		}
+	}
+	if containsString(reg.GrantTypes, GrantAuthorizationCode) && len(reg.RedirectURIs) == 0 {
+		return nil, "", NewAppError(CodeInvalidParams, "授权码模式至少需要一个回调地址", "")
+	}
+	for _, redirectURI := range reg.RedirectURIs {
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || parsed.Scheme == "" || parsed.Fragment != "" || strings.ContainsAny(redirectURI, "\n") {
+			return nil, "", NewAppError(CodeInvalidParams, "回调地址必须是不含片段的绝对地址", redirectURI)
+		}
+	}
+	for _, scope := range reg.Scopes {
+		if scope == "" || strings.ContainsAny(scope, " \"\\") {
+			return nil, "", NewAppError(CodeInvalidParams, "范围格式错误", scope)
+		}
+	}
+
+	random, err := utils.GenerateOpaqueToken(12)
+	if err != nil {
+		return nil, "", err
+	}
+	client := model.OAuthClient{
+		ClientID:     "cli_" + random,
+		Name:         reg.Name,
+		RedirectURIs: strings.Join(reg.RedirectURIs, "\n"),
+		GrantTypes:   strings.Join(reg.GrantTypes, ","),
+		Scopes:       strings.Join(reg.Scopes, " "),
+		Public:       reg.Public,
+		SkipConsent:  reg.SkipConsent,
+		CreatedBy:    createdBy,
+	}
+	secret := ""
+	if !reg.Public {
+		if secret, err = utils.GenerateOpaqueToken(32); err != nil {
+			return nil, "", err
+		}
+		client.SecretHash = utils.HashToken(secret)
+	}
+	if err := GetDB().Create(&client).Error; err != nil {
+		return nil, "", err
+	}
+	return &client, secret, nil
+}
+
+// ListOAuthClients 查询全部客户端
+func ListOAuthClients() ([]model.OAuthClient, error) {
+	var clients []model.OAuthClient
+	err := GetDB().Order("id DESC").Find(&clients).Error
+	return clients, err
+}
+
+// RevokeOAuthClient 停用客户端并撤销其刷新令牌，已签发的访问令牌在自省时视为无效
+func RevokeOAuthClient(clientID string) error {
+	db := GetDB()
+	result := db.Model(&model.OAuthClient{}).Where("client_id = ? AND revoked_at IS NULL", clientID).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return NewAppError(CodeDataNotFound, "客户端不存在", clientID)
+	}
+	return db.Model(&model.OAuthRefreshToken{}).
+		Where("client_id = ? AND revoked_at IS NULL", clientID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// OAuthScopes 范围及说明
+func OAuthScopes(scope string) []OAuthScope {
+	scopes := make([]OAuthScope, 0)
+	for _, name := range strings.Fields(scope) {
+		description, ok := oauthScopeDescriptions[name]
+		if !ok {
+			description = name
+		}
+		scopes = append(scopes, OAuthScope{Name: name, Description: description})
+	}
+	return scopes
+}
+
+// findOAuthClient 查询未停用的客户端
+func findOAuthClient(clientID string) (*model.OAuthClient, error) {
+	var client model.OAuthClient
+	if err := GetDB().Where("client_id = ? AND revoked_at IS NULL", clientID).Limit(1).Find(&client).Error; err != nil {
+		return nil, err
+	}
+	if client.ID == 0 {
+		return nil, nil
+	}
+	return &client, nil
+}
+
+// AuthenticateOAuthClient 校验客户端身份，公开客户端只需要 client_id
+func AuthenticateOAuthClient(clientID, secret string) (*model.OAuthClient, error) {
+	if clientID == "" {
+		return nil, newOAuthError("invalid_client", "缺少客户端认证信息")
+	}
+	client, err := findOAuthClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, newOAuthError("invalid_client", "客户端不存在或已停用")
+	}
+	if client.Public {
+		if secret != "" {
+			return nil, newOAuthError("invalid_client", "公开客户端不应提交密钥")
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(utils.HashToken(secret))) != 1 {
+		return nil, newOAuthError("invalid_client", "客户端认证失败")
+	}
+	return client, nil
+}
+
+// ValidateAuthorizeRequest 校验授权请求。redirectable 为 false 表示客户端或回调地址不可信，
+// 错误只能直接返回给用户，不能重定向
+func ValidateAuthorizeRequest(req *AuthorizeRequest) (client *model.OAuthClient, scope string, redirectable bool, err error) {
+	client, err = findOAuthClient(req.ClientID)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if client == nil {
+		return nil, "", false, newOAuthError("invalid_request", "客户端不存在或已停用")
+	}
+
+	redirectURIs := strings.Split(client.RedirectURIs, "\n")
+	if req.RedirectURI == "" && len(redirectURIs) == 1 {
+		req.RedirectURI = redirectURIs[0]
+	}
+	if req.RedirectURI == "" || !containsString(redirectURIs, req.RedirectURI) {
+		return nil, "", false, newOAuthError("invalid_request", "回调地址未登记")
+	}
+
+	if req.ResponseType != "code" {
+		return client, "", true, newOAuthError("unsupported_response_type", "只支持 response_type=code")
+	}
+	if !oauthClientAllows(client, GrantAuthorizationCode) {
+		return client, "", true, newOAuthError("unauthorized_client", "客户端不允许使用授权码模式")
+	}
+	if req.CodeChallenge == "" && client.Public {
+		return client, "", true, newOAuthError("invalid_request", "公开客户端必须使用 PKCE")
+	}
+	if req.CodeChallenge != "" && req.CodeChallengeMethod != "S256" {
+		return client, "", true, newOAuthError("invalid_request", "code_challenge_method 只支持 S256")
+	}
+	scope, oauthErr := resolveOAuthScope(client, req.Scope, "")
+	if oauthErr != nil {
+		return client, "", true, oauthErr
+	}
+	return client, scope, true, nil
+}
+
+// OAuthConsentRequired 判断是否需要用户确认授权，之前同意过的范围不再询问
+func OAuthConsentRequired(userID uint, client *model.OAuthClient, scope string) (bool, error) {
+	if client.SkipConsent {
+		return false, nil
+	}
+	var consent model.OAuthConsent
+	if err := GetDB().Where("user_id = ? AND client_id = ?", userID, client.ClientID).Limit(1).Find(&consent).Error; err != nil {
+		return true, err
+	}
+	granted := strings.Fields(consent.Scope)
+	for _, name := range strings.Fields(scope) {
+		if !containsString(granted, name) {
+			return true, nil
+		}
+	}
+	return consent.ID == 0, nil
+}
+
+// ApproveAuthorization 记录用户同意的范围并生成授权码
+func ApproveAuthorization(userID uint, client *model.OAuthClient, req *AuthorizeRequest, scope string) (string, error) {
+	rawCode, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = GetDB().Transaction(func(tx *gorm.DB) error {
+		if !client.SkipConsent {
+			var consent model.OAuthConsent
+			if err := tx.Where("user_id = ? AND client_id = ?", userID, client.ClientID).Limit(1).Find(&consent).Error; err != nil {
+				return err
+			}
+			merged := strings.Fields(consent.Scope)
+			for _, name := range strings.Fields(scope) {
+				if !containsString(merged, name) {
+					merged = append(merged, name)
+				}
+			}
+			consent.UserID = userID
+			consent.ClientID = client.ClientID
+			consent.Scope = strings.Join(merged, " ")
+			if err := tx.Save(&consent).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&model.OAuthAuthorizationCode{
+			CodeHash:            utils.HashToken(rawCode),
+			ClientID:            client.ClientID,
+			UserID:              userID,
+			RedirectURI:         req.RedirectURI,
+			Scope:               scope,
+			CodeChallenge:       req.CodeChallenge,
+			CodeChallengeMethod: req.CodeChallengeMethod,
+			ExpiresAt:           time.Now().Add(getOAuthCodeTTL()),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return rawCode, nil
+}
+
+// BuildRedirectURI 在回调地址上追加参数
+func BuildRedirectURI(redirectURI string, params map[string]string) string {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	query := parsed.Query()
+	for key, value := range params {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// ExchangeAuthorizationCode 用授权码换取令牌，校验回调地址和 PKCE。
+// 授权码被重复使用时，撤销用它换取的刷新令牌。
+func ExchangeAuthorizationCode(client *model.OAuthClient, rawCode, redirectURI, verifier string) (*OAuthTokenResponse, error) {
+	if !oauthClientAllows(client, GrantAuthorizationCode) {
+		return nil, newOAuthError("unauthorized_client", "客户端不允许使用授权码模式")
+	}
+
+	var response *OAuthTokenResponse
+	var replayedFamily string
+	err := GetDB().Transaction(func(tx *gorm.DB) error {
+		var code model.OAuthAuthorizationCode
+		if err := tx.Where("code_hash = ? AND client_id = ?", utils.HashToken(rawCode), client.ClientID).Limit(1).Find(&code).Error; err != nil {
+			return err
+		}
+		if code.ID == 0 {
+			return newOAuthError("invalid_grant", "授权码无效")
+		}
+		if code.UsedAt != nil {
+			replayedFamily = code.FamilyID
+			return newOAuthError("invalid_grant", "授权码已被使用")
+		}
+		if time.Now().After(code.ExpiresAt) {
+			return newOAuthError("invalid_grant", "授权码已过期")
+		}
+		if code.RedirectURI != redirectURI {
+			return newOAuthError("invalid_grant", "redirect_uri 与授权请求不一致")
+		}
+		if code.CodeChallenge != "" && !verifyPKCE(code.CodeChallenge, verifier) {
+			return newOAuthError("invalid_grant", "code_verifier 校验失败")
+		}
+
+		if err := checkOAuthUser(tx, code.UserID); err != nil {
+			return err
+		}
+
+		familyID, err := utils.GenerateOpaqueToken(16)
+		if err != nil {
+			return err
+		}
+		// 带条件更新，防止并发请求同时使用同一个授权码
+		result := tx.Model(&model.OAuthAuthorizationCode{}).
+			Where("id = ? AND used_at IS NULL", code.ID).
+			Updates(map[string]interface{}{"used_at": time.Now(), "family_id": familyID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return newOAuthError("invalid_grant", "授权码已被使用")
+		}
+
+		response, err = issueOAuthTokens(tx, client, code.UserID, code.Scope, familyID)
+		return err
+	})
+
+	if replayedFamily != "" {
+		log.Printf("检测到授权码重放，撤销令牌族: %s", replayedFamily)
+		if revokeErr := revokeOAuthRefreshFamily(GetDB(), replayedFamily); revokeErr != nil {
+			log.Printf("撤销令牌族失败: %v", revokeErr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ClientCredentialsToken 客户端以自身身份获取访问令牌，不发放刷新令牌
+func ClientCredentialsToken(client *model.OAuthClient, requestedScope string) (*OAuthTokenResponse, error) {
+	if client.Public || !oauthClientAllows(client, GrantClientCredentials) {
+		return nil, newOAuthError("unauthorized_client", "客户端不允许使用 client_credentials")
+	}
+	scope, oauthErr := resolveOAuthScope(client, requestedScope, "")
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+	accessToken, err := releaseOAuthAccessToken(client, 0, scope)
+	if err != nil {
+		return nil, err
+	}
+	return &OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(getOAuthAccessTTL().Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// RefreshOAuthToken 轮换刷新令牌，可以申请比原来更小的范围。
+// 已经使用过的刷新令牌再次出现时，撤销整个令牌族。
+func RefreshOAuthToken(client *model.OAuthClient, rawToken, requestedScope string) (*OAuthTokenResponse, error) {
+	if !oauthClientAllows(client, GrantRefreshToken) {
+		return nil, newOAuthError("unauthorized_client", "客户端不允许使用刷新令牌")
+	}
+
+	var response *OAuthTokenResponse
+	var reusedFamily string
+	err := GetDB().Transaction(func(tx *gorm.DB) error {
+		var stored model.OAuthRefreshToken
+		if err := tx.Where("token_hash = ? AND client_id = ?", utils.HashToken(rawToken), client.ClientID).Limit(1).Find(&stored).Error; err != nil {
+			return err
+		}
+		if stored.ID == 0 || stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+			return newOAuthError("invalid_grant", "刷新令牌无效或已过期")
+		}
+		if stored.UsedAt != nil {
+			reusedFamily = stored.FamilyID
+			return newOAuthError("invalid_grant", "刷新令牌已被使用")
+		}
+		scope, oauthErr := resolveOAuthScope(client, requestedScope, stored.Scope)
+		if oauthErr != nil {
+			return oauthErr
+		}
+		if err := checkOAuthUser(tx, stored.UserID); err != nil {
+			return err
+		}
+
+		result := tx.Model(&model.OAuthRefreshToken{}).
+			Where("id = ? AND used_at IS NULL", stored.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			reusedFamily = stored.FamilyID
+			return newOAuthError("invalid_grant", "刷新令牌已被使用")
+		}
+
+		var err error
+		response, err = issueOAuthTokens(tx, client, stored.UserID, scope, stored.FamilyID)
+		return err
+	})
+
+	if reusedFamily != "" {
+		log.Printf("检测到第三方应用刷新令牌重放，撤销令牌族: %s", reusedFamily)
+		if revokeErr := revokeOAuthRefreshFamily(GetDB(), reusedFamily); revokeErr != nil {
+			log.Printf("撤销令牌族失败: %v", revokeErr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// IntrospectToken 令牌自省（RFC 7662），无效令牌只返回 active=false
+func IntrospectToken(ctx context.Context, client *model.OAuthClient, token string) (map[string]interface{}, error) {
+	inactive := map[string]interface{}{"active": false}
+
+	if claims, ok := parseOAuthAccessToken(ctx, token); ok {
+		result := map[string]interface{}{
+			"active":     true,
+			"token_type": "Bearer",
+			"scope":      claims.Scope,
+			"client_id":  claims.ClientID,
+			"exp":        claims.ExpiresAt,
+			"iat":        claims.IssuedAt,
+			"iss":        claims.Issuer,
+			"aud":        claims.Audience,
+			"jti":        claims.Id,
+		}
+		if claims.UserId != 0 {
+			result["sub"] = fmt.Sprint(claims.UserId)
+		} else {
+			result["sub"] = claims.ClientID
+		}
+		return result, nil
+	}
+
+	// 刷新令牌只允许所属客户端自省
+	var stored model.OAuthRefreshToken
+	if err := GetDB().Where("token_hash = ? AND client_id = ?", utils.HashToken(token), client.ClientID).Limit(1).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	if stored.ID == 0 || stored.UsedAt != nil || stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return inactive, nil
+	}
+	return map[string]interface{}{
+		"active":     true,
+		"token_type": "refresh_token",
+		"scope":      stored.Scope,
+		"client_id":  stored.ClientID,
+		"sub":        fmt.Sprint(stored.UserID),
+		"exp":        stored.ExpiresAt.Unix(),
+		"iat":        stored.CreatedAt.Unix(),
+	}, nil
+}
+
+// RevokeOAuthToken 撤销令牌（RFC 7009），只能撤销发给本客户端的令牌，未知令牌同样视为成功
+func RevokeOAuthToken(ctx context.Context, client *model.OAuthClient, token string) error {
+	if claims, ok := parseOAuthAccessToken(ctx, token); ok {
+		if claims.ClientID != client.ClientID {
+			return nil
+		}
+		return RevokeToken(ctx, claims)
+	}
+
+	var stored model.OAuthRefreshToken
+	if err := GetDB().Where("token_hash = ? AND client_id = ?", utils.HashToken(token), client.ClientID).Limit(1).Find(&stored).Error; err != nil {
+		return err
+	}
+	if stored.ID == 0 {
+		return nil
+	}
+	return revokeOAuthRefreshFamily(GetDB(), stored.FamilyID)
+}
+
+// ParseOAuthAccessToken 解析第三方应用的访问令牌，无效、过期或已撤销时返回 false
+func ParseOAuthAccessToken(ctx context.Context, token string) (*Claims, bool) {
+	return parseOAuthAccessToken(ctx, token)
+}
+
+// ListOAuthConsents 查询用户授权过的应用
+func ListOAuthConsents(userID uint) ([]model.OAuthConsent, error) {
+	var consents []model.OAuthConsent
+	err := GetDB().Where("user_id = ?", userID).Order("updated_at DESC").Find(&consents).Error
+	return consents, err
+}
+
+// RevokeOAuthConsent 取消对应用的授权，同时撤销该应用持有的刷新令牌
+func RevokeOAuthConsent(userID uint, clientID string) error {
+	return GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND client_id = ?", userID, clientID).Delete(&model.OAuthConsent{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return NewAppError(CodeDataNotFound, "授权记录不存在", "")
+		}
+		return tx.Model(&model.OAuthRefreshToken{}).
+			Where("user_id = ? AND client_id = ? AND revoked_at IS NULL", userID, clientID).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+// OAuthUserInfo 按访问令牌的范围返回用户信息
+func OAuthUserInfo(claims *Claims) (map[string]interface{}, error) {
+	var user model.User
+	if err := GetDB().First(&user, claims.UserId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if err := CheckUserActive(user); err != nil {
+		// RFC 6750：令牌代表的用户已不可用，按令牌无效处理
+		return nil, &OAuthError{Code: "invalid_token", Description: "用户已停用或注销", Status: http.StatusUnauthorized}
+	}
+	info := map[string]interface{}{"sub": fmt.Sprint(user.ID)}
+	scopes := strings.Fields(claims.Scope)
+	if containsString(scopes, OAuthScopeProfile) {
+		info["name"] = user.Username
+	}
+	if containsString(scopes, OAuthScopePhone) {
+		info["phone_number"] = user.Telephone
+		info["phone_number_verified"] = user.PhoneVerifiedAt != nil
+	}
+	return info, nil
+}
+
+// checkOAuthUser 兑换授权码和刷新令牌时要求用户仍然存在且状态正常
+func checkOAuthUser(tx *gorm.DB, userID uint) error {
+	var user model.User
+	if err := tx.Limit(1).Find(&user, userID).Error; err != nil {
+		return err
+	}
+	if user.ID == 0 {
+		return newOAuthError("invalid_grant", "用户不存在")
+	}
+	if err := CheckUserActive(user); err != nil {
+		return newOAuthError("invalid_grant", "用户已停用或注销")
+	}
+	return nil
+}
+
+// issueOAuthTokens 发放访问令牌，客户端允许时在指定令牌族下发放刷新令牌
+func issueOAuthTokens(tx *gorm.DB, client *model.OAuthClient, userID uint, scope, familyID string) (*OAuthTokenResponse, error) {
+	accessToken, err := releaseOAuthAccessToken(client, userID, scope)
+	if err != nil {
+		return nil, err
+	}
+	response := &OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(getOAuthAccessTTL().Seconds()),
+		Scope:       scope,
+	}
+	if !oauthClientAllows(client, GrantRefreshToken) {
+		return response, nil
+	}
+
+	rawToken, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Create(&model.OAuthRefreshToken{
+		TokenHash: utils.HashToken(rawToken),
+		FamilyID:  familyID,
+		ClientID:  client.ClientID,
+		UserID:    userID,
+		Scope:     scope,
+		ExpiresAt: time.Now().Add(getOAuthRefreshTTL()),
+	}).Error; err != nil {
+		return nil, err
+	}
+	response.RefreshToken = rawToken
+	return response, nil
+}
+
+// releaseOAuthAccessToken 签发第三方应用的访问令牌，userID 为 0 表示客户端自身
+func releaseOAuthAccessToken(client *model.OAuthClient, userID uint, scope string) (string, error) {
+	claims := &Claims{
+		UserId:    userID,
+		TokenType: TokenTypeOAuthAccess,
+		ClientID:  client.ClientID,
+		Scope:     scope,
+	}
+	claims.Audience = client.ClientID
+	return releaseToken(claims, getOAuthAccessTTL())
+}
+
+// parseOAuthAccessToken 解析并校验第三方应用的访问令牌，客户端停用后其访问令牌也视为无效
+func parseOAuthAccessToken(ctx context.Context, token string) (*Claims, bool) {
+	parsed, claims, err := ParseToken(token)
+	if err != nil || !parsed.Valid || claims.TokenType != TokenTypeOAuthAccess {
+		return nil, false
+	}
+	revoked, err := IsTokenRevoked(ctx, claims)
+	if err != nil || revoked {
+		return nil, false
+	}
+	// 用户停用或注销后，已签发的令牌也随之失效
+	if claims.UserId != 0 {
+		if err := checkOAuthUser(GetDB().WithContext(ctx), claims.UserId); err != nil {
+			return nil, false
+		}
+	}
+	client, err := findOAuthClient(claims.ClientID)
+	if err != nil || client == nil {
+		return nil, false
+	}
+	return claims, true
+}
+
+// revokeOAuthRefreshFamily 撤销第三方应用的刷新令牌族
+func revokeOAuthRefreshFamily(db *gorm.DB, familyID string) error {
+	if familyID == "" {
+		return nil
+	}
+	return db.Model(&model.OAuthRefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// resolveOAuthScope 校验申请的范围。未申请时使用 base（为空则为客户端全部范围），申请的范围不能超出 base
+func resolveOAuthScope(client *model.OAuthClient, requested, base string) (string, *OAuthError) {
+	if base == "" {
+		base = client.Scopes
+	}
+	allowed := strings.Fields(base)
+	if strings.TrimSpace(requested) == "" {
+		return strings.Join(allowed, " "), nil
+	}
+	scopes := make([]string, 0)
+	for _, name := range strings.Fields(requested) {
+		if !containsString(allowed, name) {
+			return "", newOAuthError("invalid_scope", "不允许申请的范围: "+name)
+		}
+		if !containsString(scopes, name) {
+			scopes = append(scopes, name)
+		}
+	}
+	return strings.Join(scopes, " "), nil
+}
+
+// oauthClientAllows 判断客户端是否允许使用某种授权方式
+func oauthClientAllows(client *model.OAuthClient, grant string) bool {
+	return containsString(strings.Split(client.GrantTypes, ","), grant)
+}
+
+// verifyPKCE 校验 S256 code_verifier（RFC 7636）
+func verifyPKCE(challenge, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(items []string, target string) bool {
+	for _, item := range items {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	assert.True(t, verifyPKCE(challenge, verifier))
+	assert.False(t, verifyPKCE(challenge, verifier+"x"))
+	assert.False(t, verifyPKCE(challenge, ""))
+	// verifier 长度不足 43 时直接拒绝
+	short := "too-short"
+	shortSum := sha256.Sum256([]byte(short))
+	assert.False(t, verifyPKCE(base64.RawURLEncoding.EncodeToString(shortSum[:]), short))
+}
+
+func TestResolveOAuthScope(t *testing.T) {
+	client := &model.OAuthClient{Scopes: "profile phone orders:read"}
+
+	scope, err := resolveOAuthScope(client, "", "")
+	require.Nil(t, err)
+	assert.Equal(t, "profile phone orders:read", scope)
+
+	scope, err = resolveOAuthScope(client, "phone phone profile", "")
+	require.Nil(t, err)
+	assert.Equal(t, "phone profile", scope)
+
+	_, err = resolveOAuthScope(client, "profile admin", "")
+	require.NotNil(t, err)
+	assert.Equal(t, "invalid_scope", err.Code)
+
+	// 刷新时只能缩小原来的范围
+	scope, err = resolveOAuthScope(client, "", "profile")
+	require.Nil(t, err)
+	assert.Equal(t, "profile", scope)
+	_, err = resolveOAuthScope(client, "phone", "profile")
+	require.NotNil(t, err)
+}
+
+func TestBuildRedirectURI(t *testing.T) {
+	redirect := BuildRedirectURI("https://app.example.com/cb?from=login", map[string]string{
+		"code":  "abc",
+		"state": "",
+	})
+	parsed, err := url.Parse(redirect)
+	require.NoError(t, err)
+	assert.Equal(t, "login", parsed.Query().Get("from"))
+	assert.Equal(t, "abc", parsed.Query().Get("code"))
+	_, hasState := parsed.Query()["state"]
+	assert.False(t, hasState)
+}
+
+func TestOAuthErrorStatus(t *testing.T) {
+	assert.Equal(t, 401, newOAuthError("invalid_client", "").Status)
+	assert.Equal(t, 400, newOAuthError("invalid_grant", "").Status)
+}
+
+// authorizeOAuthUser 登记客户端并走完授权码流程，返回客户端和首次发放的令牌
+func authorizeOAuthUser(t *testing.T, userID uint) (*model.OAuthClient, *OAuthTokenResponse) {
+	client, _, err := RegisterOAuthClient(OAuthClientRegistration{
+		Name:         "测试应用",
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		Scopes:       []string{"profile", "phone"},
+	}, 1)
+	require.NoError(t, err)
+
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	sum := sha256.Sum256([]byte(verifier))
+	req := &AuthorizeRequest{
+		RedirectURI:         "https://app.example.com/callback",
+		CodeChallenge:       base64.RawURLEncoding.EncodeToString(sum[:]),
+		CodeChallengeMethod: "S256",
+	}
+	code, err := ApproveAuthorization(userID, client, req, "profile")
+	require.NoError(t, err)
+
+	_, err = ExchangeAuthorizationCode(client, code, req.RedirectURI, "wrong-verifier-wrong-verifier-wrong-verifier")
+	assertOAuthErrorCode(t, err, "invalid_grant")
+
+	tokens, err := ExchangeAuthorizationCode(client, code, req.RedirectURI, verifier)
+	require.NoError(t, err)
+	require.NotEmpty(t, tokens.AccessToken)
+	require.NotEmpty(t, tokens.RefreshToken)
+	assert.Equal(t, "profile", tokens.Scope)
+	return client, tokens
+}
+
+// assertOAuthErrorCode 断言返回的是指定错误码的 OAuthError
+func assertOAuthErrorCode(t *testing.T, err error, code string) {
+	t.Helper()
+	var oauthErr *OAuthError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, code, oauthErr.Code)
+}
+
+func TestOAuth_ExchangeAndRefresh(t *testing.T) {
+	useKeys(t, "HS256", "")
+	db := useTestDB(t)
+	user := createTestUser(t, db, "13920000001")
+	client, tokens := authorizeOAuthUser(t, user.ID)
+
+	claims, ok := ParseOAuthAccessToken(context.Background(), tokens.AccessToken)
+	require.True(t, ok)
+	assert.Equal(t, user.ID, claims.UserId)
+	assert.Equal(t, client.ClientID, claims.ClientID)
+
+	refreshed, err := RefreshOAuthToken(client, tokens.RefreshToken, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+
+	// 不能扩大范围
+	_, err = RefreshOAuthToken(client, refreshed.RefreshToken, "profile phone")
+	assertOAuthErrorCode(t, err, "invalid_scope")
+
+	// 旧刷新令牌重放时撤销整个令牌族，新发放的刷新令牌也一并失效
+	_, err = RefreshOAuthToken(client, tokens.RefreshToken, "")
+	assertOAuthErrorCode(t, err, "invalid_grant")
+	_, err = RefreshOAuthToken(client, refreshed.RefreshToken, "")
+	assertOAuthErrorCode(t, err, "invalid_grant")
+}
+
+func TestOAuth_IntrospectAndRevoke(t *testing.T) {
+	useKeys(t, "HS256", "")
+	db := useTestDB(t)
+	user := createTestUser(t, db, "13920000002")
+	client, tokens := authorizeOAuthUser(t, user.ID)
+	ctx := context.Background()
+
+	result, err := IntrospectToken(ctx, client, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, true, result["active"])
+	assert.Equal(t, "profile", result["scope"])
+
+	result, err = IntrospectToken(ctx, client, tokens.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, "refresh_token", result["token_type"])
+
+	require.NoError(t, RevokeOAuthToken(ctx, client, tokens.AccessToken))
+	result, err = IntrospectToken(ctx, client, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, false, result["active"])
+
+	require.NoError(t, RevokeOAuthToken(ctx, client, tokens.RefreshToken))
+	result, err = IntrospectToken(ctx, client, tokens.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, false, result["active"])
+	_, err = RefreshOAuthToken(client, tokens.RefreshToken, "")
+	assertOAuthErrorCode(t, err, "invalid_grant")
+
+	// 未知令牌同样视为撤销成功
+	require.NoError(t, RevokeOAuthToken(ctx, client, "unknown"))
+}
+
+func TestOAuth_InactiveUserTokenRejected(t *testing.T) {
+	useKeys(t, "HS256", "")
+	db := useTestDB(t)
+	user := createTestUser(t, db, "13920000003")
+	client, tokens := authorizeOAuthUser(t, user.ID)
+
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", user.ID).Update("status", UserStatusSuspended).Error)
+
+	// 即使撤销记录已经不存在，停用用户的令牌也不再有效
+	result, err := IntrospectToken(context.Background(), client, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, false, result["active"])
+	_, err = RefreshOAuthToken(client, tokens.RefreshToken, "")
+	assertOAuthErrorCode(t, err, "invalid_grant")
+}
+
+func TestRevokeUserTokens_OutlivesLongestAccessToken(t *testing.T) {
+	db := useTestDB(t)
+	useConfig(t, func(cfg *config.Config) {
+		cfg.JWT.AccessExpireMinutes = 15
+		cfg.OAuth.AccessExpireMinutes = 60
+		cfg.Impersonation.MaxMinutes = 30
+	})
+	assert.Equal(t, 60*time.Minute, maxAccessTokenTTL())
+
+	require.NoError(t, RevokeUserTokens(context.Background(), 7))
+	var record model.TokenRevocation
+	require.NoError(t, db.Where("token_key = ?", userRevocationKey(7)).First(&record).Error)
+	assert.WithinDuration(t, time.Now().Add(60*time.Minute), record.ExpiresAt, time.Minute)
+}
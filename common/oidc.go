@@ -0,0 +1,399 @@
+package common
+
+// OIDC 第三方登录（授权码 + PKCE）
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"sync"
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+	"theing/gin-template/utils"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// AMROIDC 通过第三方账号登录时写入 claims 的认证方式
+const AMROIDC = "oidc"
+
+// OIDCProviderConfig 第三方登录提供方配置
+type OIDCProviderConfig = config.OIDCProvider
+
+// OIDCProviderInfo 返回给客户端的提供方信息
+type OIDCProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// OIDCIdentity 从 ID Token 中取得的第三方身份
+type OIDCIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Phone         string
+	PhoneVerified bool
+	Name          string
+}
+
+// oidcAuthState 发起授权时保存在缓存中的状态，回调时一次性取出
+type oidcAuthState struct {
+	Provider   string `json:"provider"`
+	Nonce      string `json:"nonce"`
+	Verifier   string `json:"verifier"`               // PKCE code_verifier
+	LinkUserID uint   `json:"link_user_id,omitempty"` // 不为 0 时表示为已登录用户绑定账号
+	// BindingHash 发起授权的浏览器 Cookie 中随机值的哈希，回调必须来自同一个浏览器，
+	// 防止攻击者把自己发起的授权交给受害者完成（登录或绑定 CSRF）
+	BindingHash string `json:"binding_hash"`
+}
+
+// OIDCAuthRequest 发起授权的结果
+type OIDCAuthRequest struct {
+	URL     string // 提供方的授权地址
+	Binding string // 写入发起授权的浏览器的 Cookie，回调时原样带回
+}
+
+// oidcClient 完成自动发现后的提供方客户端
+type oidcClient struct {
+	config   OIDCProviderConfig
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+var (
+	oidcClients   = map[string]*oidcClient{}
+	oidcClientsMu sync.Mutex
+)
+
+// GetOIDCStateTTL 获取授权状态的有效期
+func GetOIDCStateTTL() time.Duration {
+	minutes := config.Get().OIDC.StateTTLMinutes
+	if minutes <= 0 {
+		minutes = 10
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// OIDCProviders 列出已配置的提供方
+func OIDCProviders() []OIDCProviderInfo {
+	configs := config.Get().OIDC.Providers
+	providers := make([]OIDCProviderInfo, 0, len(configs))
+	for _, cfg := range configs {
+		name := cfg.DisplayName
+		if name == "" {
+			name = cfg.Name
+		}
+		providers = append(providers, OIDCProviderInfo{Name: cfg.Name, DisplayName: name})
+	}
+	return providers
+}
+
+// getOIDCClient 获取提供方客户端，首次使用时访问 issuer 完成自动发现
+func getOIDCClient(ctx context.Context, name string) (*oidcClient, error) {
+	oidcClientsMu.Lock()
+	defer oidcClientsMu.Unlock()
+	if client, ok := oidcClients[name]; ok {
+		return client, nil
+	}
+
+	for _, cfg := range config.Get().OIDC.Providers {
+		if cfg.Name != name {
+			continue
+		}
+		provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+		if err != nil {
+			return nil, NewAppError(CodeServiceUnavailable, "第三方登录服务暂不可用", err.Error())
+		}
+		scopes := []string{oidc.ScopeOpenID}
+		for _, scope := range cfg.Scopes {
+			if scope != oidc.ScopeOpenID {
+				scopes = append(scopes, scope)
+			}
+		}
+		client := &oidcClient{
+			config: cfg,
+			oauth2: &oauth2.Config{
+				ClientID:     cfg.ClientID,
+				ClientSecret: cfg.ClientSecret,
+				RedirectURL:  cfg.RedirectURL,
+				Endpoint:     provider.Endpoint(),
+				Scopes:       scopes,
+			},
+			verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		}
+		oidcClients[name] = client
+		return client, nil
+	}
+	return nil, NewAppError(CodeNotFound, "不支持的登录方式", name)
+}
+
+// resetOIDCClients 清空已发现的提供方，配置变更后调用
+func resetOIDCClients() {
+	oidcClientsMu.Lock()
+	oidcClients = map[string]*oidcClient{}
+	oidcClientsMu.Unlock()
+}
+
+// BeginOIDCAuth 生成 state、nonce 和 PKCE 参数，返回提供方的授权地址和绑定浏览器的随机值。
+// linkUserID 不为 0 时，回调后把第三方账号绑定到该用户。
+func BeginOIDCAuth(ctx context.Context, provider string, linkUserID uint) (*OIDCAuthRequest, error) {
+	client, err := getOIDCClient(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := utils.GenerateOpaqueToken(24)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := utils.GenerateOpaqueToken(24)
+	if err != nil {
+		return nil, err
+	}
+	binding, err := utils.GenerateOpaqueToken(24)
+	if err != nil {
+		return nil, err
+	}
+	authState := oidcAuthState{
+		Provider:    provider,
+		Nonce:       nonce,
+		Verifier:    oauth2.GenerateVerifier(),
+		LinkUserID:  linkUserID,
+		BindingHash: utils.HashToken(binding),
+	}
+	if err := saveCacheJSON(ctx, oidcStateKey(state), authState, GetOIDCStateTTL()); err != nil {
+		return nil, err
+	}
+
+	return &OIDCAuthRequest{
+		URL:     client.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(authState.Verifier)),
+		Binding: binding,
+	}, nil
+}
+
+// ExchangeOIDCCode 校验回调的 state 及其绑定的浏览器，用授权码和 code_verifier 换取并验证 ID Token。
+// binding 为发起授权时写入浏览器的随机值，返回第三方身份以及发起授权时指定的绑定用户。
+func ExchangeOIDCCode(ctx context.Context, provider, state, binding, code string) (*OIDCIdentity, uint, error) {
+	authState, err := takeOIDCState(ctx, state)
+	if err != nil {
+		return nil, 0, err
+	}
+	if binding == "" || subtle.ConstantTimeCompare([]byte(utils.HashToken(binding)), []byte(authState.BindingHash)) != 1 {
+		return nil, 0, NewAppError(CodeExternalLoginFailed, "第三方登录失败", "state 与发起授权的浏览器不匹配")
+	}
+	if authState.Provider != provider {
+		return nil, 0, NewAppError(CodeExternalLoginFailed, "第三方登录失败", "state 与提供方不匹配")
+	}
+	client, err := getOIDCClient(ctx, provider)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	token, err := client.oauth2.Exchange(ctx, code, oauth2.VerifierOption(authState.Verifier))
+	if err != nil {
+		return nil, 0, NewAppError(CodeExternalLoginFailed, "第三方登录失败", "授权码换取令牌失败: "+err.Error())
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, 0, NewAppError(CodeExternalLoginFailed, "第三方登录失败", "响应中缺少 id_token")
+	}
+	idToken, err := client.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, 0, NewAppError(CodeExternalLoginFailed, "第三方登录失败", "id_token 校验失败: "+err.Error())
+	}
+	if idToken.Nonce != authState.Nonce {
+		return nil, 0, NewAppError(CodeExternalLoginFailed, "第三方登录失败", "nonce 不匹配")
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Phone         string `json:"phone_number"`
+		PhoneVerified bool   `json:"phone_number_verified"`
+		Name          string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, 0, NewAppError(CodeExternalLoginFailed, "第三方登录失败", "id_token 格式错误: "+err.Error())
+	}
+	return &OIDCIdentity{
+		Provider:      provider,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Phone:         claims.Phone,
+		PhoneVerified: claims.PhoneVerified,
+		Name:          claims.Name,
+	}, authState.LinkUserID, nil
+}
+
+// ResolveOIDCUser 查找第三方身份对应的本地用户。
+// 未绑定时按已验证的手机号关联已有用户，配置 auto_create 时自动创建用户。
+func ResolveOIDCUser(identity *OIDCIdentity) (model.User, error) {
+	var user model.User
+	db := GetDB()
+
+	var linked model.ExternalIdentity
+	if err := db.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).Limit(1).Find(&linked).Error; err != nil {
+		return user, err
+	}
+	if linked.ID != 0 {
+		// 包含已注销的用户，由调用方按账号状态拒绝登录
+		if err := db.Unscoped().Preload("Roles").First(&user, linked.UserID).Error; err != nil {
+			return user, err
+		}
+		db.Model(&linked).Update("last_login_at", time.Now())
+		return user, nil
+	}
+
+	// 只信任提供方已验证的手机号，否则任何人都可以声明他人的手机号
+	phone := normalizeOIDCPhone(identity.Phone)
+	if phone == "" || !identity.PhoneVerified {
+		return user, NewAppError(CodeExternalLoginFailed, "该第三方账号尚未绑定，请先使用手机号登录后绑定", "")
+	}
+	if err := db.Preload("Roles").Where("telephone = ?", phone).Limit(1).Find(&user).Error; err != nil {
+		return user, err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if user.ID == 0 {
+			client, err := getOIDCClient(context.Background(), identity.Provider)
+			if err != nil {
+				return err
+			}
+			if !client.config.AutoCreate {
+				return NewAppError(CodeExternalLoginFailed, "该第三方账号尚未绑定，请先使用手机号注册后绑定", "")
+			}
+			if user, err = createOIDCUser(tx, identity, phone); err != nil {
+				return err
+			}
+		}
+		return createExternalIdentity(tx, user.ID, identity)
+	})
+	return user, err
+}
+
+// LinkOIDCIdentity 把第三方身份绑定到指定用户，已绑定到其他用户时拒绝
+func LinkOIDCIdentity(userID uint, identity *OIDCIdentity) error {
+	var linked model.ExternalIdentity
+	db := GetDB()
+	if err := db.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).Limit(1).Find(&linked).Error; err != nil {
+		return err
+	}
+	if linked.ID != 0 {
+		if linked.UserID == userID {
+			return nil
+		}
+		return NewAppError(CodeDataExists, "该第三方账号已绑定其他用户", "")
+	}
+	return createExternalIdentity(db, userID, identity)
+}
+
+// ListExternalIdentities 查询用户绑定的第三方账号
+func ListExternalIdentities(userID uint) ([]model.ExternalIdentity, error) {
+	var identities []model.ExternalIdentity
+	err := GetDB().Where("user_id = ?", userID).Order("id").Find(&identities).Error
+	return identities, err
+}
+
+// UnlinkExternalIdentity 解除绑定
+func UnlinkExternalIdentity(userID, identityID uint) error {
+	result := GetDB().Where("id = ? AND user_id = ?", identityID, userID).Delete(&model.ExternalIdentity{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return NewAppError(CodeDataNotFound, "绑定记录不存在", "")
+	}
+	return nil
+}
+
+// takeOIDCState 取出并删除授权状态，同一个 state 只能使用一次
+func takeOIDCState(ctx context.Context, state string) (*oidcAuthState, error) {
+	if state == "" {
+		return nil, NewAppError(CodeExternalLoginFailed, "第三方登录失败", "缺少 state")
+	}
+	key := oidcStateKey(state)
+	var authState oidcAuthState
+	if err := loadCacheJSON(ctx, key, &authState); err != nil {
+		return nil, err
+	}
+	if authState.Provider == "" {
+		return nil, NewAppError(CodeExternalLoginFailed, "第三方登录失败", "state 无效或已过期")
+	}
+	if err := GetCache().Delete(ctx, key); err != nil {
+		return nil, err
+	}
+	return &authState, nil
+}
+
+// createOIDCUser 按第三方身份自动创建用户，密码随机生成，用户可通过找回密码设置
+func createOIDCUser(tx *gorm.DB, identity *OIDCIdentity, phone string) (model.User, error) {
+	randomPassword, err := utils.GenerateOpaqueToken(24)
+	if err != nil {
+		return model.User{}, err
+	}
+	hashedPassword, err := HashPassword(randomPassword)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	name := identity.Name
+	if name == "" || len([]rune(name)) > 20 {
+		name = utils.RandomString(10)
+	}
+	now := time.Now()
+	user := model.User{
+		Username:        name,
+		Telephone:       phone,
+		Password:        hashedPassword,
+		PhoneVerifiedAt: &now,
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return user, err
+	}
+	if err := assignRole(tx, user.ID, RoleUser); err != nil {
+		return user, err
+	}
+	return user, tx.Preload("Roles").First(&user, user.ID).Error
+}
+
+// createExternalIdentity 写入绑定关系
+func createExternalIdentity(db *gorm.DB, userID uint, identity *OIDCIdentity) error {
+	now := time.Now()
+	err := db.Create(&model.ExternalIdentity{
+		UserID:      userID,
+		Provider:    identity.Provider,
+		Subject:     identity.Subject,
+		Email:       identity.Email,
+		Name:        identity.Name,
+		LastLoginAt: &now,
+	}).Error
+	if err != nil && errors.Is(err, gorm.ErrDuplicatedKey) {
+		return NewAppError(CodeDataExists, "该第三方账号已绑定其他用户", "")
+	}
+	return err
+}
+
+// normalizeOIDCPhone 把 E.164 格式的手机号转换为 11 位国内手机号，无法转换时返回空
+func normalizeOIDCPhone(phone string) string {
+	phone = strings.TrimPrefix(strings.ReplaceAll(phone, " ", ""), "+86")
+	if len(phone) != 11 {
+		return ""
+	}
+	for _, r := range phone {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	return phone
+}
+
+// oidcStateKey 授权状态的缓存键
+func oidcStateKey(state string) string {
+	return "oidc:state:" + state
+}
@@ -0,0 +1,299 @@
+package common
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"theing/gin-template/config"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOIDCProvider 进程内的 OIDC 提供方，支持自动发现、授权、换取令牌和 JWKS
+type fakeOIDCProvider struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu    sync.Mutex
+	codes map[string]fakeAuthCode
+
+	subject       string
+	phone         string
+	overrideNonce string // 不为空时签发错误的 nonce
+}
+
+// fakeAuthCode 授权码绑定的参数
+type fakeAuthCode struct {
+	nonce     string
+	challenge string
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p := &fakeOIDCProvider{
+		key:      key,
+		clientID: "test-client",
+		codes:    make(map[string]fakeAuthCode),
+		subject:  "external-user-1",
+		phone:    "+8613700000009",
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/jwks", p.jwks)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *fakeOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := p.server.URL
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"jwks_uri":                              issuer + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+// authorize 模拟用户同意授权，直接重定向回客户端
+func (p *fakeOIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != p.clientID || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	code := "code-" + query.Get("state")
+	p.mu.Lock()
+	p.codes[code] = fakeAuthCode{nonce: query.Get("nonce"), challenge: query.Get("code_challenge")}
+	p.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token 校验授权码和 PKCE 后签发 ID Token
+func (p *fakeOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	p.mu.Lock()
+	authCode, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != authCode.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	nonce := authCode.nonce
+	if p.overrideNonce != "" {
+		nonce = p.overrideNonce
+	}
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                   p.server.URL,
+		"aud":                   p.clientID,
+		"sub":                   p.subject,
+		"iat":                   now.Unix(),
+		"exp":                   now.Add(time.Hour).Unix(),
+		"nonce":                 nonce,
+		"name":                  "Alice",
+		"email":                 "alice@example.com",
+		"phone_number":          p.phone,
+		"phone_number_verified": true,
+	})
+	idToken.Header["kid"] = "fake"
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "fake-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+func (p *fakeOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "fake",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// useFakeOIDCProvider 启动提供方并写入配置，测试结束后清理
+func useFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	provider := newFakeOIDCProvider(t)
+	useConfig(t, func(cfg *config.Config) {
+		cfg.OIDC.Providers = []config.OIDCProvider{{
+			Name:         "fake",
+			Issuer:       provider.server.URL,
+			ClientID:     provider.clientID,
+			ClientSecret: "test-secret",
+			RedirectURL:  "http://app.local/api/auth/oidc/fake/callback",
+			Scopes:       []string{"profile", "phone"},
+		}}
+	})
+	resetOIDCClients()
+	t.Cleanup(resetOIDCClients)
+	return provider
+}
+
+// authorizeAt 访问授权地址并返回回调中的 code 和 state
+func authorizeAt(t *testing.T, authURL string) (string, string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestOIDC_AuthorizationCodeWithPKCE(t *testing.T) {
+	provider := useFakeOIDCProvider(t)
+	ctx := context.Background()
+
+	auth, err := BeginOIDCAuth(ctx, "fake", 0)
+	require.NoError(t, err)
+	parsed, err := url.Parse(auth.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "S256", parsed.Query().Get("code_challenge_method"))
+	assert.NotEmpty(t, parsed.Query().Get("nonce"))
+	assert.Contains(t, parsed.Query().Get("scope"), "openid")
+
+	code, state := authorizeAt(t, auth.URL)
+	identity, linkUserID, err := ExchangeOIDCCode(ctx, "fake", state, auth.Binding, code)
+	require.NoError(t, err)
+	assert.Equal(t, uint(0), linkUserID)
+	assert.Equal(t, provider.subject, identity.Subject)
+	assert.Equal(t, "fake", identity.Provider)
+	assert.True(t, identity.PhoneVerified)
+	assert.Equal(t, "13700000009", normalizeOIDCPhone(identity.Phone))
+
+	// state 只能使用一次
+	_, _, err = ExchangeOIDCCode(ctx, "fake", state, auth.Binding, code)
+	assertAppErrorCode(t, err, CodeExternalLoginFailed)
+}
+
+func TestOIDC_LinkUserCarriedInState(t *testing.T) {
+	useFakeOIDCProvider(t)
+	ctx := context.Background()
+
+	auth, err := BeginOIDCAuth(ctx, "fake", 42)
+	require.NoError(t, err)
+	code, state := authorizeAt(t, auth.URL)
+
+	_, linkUserID, err := ExchangeOIDCCode(ctx, "fake", state, auth.Binding, code)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), linkUserID)
+}
+
+func TestOIDC_RejectsTamperedChallenge(t *testing.T) {
+	useFakeOIDCProvider(t)
+	ctx := context.Background()
+
+	auth, err := BeginOIDCAuth(ctx, "fake", 0)
+	require.NoError(t, err)
+	// 授权请求中的 code_challenge 被替换后，保存的 code_verifier 无法通过校验
+	sum := sha256.Sum256([]byte("attacker-verifier"))
+	tampered := strings.Replace(auth.URL, "code_challenge=", "code_challenge="+base64.RawURLEncoding.EncodeToString(sum[:])+"&x=", 1)
+	code, state := authorizeAt(t, tampered)
+
+	_, _, err = ExchangeOIDCCode(ctx, "fake", state, auth.Binding, code)
+	assertAppErrorCode(t, err, CodeExternalLoginFailed)
+}
+
+func TestOIDC_RejectsNonceMismatch(t *testing.T) {
+	provider := useFakeOIDCProvider(t)
+	provider.overrideNonce = "replayed-nonce"
+	ctx := context.Background()
+
+	auth, err := BeginOIDCAuth(ctx, "fake", 0)
+	require.NoError(t, err)
+	code, state := authorizeAt(t, auth.URL)
+
+	_, _, err = ExchangeOIDCCode(ctx, "fake", state, auth.Binding, code)
+	assertAppErrorCode(t, err, CodeExternalLoginFailed)
+	assert.Contains(t, err.(*AppError).Details, "nonce")
+}
+
+func TestOIDC_RejectsCallbackFromAnotherBrowser(t *testing.T) {
+	useFakeOIDCProvider(t)
+	ctx := context.Background()
+
+	// 攻击者发起授权，受害者的浏览器没有对应的绑定 Cookie
+	for _, binding := range []string{"", "victim-binding"} {
+		auth, err := BeginOIDCAuth(ctx, "fake", 0)
+		require.NoError(t, err)
+		code, state := authorizeAt(t, auth.URL)
+
+		_, _, err = ExchangeOIDCCode(ctx, "fake", state, binding, code)
+		assertAppErrorCode(t, err, CodeExternalLoginFailed)
+		assert.Contains(t, err.(*AppError).Details, "浏览器")
+
+		// 校验失败后 state 作废，攻击者不能再用它完成登录
+		_, _, err = ExchangeOIDCCode(ctx, "fake", state, auth.Binding, code)
+		assertAppErrorCode(t, err, CodeExternalLoginFailed)
+	}
+}
+
+func TestOIDC_RejectsUnknownStateAndProvider(t *testing.T) {
+	useFakeOIDCProvider(t)
+	ctx := context.Background()
+
+	_, _, err := ExchangeOIDCCode(ctx, "fake", "unknown-state", "binding", "code")
+	assertAppErrorCode(t, err, CodeExternalLoginFailed)
+
+	_, err = BeginOIDCAuth(ctx, "missing", 0)
+	assertAppErrorCode(t, err, CodeNotFound)
+}
+
+// assertAppErrorCode 断言错误为指定错误码的 AppError
+func assertAppErrorCode(t *testing.T, err error, code ErrorCode) {
+	t.Helper()
+	require.Error(t, err)
+	appErr, ok := err.(*AppError)
+	require.True(t, ok, "期望 AppError，实际为 %T: %v", err, err)
+	assert.Equal(t, code, appErr.Code)
+}
@@ -0,0 +1,264 @@
+package common
+
+// 密码哈希：支持 Argon2id 与 bcrypt，哈希值自带算法和参数，登录时自动升级旧哈希
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 密码哈希算法
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+// ErrUnknownPasswordHash 无法识别的哈希格式
+var ErrUnknownPasswordHash = errors.New("无法识别的密码哈希格式")
+
+// PasswordHasher 密码哈希算法
+type PasswordHasher interface {
+	// Algorithm 算法名称
+	Algorithm() string
+	// Hash 计算密码哈希，返回自描述格式的字符串
+	Hash(password string) (string, error)
+	// Verify 校验密码是否与哈希一致
+	Verify(encoded, password string) (bool, error)
+	// NeedsRehash 哈希使用的参数是否弱于当前配置
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2idHasher Argon2id 哈希，使用 PHC 字符串格式：$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type Argon2idHasher struct {
+	Memory      uint32 // 内存（KiB）
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Algorithm 算法名称
+func (h *Argon2idHasher) Algorithm() string {
+	return PasswordAlgorithmArgon2id
+}
+
+// Hash 计算密码哈希
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify 按哈希中记录的参数重新计算并比较
+func (h *Argon2idHasher) Verify(encoded, password string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1, nil
+}
+
+// NeedsRehash 任一参数低于当前配置时需要升级
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Memory < h.Memory ||
+		params.Iterations < h.Iterations ||
+		params.Parallelism < h.Parallelism ||
+		uint32(len(salt)) < h.SaltLength ||
+		uint32(len(key)) < h.KeyLength
+}
+
+// bcryptMaxPasswordBytes bcrypt 能处理的最大密码长度，超出时 GenerateFromPassword 直接报错
+const bcryptMaxPasswordBytes = 72
+
+// BcryptHasher bcrypt 哈希，格式为 $2a$<cost>$...
+type BcryptHasher struct {
+	Cost int
+}
+
+// Algorithm 算法名称
+func (h *BcryptHasher) Algorithm() string {
+	return PasswordAlgorithmBcrypt
+}
+
+// Hash 计算密码哈希
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify 校验密码
+func (h *BcryptHasher) Verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// NeedsRehash cost 低于当前配置时需要升级
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < h.Cost
+}
+
+// GetPasswordHasher 按配置返回新密码使用的哈希算法
+func GetPasswordHasher() PasswordHasher {
+	if config.Get().Password.Algorithm == PasswordAlgorithmBcrypt {
+		return newBcryptHasher()
+	}
+	return newArgon2idHasher()
+}
+
+// HashPassword 使用配置的算法计算密码哈希
+func HashPassword(password string) (string, error) {
+	return GetPasswordHasher().Hash(password)
+}
+
+// VerifyPassword 按哈希格式选择算法校验密码，rehash 表示哈希的算法或参数已过时
+func VerifyPassword(encoded, password string) (match bool, rehash bool, err error) {
+	hasher := hasherFor(encoded)
+	if hasher == nil {
+		return false, false, ErrUnknownPasswordHash
+	}
+	match, err = hasher.Verify(encoded, password)
+	if err != nil || !match {
+		return false, false, err
+	}
+	current := GetPasswordHasher()
+	rehash = hasher.Algorithm() != current.Algorithm() || current.NeedsRehash(encoded)
+	return true, rehash, nil
+}
+
+// CheckUserPassword 校验用户密码，通过后如果哈希已过时则用当前配置重新计算并保存。
+// 升级失败只记录日志，不影响本次登录。
+func CheckUserPassword(user *model.User, password string) bool {
+	match, rehash, err := VerifyPassword(user.Password, password)
+	if err != nil {
+		log.Printf("校验用户 %d 的密码失败: %v", user.ID, err)
+		return false
+	}
+	if !match {
+		return false
+	}
+	if rehash {
+		hashed, err := HashPassword(password)
+		if err != nil {
+			log.Printf("升级用户 %d 的密码哈希失败: %v", user.ID, err)
+			return true
+		}
+		if err := GetDB().Model(&model.User{}).Where("id = ?", user.ID).Update("password", hashed).Error; err != nil {
+			log.Printf("保存用户 %d 的密码哈希失败: %v", user.ID, err)
+			return true
+		}
+		user.Password = hashed
+	}
+	return true
+}
+
+// argon2idParams 哈希中记录的参数
+type argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// decodeArgon2id 解析 PHC 格式的 Argon2id 哈希
+func decodeArgon2id(encoded string) (argon2idParams, []byte, []byte, error) {
+	var params argon2idParams
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != PasswordAlgorithmArgon2id {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("不支持的 argon2 版本: %s", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("argon2 参数格式错误: %w", err)
+	}
+	if params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, fmt.Errorf("argon2 参数格式错误: %s", parts[3])
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("argon2 盐值格式错误: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("argon2 哈希格式错误: %v", err)
+	}
+	return params, salt, key, nil
+}
+
+// hasherFor 按哈希前缀选择算法，参数从哈希自身读取
+func hasherFor(encoded string) PasswordHasher {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return newArgon2idHasher()
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return newBcryptHasher()
+	}
+	return nil
+}
+
+// newArgon2idHasher 读取 password.argon2id 配置
+func newArgon2idHasher() *Argon2idHasher {
+	cfg := config.Get().Password.Argon2id
+	h := &Argon2idHasher{
+		Memory:      uint32(cfg.MemoryKiB),
+		Iterations:  uint32(cfg.Iterations),
+		Parallelism: uint8(cfg.Parallelism),
+		SaltLength:  uint32(cfg.SaltLength),
+		KeyLength:   uint32(cfg.KeyLength),
+	}
+	if h.Memory == 0 {
+		h.Memory = 64 * 1024
+	}
+	if h.Iterations == 0 {
+		h.Iterations = 3
+	}
+	if h.Parallelism == 0 {
+		h.Parallelism = 2
+	}
+	if h.SaltLength < 16 {
+		h.SaltLength = 16
+	}
+	if h.KeyLength < 16 {
+		h.KeyLength = 32
+	}
+	return h
+}
+
+// newBcryptHasher 读取 password.bcrypt.cost 配置
+func newBcryptHasher() *BcryptHasher {
+	cost := config.Get().Password.Bcrypt.Cost
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{Cost: cost}
+}
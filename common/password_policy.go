@@ -0,0 +1,365 @@
+package common
+
+// 密码策略：长度、字符类型、泄露密码库、历史密码、有效期以及与账号信息的相似度
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// 违反密码策略的原因，前端可以按 code 和 params 做本地化
+const (
+	PasswordTooShort          = "too_short"
+	PasswordTooLong           = "too_long"
+	PasswordTooManyBytes      = "too_many_bytes"
+	PasswordMissingLowercase  = "missing_lowercase"
+	PasswordMissingUppercase  = "missing_uppercase"
+	PasswordMissingDigit      = "missing_digit"
+	PasswordMissingSpecial    = "missing_special"
+	PasswordTooFewClasses     = "too_few_character_classes"
+	PasswordBreached          = "breached"
+	PasswordReused            = "reused"
+	PasswordSimilarToIdentity = "similar_to_identity"
+)
+
+// passwordViolationMessages 默认的中文提示，{name} 替换为 params 中的值
+var passwordViolationMessages = map[string]string{
+	PasswordTooShort:          "密码长度不能少于{min}位",
+	PasswordTooLong:           "密码长度不能超过{max}位",
+	PasswordTooManyBytes:      "密码编码后不能超过{max}字节，请减少中文等非 ASCII 字符",
+	PasswordMissingLowercase:  "密码必须包含小写字母",
+	PasswordMissingUppercase:  "密码必须包含大写字母",
+	PasswordMissingDigit:      "密码必须包含数字",
+	PasswordMissingSpecial:    "密码必须包含特殊字符",
+	PasswordTooFewClasses:     "密码必须包含小写字母、大写字母、数字、特殊字符中的至少{min}种",
+	PasswordBreached:          "该密码已出现在泄露的密码库中",
+	PasswordReused:            "不能使用最近{count}次用过的密码",
+	PasswordSimilarToIdentity: "密码不能包含用户名或手机号",
+}
+
+// PasswordViolation 一条不满足的策略
+type PasswordViolation struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// PasswordPolicy 密码策略，从 password.policy 配置读取
+type PasswordPolicy struct {
+	MinLength           int
+	MaxLength           int
+	MaxBytes            int // 按 UTF-8 字节计的长度上限，bcrypt 只能处理 72 字节，0 表示不限制
+	RequireLowercase    bool
+	RequireUppercase    bool
+	RequireDigit        bool
+	RequireSpecial      bool
+	MinCharacterClasses int    // 四类字符中至少包含几类，0 表示不限制
+	BreachedListFile    string // 泄露密码库，每行一个明文密码或 SHA-1 十六进制摘要
+	HistorySize         int    // 禁止重复使用最近几次的密码，0 表示不限制
+	MaxAgeDays          int    // 密码有效期（天），0 表示永久有效
+	CheckSimilarity     bool   // 禁止包含用户名或手机号
+}
+
+// PasswordSubject 设置密码的用户，新用户的 UserID 为 0
+type PasswordSubject struct {
+	UserID    uint
+	Username  string
+	Telephone string
+}
+
+// GetPasswordPolicy 读取密码策略
+func GetPasswordPolicy() PasswordPolicy {
+	settings := config.Get().Password.Policy
+	policy := PasswordPolicy{
+		MinLength:           settings.MinLength,
+		MaxLength:           settings.MaxLength,
+		RequireLowercase:    settings.RequireLowercase,
+		RequireUppercase:    settings.RequireUppercase,
+		RequireDigit:        settings.RequireDigit,
+		RequireSpecial:      settings.RequireSpecial,
+		MinCharacterClasses: settings.MinCharacterClasses,
+		BreachedListFile:    settings.BreachedListFile,
+		HistorySize:         settings.HistorySize,
+		MaxAgeDays:          settings.MaxAgeDays,
+		CheckSimilarity:     settings.CheckSimilarity,
+	}
+	if policy.MinLength <= 0 {
+		policy.MinLength = 8
+	}
+	if policy.MaxLength <= 0 {
+		policy.MaxLength = 128
+	}
+	if config.Get().Password.Algorithm == PasswordAlgorithmBcrypt {
+		policy.MaxBytes = bcryptMaxPasswordBytes
+	}
+	return policy
+}
+
+// ValidatePassword 按密码策略校验新密码，不满足时返回 ErrPasswordTooWeak，data 为逐条的原因
+func ValidatePassword(password string, subject PasswordSubject) error {
+	violations, err := GetPasswordPolicy().Check(password, subject)
+	if err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		messages = append(messages, violation.Message)
+	}
+	appErr := ErrPasswordTooWeak.WithData(violations)
+	appErr.Details = strings.Join(messages, "；")
+	return appErr
+}
+
+// Check 返回全部不满足的策略，只有查询历史密码出错时返回 error
+func (p PasswordPolicy) Check(password string, subject PasswordSubject) ([]PasswordViolation, error) {
+	violations := make([]PasswordViolation, 0)
+	add := func(code string, params map[string]interface{}) {
+		violations = append(violations, newPasswordViolation(code, params))
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		add(PasswordTooShort, map[string]interface{}{"min": p.MinLength})
+	}
+	if length > p.MaxLength {
+		add(PasswordTooLong, map[string]interface{}{"max": p.MaxLength})
+	} else if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		add(PasswordTooManyBytes, map[string]interface{}{"max": p.MaxBytes})
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSpecial = true
+		}
+	}
+	if p.RequireLowercase && !hasLower {
+		add(PasswordMissingLowercase, nil)
+	}
+	if p.RequireUppercase && !hasUpper {
+		add(PasswordMissingUppercase, nil)
+	}
+	if p.RequireDigit && !hasDigit {
+		add(PasswordMissingDigit, nil)
+	}
+	if p.RequireSpecial && !hasSpecial {
+		add(PasswordMissingSpecial, nil)
+	}
+	classes := 0
+	for _, has := range []bool{hasLower, hasUpper, hasDigit, hasSpecial} {
+		if has {
+			classes++
+		}
+	}
+	if classes < p.MinCharacterClasses {
+		add(PasswordTooFewClasses, map[string]interface{}{"min": p.MinCharacterClasses})
+	}
+
+	if p.CheckSimilarity && similarToIdentity(password, subject) {
+		add(PasswordSimilarToIdentity, nil)
+	}
+	if p.BreachedListFile != "" && isBreachedPassword(p.BreachedListFile, password) {
+		add(PasswordBreached, nil)
+	}
+
+	if p.HistorySize > 0 && subject.UserID != 0 {
+		reused, err := isRecentPassword(subject.UserID, password, p.HistorySize)
+		if err != nil {
+			return nil, err
+		}
+		if reused {
+			add(PasswordReused, map[string]interface{}{"count": p.HistorySize})
+		}
+	}
+	return violations, nil
+}
+
+// SetUserPassword 保存用户的新密码并记入历史，调用前应先通过 ValidatePassword 校验
+func SetUserPassword(db *gorm.DB, userID uint, password string) error {
+	hashed, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"password":                hashed,
+			"password_changed_at":     time.Now(),
+			"password_reset_required": false,
+		}).Error; err != nil {
+			return err
+		}
+		return RecordPasswordHistory(tx, userID, hashed)
+	})
+}
+
+// RecordPasswordHistory 记录密码哈希，只保留策略要求的条数
+func RecordPasswordHistory(db *gorm.DB, userID uint, hashed string) error {
+	historySize := GetPasswordPolicy().HistorySize
+	if historySize <= 0 {
+		return nil
+	}
+	if err := db.Create(&model.PasswordHistory{UserID: userID, PasswordHash: hashed}).Error; err != nil {
+		return err
+	}
+
+	var keep []uint
+	if err := db.Model(&model.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(historySize).
+		Pluck("id", &keep).Error; err != nil {
+		return err
+	}
+	return db.Where("user_id = ? AND id NOT IN ?", userID, keep).Delete(&model.PasswordHistory{}).Error
+}
+
+// PasswordExpired 密码是否超过有效期或被管理员要求重置，未记录设置时间的旧用户视为未过期
+func PasswordExpired(user model.User) bool {
+	if user.PasswordResetRequired {
+		return true
+	}
+	maxAgeDays := GetPasswordPolicy().MaxAgeDays
+	if maxAgeDays <= 0 || user.PasswordChangedAt == nil {
+		return false
+	}
+	return time.Since(*user.PasswordChangedAt) > time.Duration(maxAgeDays)*24*time.Hour
+}
+
+// newPasswordViolation 生成带默认提示的违规项
+func newPasswordViolation(code string, params map[string]interface{}) PasswordViolation {
+	message := passwordViolationMessages[code]
+	for name, value := range params {
+		message = strings.ReplaceAll(message, "{"+name+"}", fmt.Sprint(value))
+	}
+	return PasswordViolation{Code: code, Message: message, Params: params}
+}
+
+// similarToIdentity 密码包含用户名、手机号或手机号后 6 位（忽略大小写）
+func similarToIdentity(password string, subject PasswordSubject) bool {
+	lower := strings.ToLower(password)
+	candidates := []string{strings.ToLower(subject.Username), subject.Telephone}
+	if len(subject.Telephone) > 6 {
+		candidates = append(candidates, subject.Telephone[len(subject.Telephone)-6:])
+	}
+	for _, candidate := range candidates {
+		// 太短的用户名容易误判
+		if utf8.RuneCountInString(candidate) >= 4 && strings.Contains(lower, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRecentPassword 密码是否与最近几次的密码相同
+func isRecentPassword(userID uint, password string, historySize int) (bool, error) {
+	var hashes []string
+	if err := GetDB().Model(&model.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(historySize).
+		Pluck("password_hash", &hashes).Error; err != nil {
+		return false, err
+	}
+	for _, hashed := range hashes {
+		if match, _, err := VerifyPassword(hashed, password); err == nil && match {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// 泄露密码库，按文件路径缓存 SHA-1 摘要集合
+var (
+	breachedMu       sync.Mutex
+	breachedFile     string
+	breachedPassword map[string]struct{}
+)
+
+// isBreachedPassword 密码是否在泄露密码库中，文件无法读取时记录日志并跳过检查
+func isBreachedPassword(file, password string) bool {
+	breachedMu.Lock()
+	defer breachedMu.Unlock()
+	if breachedFile != file {
+		set, err := loadBreachedPasswords(file)
+		if err != nil {
+			log.Printf("加载泄露密码库失败: %v", err)
+			return false
+		}
+		breachedFile, breachedPassword = file, set
+	}
+	_, found := breachedPassword[sha1Hex(password)]
+	return found
+}
+
+// loadBreachedPasswords 读取泄露密码库，40 位十六进制的行视为 SHA-1 摘要，其余视为明文
+func loadBreachedPasswords(file string) (map[string]struct{}, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	set := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// 兼容 HIBP 格式的 "摘要:次数"
+		if digest, _, found := strings.Cut(line, ":"); found && isSHA1Hex(digest) {
+			line = digest
+		}
+		if isSHA1Hex(line) {
+			set[strings.ToUpper(line)] = struct{}{}
+		} else {
+			set[sha1Hex(line)] = struct{}{}
+		}
+	}
+	return set, scanner.Err()
+}
+
+// resetBreachedPasswords 清除泄露密码库缓存，配置变化后下次检查时重新加载
+func resetBreachedPasswords() {
+	breachedMu.Lock()
+	defer breachedMu.Unlock()
+	breachedFile, breachedPassword = "", nil
+}
+
+// sha1Hex 大写十六进制的 SHA-1 摘要
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// isSHA1Hex 是否为 40 位十六进制字符串
+func isSHA1Hex(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
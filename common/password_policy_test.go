@@ -0,0 +1,110 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"theing/gin-template/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// violationCodes 提取违规项的 code
+func violationCodes(violations []PasswordViolation) []string {
+	codes := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		codes = append(codes, violation.Code)
+	}
+	return codes
+}
+
+func TestPasswordPolicy_LengthAndClasses(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 8, MaxLength: 16, MinCharacterClasses: 3, RequireDigit: true}
+
+	violations, err := policy.Check("abc", PasswordSubject{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{PasswordTooShort, PasswordMissingDigit, PasswordTooFewClasses}, violationCodes(violations))
+	assert.Equal(t, "密码长度不能少于8位", violations[0].Message)
+	assert.Equal(t, 8, violations[0].Params["min"])
+
+	violations, err = policy.Check("Abcdefg1!Abcdefg1!", PasswordSubject{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{PasswordTooLong}, violationCodes(violations))
+
+	// 长度按字符计算，非 ASCII 字母计为特殊字符
+	violations, err = policy.Check("密码Abc12345", PasswordSubject{})
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestPasswordPolicy_BcryptByteLimit(t *testing.T) {
+	// 30 个汉字只有 30 个字符，但 UTF-8 编码后有 90 字节
+	password := "Abc1" + strings.Repeat("密", 30)
+
+	useConfig(t, func(cfg *config.Config) { cfg.Password.Algorithm = PasswordAlgorithmArgon2id })
+	assert.NoError(t, ValidatePassword(password, PasswordSubject{}))
+
+	useConfig(t, func(cfg *config.Config) { cfg.Password.Algorithm = PasswordAlgorithmBcrypt })
+	policy := GetPasswordPolicy()
+	assert.Equal(t, 72, policy.MaxBytes)
+	violations, err := policy.Check(password, PasswordSubject{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{PasswordTooManyBytes}, violationCodes(violations))
+	assertAppErrorCode(t, ValidatePassword(password, PasswordSubject{}), CodePasswordTooWeak)
+
+	// 通过校验的密码 bcrypt 都能处理
+	violations, err = policy.Check(strings.Repeat("Ab1!", 18), PasswordSubject{})
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+	_, err = HashPassword(strings.Repeat("Ab1!", 18))
+	assert.NoError(t, err)
+}
+
+func TestPasswordPolicy_SimilarToIdentity(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 1, MaxLength: 128, CheckSimilarity: true}
+	subject := PasswordSubject{Username: "Alice", Telephone: "13812345678"}
+
+	for _, password := range []string{"xxALICExx", "p13812345678", "Pw345678!"} {
+		violations, err := policy.Check(password, subject)
+		require.NoError(t, err)
+		assert.Equal(t, []string{PasswordSimilarToIdentity}, violationCodes(violations), password)
+	}
+
+	// 过短的用户名不参与比较
+	violations, err := policy.Check("bob-Secret-1", PasswordSubject{Username: "bob"})
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestPasswordPolicy_BreachedList(t *testing.T) {
+	t.Cleanup(resetBreachedPasswords)
+	file := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(file, []byte("# 注释\nPassw0rd!\n"+sha1Hex("Qwerty123!")+":42\n"), 0o600))
+	policy := PasswordPolicy{MinLength: 1, MaxLength: 128, BreachedListFile: file}
+
+	for _, password := range []string{"Passw0rd!", "Qwerty123!"} {
+		violations, err := policy.Check(password, PasswordSubject{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{PasswordBreached}, violationCodes(violations), password)
+	}
+	violations, err := policy.Check("Unlisted-Pass-9", PasswordSubject{})
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestValidatePassword_ReturnsStructuredViolations(t *testing.T) {
+	useConfig(t, func(cfg *config.Config) { cfg.Password.Policy.MinLength = 10 })
+
+	err := ValidatePassword("short", PasswordSubject{})
+	assertAppErrorCode(t, err, CodePasswordTooWeak)
+	violations, ok := err.(*AppError).Data.([]PasswordViolation)
+	require.True(t, ok)
+	assert.Contains(t, violationCodes(violations), PasswordTooShort)
+	// 预定义错误本身不被修改
+	assert.Nil(t, ErrPasswordTooWeak.Data)
+
+	assert.NoError(t, ValidatePassword("Long-Enough-Pass-1", PasswordSubject{}))
+}
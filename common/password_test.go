@@ -0,0 +1,89 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"theing/gin-template/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// useFastPasswordHashing 使用较低的参数加快测试
+func useFastPasswordHashing(t *testing.T, algorithm string) {
+	useConfig(t, func(cfg *config.Config) {
+		cfg.Password.Algorithm = algorithm
+		cfg.Password.Argon2id.MemoryKiB = 1024
+		cfg.Password.Argon2id.Iterations = 1
+		cfg.Password.Argon2id.Parallelism = 1
+		cfg.Password.Bcrypt.Cost = bcrypt.MinCost
+	})
+}
+
+func TestHashPassword_Argon2id(t *testing.T) {
+	useFastPasswordHashing(t, PasswordAlgorithmArgon2id)
+
+	encoded, err := HashPassword("Correct-Horse-1")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	match, rehash, err := VerifyPassword(encoded, "Correct-Horse-1")
+	require.NoError(t, err)
+	assert.True(t, match)
+	assert.False(t, rehash)
+
+	match, _, err = VerifyPassword(encoded, "wrong-password")
+	require.NoError(t, err)
+	assert.False(t, match)
+
+	// 相同密码每次使用不同的盐
+	again, err := HashPassword("Correct-Horse-1")
+	require.NoError(t, err)
+	assert.NotEqual(t, encoded, again)
+}
+
+func TestVerifyPassword_RehashOutdatedHashes(t *testing.T) {
+	useFastPasswordHashing(t, PasswordAlgorithmBcrypt)
+	legacy, err := HashPassword("Correct-Horse-1")
+	require.NoError(t, err)
+
+	// 切换到 Argon2id 后，bcrypt 哈希仍能校验，但需要升级
+	useConfig(t, func(cfg *config.Config) { cfg.Password.Algorithm = PasswordAlgorithmArgon2id })
+	match, rehash, err := VerifyPassword(legacy, "Correct-Horse-1")
+	require.NoError(t, err)
+	assert.True(t, match)
+	assert.True(t, rehash)
+
+	// 提高 Argon2id 参数后，旧参数的哈希需要升级
+	weak, err := HashPassword("Correct-Horse-1")
+	require.NoError(t, err)
+	useConfig(t, func(cfg *config.Config) { cfg.Password.Argon2id.Iterations = 2 })
+	match, rehash, err = VerifyPassword(weak, "Correct-Horse-1")
+	require.NoError(t, err)
+	assert.True(t, match)
+	assert.True(t, rehash)
+
+	// 密码错误时不提示升级
+	_, rehash, err = VerifyPassword(weak, "wrong-password")
+	require.NoError(t, err)
+	assert.False(t, rehash)
+}
+
+func TestVerifyPassword_RejectsMalformedHashes(t *testing.T) {
+	useFastPasswordHashing(t, PasswordAlgorithmArgon2id)
+
+	for _, encoded := range []string{
+		"",
+		"plaintext",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$aGFzaA",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=1024,t=0,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$aGFzaA",
+	} {
+		match, _, err := VerifyPassword(encoded, "password")
+		assert.Error(t, err, encoded)
+		assert.False(t, match, encoded)
+	}
+}
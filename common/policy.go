@@ -0,0 +1,415 @@
+package common
+
+// 基于属性的访问控制（ABAC）策略引擎
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"theing/gin-template/config"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// 策略效果
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Policy 一条授权策略，动作、资源和角色都匹配且条件全部成立时生效
+type Policy struct {
+	ID          string            `mapstructure:"id" json:"id"`
+	Description string            `mapstructure:"description" json:"description"`
+	Effect      string            `mapstructure:"effect" json:"effect"`         // allow 或 deny
+	Actions     []string          `mapstructure:"actions" json:"actions"`       // 支持通配符，如 user:*
+	Resources   []string          `mapstructure:"resources" json:"resources"`   // 资源类型，为空表示全部
+	Roles       []string          `mapstructure:"roles" json:"roles"`           // 主体角色，为空表示全部
+	Conditions  []PolicyCondition `mapstructure:"conditions" json:"conditions"` // 附加条件
+}
+
+// PolicyCondition 策略条件，Ref 不为空时与另一个属性比较，否则与 Value 比较
+type PolicyCondition struct {
+	Attribute string      `mapstructure:"attr" json:"attr"` // 属性路径，如 resource.owner_id
+	Operator  string      `mapstructure:"op" json:"op"`     // 比较运算符
+	Value     interface{} `mapstructure:"value" json:"value,omitempty"`
+	Ref       string      `mapstructure:"ref" json:"ref,omitempty"` // 引用的属性路径，如 subject.id
+}
+
+// AccessRequest 一次授权请求
+type AccessRequest struct {
+	Subject  map[string]interface{} `json:"subject"`  // 主体属性，如 id、roles
+	Resource map[string]interface{} `json:"resource"` // 资源属性，type 为资源类型
+	Action   string                 `json:"action"`
+	Context  map[string]interface{} `json:"context"` // 环境属性，如 hour、weekday、ip
+}
+
+// PolicyDecision 授权结果
+type PolicyDecision struct {
+	Allowed  bool          `json:"allowed"`
+	PolicyID string        `json:"policy_id,omitempty"` // 决定结果的策略
+	Reason   string        `json:"reason"`
+	Trace    []PolicyTrace `json:"trace,omitempty"` // 每条策略的匹配过程
+}
+
+// PolicyTrace 单条策略的匹配情况
+type PolicyTrace struct {
+	PolicyID string `json:"policy_id"`
+	Effect   string `json:"effect"`
+	Matched  bool   `json:"matched"`
+	Reason   string `json:"reason"`
+}
+
+// PolicyEngine 策略引擎，deny 优先，没有策略匹配时默认拒绝
+type PolicyEngine struct {
+	policies []Policy
+}
+
+// 条件运算符
+var policyOperators = map[string]func(actual, expected interface{}) bool{
+	"eq":         policyEqual,
+	"ne":         func(a, e interface{}) bool { return !policyEqual(a, e) },
+	"in":         func(a, e interface{}) bool { return policyContains(e, a) },
+	"not_in":     func(a, e interface{}) bool { return !policyContains(e, a) },
+	"contains":   policyContains,
+	"gt":         func(a, e interface{}) bool { return policyCompare(a, e, func(x, y float64) bool { return x > y }) },
+	"gte":        func(a, e interface{}) bool { return policyCompare(a, e, func(x, y float64) bool { return x >= y }) },
+	"lt":         func(a, e interface{}) bool { return policyCompare(a, e, func(x, y float64) bool { return x < y }) },
+	"lte":        func(a, e interface{}) bool { return policyCompare(a, e, func(x, y float64) bool { return x <= y }) },
+	"between":    policyBetween,
+	"exists":     func(a, e interface{}) bool { return a != nil },
+	"not_exists": func(a, e interface{}) bool { return a == nil },
+}
+
+var (
+	policyEngine   *PolicyEngine
+	policyEngineMu sync.RWMutex
+)
+
+// NewPolicyEngine 校验策略并创建引擎
+func NewPolicyEngine(policies []Policy) (*PolicyEngine, error) {
+	var problems []string
+	seen := make(map[string]bool)
+	for i, policy := range policies {
+		name := policy.ID
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+			problems = append(problems, fmt.Sprintf("策略 %s 缺少 id", name))
+		} else if seen[name] {
+			problems = append(problems, fmt.Sprintf("策略 %s 重复", name))
+		}
+		seen[name] = true
+
+		if policy.Effect != EffectAllow && policy.Effect != EffectDeny {
+			problems = append(problems, fmt.Sprintf("策略 %s 的 effect 必须为 allow 或 deny", name))
+		}
+		if len(policy.Actions) == 0 {
+			problems = append(problems, fmt.Sprintf("策略 %s 缺少 actions", name))
+		}
+		for _, condition := range policy.Conditions {
+			if _, ok := policyOperators[condition.Operator]; !ok {
+				problems = append(problems, fmt.Sprintf("策略 %s 的运算符 %q 不受支持", name, condition.Operator))
+			}
+			if condition.Attribute == "" {
+				problems = append(problems, fmt.Sprintf("策略 %s 的条件缺少 attr", name))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	return &PolicyEngine{policies: policies}, nil
+}
+
+// LoadPolicies 从 YAML 文件加载策略
+func LoadPolicies(file string) (*PolicyEngine, error) {
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("读取策略文件失败: %v", err)
+	}
+	var policies []Policy
+	if err := v.UnmarshalKey("policies", &policies); err != nil {
+		return nil, fmt.Errorf("解析策略文件失败: %v", err)
+	}
+	return NewPolicyEngine(policies)
+}
+
+// getPolicyFile 获取策略文件路径
+func getPolicyFile() string {
+	if file := config.Get().Policy.File; file != "" {
+		return file
+	}
+	return "config/policies.yml"
+}
+
+// InitPolicies 加载配置中的策略文件，失败时保留原有策略
+func InitPolicies() error {
+	engine, err := LoadPolicies(getPolicyFile())
+	if err != nil {
+		return err
+	}
+	policyEngineMu.Lock()
+	policyEngine = engine
+	policyEngineMu.Unlock()
+	return nil
+}
+
+// GetPolicyEngine 获取策略引擎，未初始化时尝试加载，加载失败则拒绝所有请求
+func GetPolicyEngine() *PolicyEngine {
+	policyEngineMu.RLock()
+	engine := policyEngine
+	policyEngineMu.RUnlock()
+	if engine != nil {
+		return engine
+	}
+
+	if err := InitPolicies(); err != nil {
+		log.Printf("加载策略失败，默认拒绝所有请求: %v", err)
+		policyEngineMu.Lock()
+		if policyEngine == nil {
+			policyEngine = &PolicyEngine{}
+		}
+		policyEngineMu.Unlock()
+	}
+	return GetPolicyEngine()
+}
+
+// Authorize 使用全局策略引擎进行授权判断
+func Authorize(req AccessRequest) PolicyDecision {
+	return GetPolicyEngine().Evaluate(req)
+}
+
+// SubjectFromClaims 由 token 的 claims 构造主体属性
+func SubjectFromClaims(claims *Claims) map[string]interface{} {
+	return map[string]interface{}{
+		"id":    claims.UserId,
+		"roles": claims.Roles,
+	}
+}
+
+// DefaultPolicyContext 构造常用的环境属性
+func DefaultPolicyContext(now time.Time, ip string) map[string]interface{} {
+	return map[string]interface{}{
+		"time":    now.Format(time.RFC3339),
+		"hour":    now.Hour(),
+		"weekday": int(now.Weekday()),
+		"ip":      ip,
+	}
+}
+
+// Policies 返回引擎中的全部策略
+func (e *PolicyEngine) Policies() []Policy {
+	return e.policies
+}
+
+// Evaluate 依次匹配所有策略，任一 deny 策略匹配即拒绝，否则有 allow 策略匹配才允许
+func (e *PolicyEngine) Evaluate(req AccessRequest) PolicyDecision {
+	decision := PolicyDecision{Reason: "没有匹配的策略，默认拒绝"}
+	var allowedBy string
+
+	for _, policy := range e.policies {
+		matched, reason := policy.match(req)
+		decision.Trace = append(decision.Trace, PolicyTrace{
+			PolicyID: policy.ID,
+			Effect:   policy.Effect,
+			Matched:  matched,
+			Reason:   reason,
+		})
+		if !matched {
+			continue
+		}
+		if policy.Effect == EffectDeny {
+			decision.Allowed = false
+			decision.PolicyID = policy.ID
+			decision.Reason = "被策略 " + policy.ID + " 拒绝"
+			return decision
+		}
+		if allowedBy == "" {
+			allowedBy = policy.ID
+		}
+	}
+
+	if allowedBy != "" {
+		decision.Allowed = true
+		decision.PolicyID = allowedBy
+		decision.Reason = "由策略 " + allowedBy + " 允许"
+	}
+	return decision
+}
+
+// match 判断策略是否适用于请求，并给出原因
+func (p Policy) match(req AccessRequest) (bool, string) {
+	if !matchPattern(p.Actions, req.Action) {
+		return false, "动作不匹配"
+	}
+	if len(p.Resources) > 0 {
+		resourceType, _ := lookupAttribute(req, "resource.type").(string)
+		if !matchPattern(p.Resources, resourceType) {
+			return false, "资源类型不匹配"
+		}
+	}
+	if len(p.Roles) > 0 && !policyIntersects(lookupAttribute(req, "subject.roles"), p.Roles) {
+		return false, "主体角色不匹配"
+	}
+	for _, condition := range p.Conditions {
+		actual := lookupAttribute(req, condition.Attribute)
+		expected := condition.Value
+		if condition.Ref != "" {
+			expected = lookupAttribute(req, condition.Ref)
+		}
+		if !policyOperators[condition.Operator](actual, expected) {
+			return false, fmt.Sprintf("条件不成立: %s %s %v", condition.Attribute, condition.Operator, describeExpected(condition, expected))
+		}
+	}
+	return true, "匹配"
+}
+
+// describeExpected 描述条件的期望值
+func describeExpected(condition PolicyCondition, expected interface{}) string {
+	if condition.Ref != "" {
+		return fmt.Sprintf("%s(%v)", condition.Ref, expected)
+	}
+	return fmt.Sprint(expected)
+}
+
+// matchPattern 判断值是否匹配任一模式，模式支持 path.Match 通配符
+func matchPattern(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" || pattern == value {
+			return true
+		}
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupAttribute 按 subject.xxx / resource.xxx / context.xxx 路径查找属性
+func lookupAttribute(req AccessRequest, attribute string) interface{} {
+	parts := strings.SplitN(attribute, ".", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	var source map[string]interface{}
+	switch parts[0] {
+	case "subject":
+		source = req.Subject
+	case "resource":
+		source = req.Resource
+	case "context":
+		source = req.Context
+	default:
+		return nil
+	}
+
+	var current interface{} = source
+	for _, key := range strings.Split(parts[1], ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+// policyEqual 比较两个属性，数字按数值比较，其余按字符串比较
+func policyEqual(actual, expected interface{}) bool {
+	if actual == nil || expected == nil {
+		return actual == nil && expected == nil
+	}
+	if x, ok := toFloat(actual); ok {
+		if y, ok := toFloat(expected); ok {
+			return x == y
+		}
+	}
+	return fmt.Sprint(actual) == fmt.Sprint(expected)
+}
+
+// policyContains 判断列表中是否包含某个元素
+func policyContains(list, item interface{}) bool {
+	for _, element := range toSlice(list) {
+		if policyEqual(element, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// policyIntersects 判断列表与候选值是否有交集
+func policyIntersects(list interface{}, candidates []string) bool {
+	for _, candidate := range candidates {
+		if policyContains(list, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// policyCompare 按数值比较
+func policyCompare(actual, expected interface{}, cmp func(x, y float64) bool) bool {
+	x, ok := toFloat(actual)
+	if !ok {
+		return false
+	}
+	y, ok := toFloat(expected)
+	if !ok {
+		return false
+	}
+	return cmp(x, y)
+}
+
+// policyBetween 判断数值是否在闭区间 [min, max] 内，期望值为两个元素的列表
+func policyBetween(actual, expected interface{}) bool {
+	bounds := toSlice(expected)
+	if len(bounds) != 2 {
+		return false
+	}
+	return policyCompare(actual, bounds[0], func(x, y float64) bool { return x >= y }) &&
+		policyCompare(actual, bounds[1], func(x, y float64) bool { return x <= y })
+}
+
+// toFloat 将数字或数字字符串转换为 float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	case bool, nil:
+		return 0, false
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// toSlice 将任意切片转换为 []interface{}
+func toSlice(value interface{}) []interface{} {
+	if value == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items
+}
@@ -0,0 +1,119 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadSamplePolicies 加载仓库自带的策略文件
+func loadSamplePolicies(t *testing.T) *PolicyEngine {
+	engine, err := LoadPolicies(filepath.Join("..", "config", "policies.yml"))
+	require.NoError(t, err)
+	return engine
+}
+
+// accessRequest 构造测试用的授权请求
+func accessRequest(userID uint, roles []string, action string, resource map[string]interface{}, hour, weekday int) AccessRequest {
+	return AccessRequest{
+		Subject:  map[string]interface{}{"id": userID, "roles": roles},
+		Resource: resource,
+		Action:   action,
+		Context:  map[string]interface{}{"hour": hour, "weekday": weekday},
+	}
+}
+
+func TestPolicyEngine_OwnProfile(t *testing.T) {
+	engine := loadSamplePolicies(t)
+
+	// 路径参数是字符串，主体 id 是数字，按数值比较
+	own := engine.Evaluate(accessRequest(7, []string{"user"}, "profile:update", map[string]interface{}{"type": "user", "id": "7"}, 10, 2))
+	assert.True(t, own.Allowed)
+	assert.Equal(t, "user-own-profile", own.PolicyID)
+
+	other := engine.Evaluate(accessRequest(7, []string{"user"}, "profile:update", map[string]interface{}{"type": "user", "id": "8"}, 10, 2))
+	assert.False(t, other.Allowed)
+	assert.Empty(t, other.PolicyID)
+	assert.Len(t, other.Trace, len(engine.Policies()))
+}
+
+func TestPolicyEngine_BusinessHours(t *testing.T) {
+	engine, err := NewPolicyEngine([]Policy{{
+		ID:        "auditor-business-hours",
+		Effect:    EffectAllow,
+		Actions:   []string{"user:read"},
+		Resources: []string{"user"},
+		Roles:     []string{"auditor"},
+		Conditions: []PolicyCondition{
+			{Attribute: "context.weekday", Operator: "between", Value: []interface{}{1, 5}},
+			{Attribute: "context.hour", Operator: "between", Value: []interface{}{9, 17}},
+		},
+	}})
+	require.NoError(t, err)
+	resource := map[string]interface{}{"type": "user", "id": "8"}
+
+	assert.True(t, engine.Evaluate(accessRequest(3, []string{"auditor"}, "user:read", resource, 9, 1)).Allowed)
+	assert.False(t, engine.Evaluate(accessRequest(3, []string{"auditor"}, "user:read", resource, 20, 1)).Allowed)
+	assert.False(t, engine.Evaluate(accessRequest(3, []string{"auditor"}, "user:read", resource, 10, 6)).Allowed)
+	assert.False(t, engine.Evaluate(accessRequest(3, []string{"auditor"}, "user:delete", resource, 10, 1)).Allowed)
+}
+
+func TestPolicyEngine_DenyOverrides(t *testing.T) {
+	engine := loadSamplePolicies(t)
+
+	self := engine.Evaluate(accessRequest(1, []string{"admin"}, "user:delete", map[string]interface{}{"type": "user", "id": "1"}, 10, 1))
+	assert.False(t, self.Allowed)
+	assert.Equal(t, "admin-cannot-delete-self", self.PolicyID)
+
+	other := engine.Evaluate(accessRequest(1, []string{"admin"}, "user:delete", map[string]interface{}{"type": "user", "id": "2"}, 10, 1))
+	assert.True(t, other.Allowed)
+	assert.Equal(t, "admin-full-access", other.PolicyID)
+}
+
+func TestPolicyEngine_Operators(t *testing.T) {
+	engine, err := NewPolicyEngine([]Policy{{
+		ID:      "office-network",
+		Effect:  EffectAllow,
+		Actions: []string{"report:*"},
+		Conditions: []PolicyCondition{
+			{Attribute: "context.ip", Operator: "in", Value: []interface{}{"10.0.0.1", "10.0.0.2"}},
+			{Attribute: "resource.tags", Operator: "contains", Value: "internal"},
+			{Attribute: "resource.size", Operator: "lt", Value: 100},
+		},
+	}})
+	require.NoError(t, err)
+
+	req := AccessRequest{
+		Resource: map[string]interface{}{"tags": []string{"internal", "q3"}, "size": 42.0},
+		Action:   "report:export",
+		Context:  map[string]interface{}{"ip": "10.0.0.2"},
+	}
+	assert.True(t, engine.Evaluate(req).Allowed)
+
+	req.Context["ip"] = "192.168.1.1"
+	decision := engine.Evaluate(req)
+	assert.False(t, decision.Allowed)
+	assert.Contains(t, decision.Trace[0].Reason, "context.ip")
+}
+
+func TestPolicyEngine_InvalidPolicies(t *testing.T) {
+	_, err := NewPolicyEngine([]Policy{
+		{ID: "a", Effect: "maybe", Actions: []string{"x"}},
+		{ID: "a", Effect: EffectAllow},
+		{Effect: EffectDeny, Actions: []string{"x"}, Conditions: []PolicyCondition{{Attribute: "subject.id", Operator: "like"}}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "effect")
+	assert.Contains(t, err.Error(), "重复")
+	assert.Contains(t, err.Error(), "缺少 actions")
+	assert.Contains(t, err.Error(), "缺少 id")
+	assert.Contains(t, err.Error(), "like")
+
+	file := filepath.Join(t.TempDir(), "policies.yml")
+	require.NoError(t, os.WriteFile(file, []byte("policies:\n  - id: x\n    effect: allow\n"), 0o644))
+	_, err = LoadPolicies(file)
+	assert.Error(t, err)
+}
@@ -0,0 +1,511 @@
+package common
+
+// 个人数据导出与删除：用户提交申请，后台任务异步生成 ZIP 导出包，删除申请在宽限期结束后匿名化账号并清除关联数据
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// 申请类型
+const (
+	PrivacyRequestExport  = "export"  // 导出个人数据
+	PrivacyRequestErasure = "erasure" // 删除个人数据
+)
+
+// 申请状态
+const (
+	PrivacyStatusPending    = "pending"
+	PrivacyStatusProcessing = "processing"
+	PrivacyStatusCompleted  = "completed"
+	PrivacyStatusFailed     = "failed"
+	PrivacyStatusCancelled  = "cancelled"
+)
+
+// erasedUsername 匿名化后的用户名
+const erasedUsername = "已注销用户"
+
+// privacyExportSections 导出包中除 user.json 外的文件，只导出对用户有意义的列，不包含密钥和哈希
+var privacyExportSections = []struct {
+	file    string
+	model   interface{}
+	columns string
+}{
+	{"sessions.json", &model.Session{}, "id, user_agent, ip, last_seen_at, expires_at, revoked_at, created_at"},
+	{"api_keys.json", &model.APIKey{}, "id, name, prefix, scopes, expires_at, last_used_at, last_used_ip, revoked_at, created_at"},
+	{"external_identities.json", &model.ExternalIdentity{}, "provider, subject, email, name, last_login_at, created_at"},
+	{"oauth_consents.json", &model.OAuthConsent{}, "client_id, scope, created_at, updated_at"},
+	{"mfa.json", &model.UserMFA{}, "enabled, enabled_at, created_at"},
+	{"password_history.json", &model.PasswordHistory{}, "created_at"},
+	{"privacy_requests.json", &model.PrivacyRequest{}, "id, type, status, scheduled_at, completed_at, created_at"},
+}
+
+// privacyErasureTables 删除账号时按 user_id 清除的表
+var privacyErasureTables = []interface{}{
+	&model.Session{},
+	&model.RefreshToken{},
+	&model.APIKey{},
+	&model.UserMFA{},
+	&model.RecoveryCode{},
+	&model.ExternalIdentity{},
+	&model.OAuthAuthorizationCode{},
+	&model.OAuthRefreshToken{},
+	&model.OAuthConsent{},
+	&model.PasswordHistory{},
+}
+
+// privacyWake 提交导出申请后唤醒后台任务，不必等到下一次轮询
+var privacyWake = make(chan struct{}, 1)
+
+// RequestDataExport 提交数据导出申请，同一用户同时只能有一个未完成的导出
+func RequestDataExport(userID uint) (*model.PrivacyRequest, error) {
+	req, err := createPrivacyRequest(userID, PrivacyRequestExport, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	notifyPrivacyWorker()
+	return req, nil
+}
+
+// RequestErasure 提交删除申请，宽限期内账号照常使用，可以撤回
+func RequestErasure(ctx context.Context, user model.User) (*model.PrivacyRequest, error) {
+	scheduledAt := time.Now().Add(getErasureGracePeriod())
+	req, err := createPrivacyRequest(user.ID, PrivacyRequestErasure, scheduledAt)
+	if err != nil {
+		return nil, err
+	}
+	notice := fmt.Sprintf("您已申请删除账号，账号及个人数据将于 %s 后永久删除，期间登录后可撤回申请。", scheduledAt.Format("2006-01-02 15:04"))
+	if err := GetSMSSender().Send(ctx, user.Telephone, notice); err != nil {
+		log.Printf("发送删除申请通知失败: %v", err)
+	}
+	return req, nil
+}
+
+// CancelErasure 撤回尚未执行的删除申请
+func CancelErasure(userID uint) error {
+	result := GetDB().Model(&model.PrivacyRequest{}).
+		Where("user_id = ? AND type = ? AND status = ?", userID, PrivacyRequestErasure, PrivacyStatusPending).
+		Update("status", PrivacyStatusCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return NewAppError(CodeDataNotFound, "没有可撤回的删除申请", "")
+	}
+	return nil
+}
+
+// ListUserPrivacyRequests 查询用户自己的申请，最新的在前
+func ListUserPrivacyRequests(userID uint) ([]model.PrivacyRequest, error) {
+	var requests []model.PrivacyRequest
+	err := GetDB().Where("user_id = ?", userID).Order("id DESC").Find(&requests).Error
+	return requests, err
+}
+
+// ListPrivacyRequests 管理员分页查询申请，requestType、status 为空时不过滤
+func ListPrivacyRequests(requestType, status string, page, pageSize int) ([]model.PrivacyRequest, int64, error) {
+	query := GetDB().Model(&model.PrivacyRequest{})
+	if requestType != "" {
+		query = query.Where("type = ?", requestType)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var requests []model.PrivacyRequest
+	err := query.Order("scheduled_at").Offset((page - 1) * pageSize).Limit(pageSize).Find(&requests).Error
+	return requests, total, err
+}
+
+// DataExportFile 返回可下载的导出文件路径，只有本人、已完成且未过期的导出可以下载
+func DataExportFile(userID, requestID uint) (string, error) {
+	var req model.PrivacyRequest
+	err := GetDB().Where("id = ? AND user_id = ? AND type = ?", requestID, userID, PrivacyRequestExport).Limit(1).Find(&req).Error
+	if err != nil {
+		return "", err
+	}
+	if req.ID == 0 {
+		return "", NewAppError(CodeDataNotFound, "导出申请不存在", "")
+	}
+	if req.Status != PrivacyStatusCompleted || req.FilePath == "" {
+		return "", NewAppError(CodeBusinessError, "导出尚未完成", req.Status)
+	}
+	if req.ExpiresAt != nil && time.Now().After(*req.ExpiresAt) {
+		return "", NewAppError(CodeDataNotFound, "导出文件已过期，请重新申请", "")
+	}
+	return req.FilePath, nil
+}
+
+// StartPrivacyWorker 启动后台任务，定期处理到期的申请并清理过期的导出文件，ctx 取消后退出。
+// 返回的通道在任务退出后关闭
+func StartPrivacyWorker(ctx context.Context) <-chan struct{} {
+	interval := time.Duration(config.Get().Privacy.WorkerIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := ProcessPrivacyRequests(ctx); err != nil {
+				log.Printf("处理个人数据申请失败: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-privacyWake:
+			}
+		}
+	}()
+	return done
+}
+
+// ProcessPrivacyRequests 处理已到期的申请。多个实例同时运行时，通过带条件的状态更新保证每个申请只处理一次
+func ProcessPrivacyRequests(ctx context.Context) error {
+	db := GetDB()
+	if err := reclaimStalePrivacyRequests(db); err != nil {
+		return err
+	}
+	var due []model.PrivacyRequest
+	if err := db.Where("status = ? AND scheduled_at <= ?", PrivacyStatusPending, time.Now()).
+		Order("scheduled_at").Limit(20).Find(&due).Error; err != nil {
+		return err
+	}
+
+	for _, req := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		claimed := db.Model(&model.PrivacyRequest{}).
+			Where("id = ? AND status = ?", req.ID, PrivacyStatusPending).
+			Update("status", PrivacyStatusProcessing)
+		if claimed.Error != nil {
+			return claimed.Error
+		}
+		if claimed.RowsAffected == 0 {
+			continue
+		}
+
+		updates := map[string]interface{}{"status": PrivacyStatusCompleted, "completed_at": time.Now()}
+		var err error
+		switch req.Type {
+		case PrivacyRequestExport:
+			var path string
+			path, err = buildDataExport(req)
+			updates["file_path"] = path
+			updates["expires_at"] = time.Now().Add(getExportTTL())
+		case PrivacyRequestErasure:
+			err = EraseUser(ctx, req.UserID)
+		default:
+			err = fmt.Errorf("未知的申请类型: %s", req.Type)
+		}
+		if err != nil {
+			log.Printf("处理个人数据申请 %d 失败: %v", req.ID, err)
+			updates = map[string]interface{}{"status": PrivacyStatusFailed, "error": truncateString(err.Error(), 512)}
+		}
+		if err := db.Model(&model.PrivacyRequest{}).Where("id = ?", req.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	return removeExpiredExports(db)
+}
+
+// reclaimStalePrivacyRequests 处理进程崩溃或退出时申请停留在处理中，超过 processing_timeout_minutes 后放回待处理，
+// 否则该用户再也无法提交新的申请
+func reclaimStalePrivacyRequests(db *gorm.DB) error {
+	result := db.Model(&model.PrivacyRequest{}).
+		Where("status = ? AND updated_at < ?", PrivacyStatusProcessing, time.Now().Add(-getProcessingTimeout())).
+		Update("status", PrivacyStatusPending)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("%d 个处理超时的个人数据申请已重新放回待处理", result.RowsAffected)
+	}
+	return nil
+}
+
+// EraseUser 删除账号：撤销全部登录，清除凭据、授权和登录记录，用户行匿名化后软删除保留，便于统计和外键引用
+func EraseUser(ctx context.Context, userID uint) error {
+	var user model.User
+	if err := GetDB().Unscoped().Limit(1).Find(&user, userID).Error; err != nil {
+		return err
+	}
+	if user.ID == 0 {
+		return ErrUserNotFound
+	}
+	if err := revokeUserAccess(ctx, userID); err != nil {
+		return err
+	}
+
+	err := GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		for _, value := range privacyErasureTables {
+			if err := tx.Where("user_id = ?", userID).Delete(value).Error; err != nil {
+				return err
+			}
+		}
+		// 锁定记录以手机号标识账号，注销过的账号手机号带有删除标记
+		phone := strings.SplitN(user.Telephone, "#", 2)[0]
+		if err := tx.Where("scope = ? AND identifier = ?", "account", phone).Delete(&model.LockoutEvent{}).Error; err != nil {
+			return err
+		}
+		// 审计日志需要保留，只去掉该用户作为操作人时记录的客户端信息；被操作的记录关联的是已匿名化的用户行
+		if err := tx.Model(&model.AuditLog{}).
+			Where("actor_id = ?", userID).
+			Updates(map[string]interface{}{"ip": "", "user_agent": ""}).Error; err != nil {
+			return err
+		}
+		// 其他未处理的导出申请不再需要
+		if err := tx.Model(&model.PrivacyRequest{}).
+			Where("user_id = ? AND type = ? AND status = ?", userID, PrivacyRequestExport, PrivacyStatusPending).
+			Update("status", PrivacyStatusCancelled).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{
+			"username":                erasedUsername,
+			"telephone":               fmt.Sprintf("erased-%d", user.ID),
+			"password":                "",
+			"avatar":                  "",
+			"bio":                     "",
+			"phone_verified_at":       nil,
+			"password_changed_at":     nil,
+			"password_reset_required": false,
+			"status":                  UserStatusDeleted,
+		}
+		if !user.DeletedAt.Valid {
+			updates["deleted_at"] = now
+		}
+		return tx.Unscoped().Model(&model.User{}).Where("id = ?", user.ID).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+	return removeUserExports(GetDB(), userID)
+}
+
+// createPrivacyRequest 创建申请，同类型未完成的申请已存在时返回错误
+func createPrivacyRequest(userID uint, requestType string, scheduledAt time.Time) (*model.PrivacyRequest, error) {
+	var req *model.PrivacyRequest
+	err := GetDB().Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.PrivacyRequest{}).
+			Where("user_id = ? AND type = ? AND status IN ?", userID, requestType, []string{PrivacyStatusPending, PrivacyStatusProcessing}).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return NewAppError(CodeDataExists, "已有处理中的申请", requestType)
+		}
+		req = &model.PrivacyRequest{
+			UserID:      userID,
+			Type:        requestType,
+			Status:      PrivacyStatusPending,
+			ScheduledAt: scheduledAt,
+		}
+		return tx.Create(req).Error
+	})
+	return req, err
+}
+
+// buildDataExport 生成导出包：user.json 与各关联数据一个 JSON 文件
+func buildDataExport(req model.PrivacyRequest) (string, error) {
+	db := GetDB()
+	var user model.User
+	if err := db.Preload("Roles").First(&user, req.UserID).Error; err != nil {
+		return "", err
+	}
+
+	dir := getExportDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("user-%d-%d-%s.zip", req.UserID, req.ID, hex.EncodeToString(suffix)))
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+	zw := zip.NewWriter(file)
+	err = writeDataExport(db, zw, user)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// writeDataExport 将用户数据逐个写入 ZIP
+func writeDataExport(db *gorm.DB, zw *zip.Writer, user model.User) error {
+	profile := map[string]interface{}{
+		"id":                  user.ID,
+		"username":            user.Username,
+		"telephone":           user.Telephone,
+		"avatar":              user.Avatar,
+		"bio":                 user.Bio,
+		"status":              user.Status,
+		"roles":               RoleNames(user),
+		"phone_verified_at":   user.PhoneVerifiedAt,
+		"password_changed_at": user.PasswordChangedAt,
+		"created_at":          user.CreatedAt,
+		"updated_at":          user.UpdatedAt,
+	}
+	if err := writeZipJSON(zw, "user.json", profile); err != nil {
+		return err
+	}
+
+	for _, section := range privacyExportSections {
+		rows := make([]map[string]interface{}, 0)
+		if err := db.Model(section.model).Select(section.columns).Where("user_id = ?", user.ID).Find(&rows).Error; err != nil {
+			return err
+		}
+		if err := writeZipJSON(zw, section.file, rows); err != nil {
+			return err
+		}
+	}
+
+	lockouts := make([]map[string]interface{}, 0)
+	if err := db.Model(&model.LockoutEvent{}).Select("ip, failures, locked_until, created_at").
+		Where("scope = ? AND identifier = ?", "account", user.Telephone).Find(&lockouts).Error; err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "lockout_events.json", lockouts); err != nil {
+		return err
+	}
+
+	return writeZipJSON(zw, "manifest.json", map[string]interface{}{
+		"user_id":      user.ID,
+		"generated_at": time.Now(),
+	})
+}
+
+// writeZipJSON 以缩进格式写入一个 JSON 文件
+func writeZipJSON(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// removeExpiredExports 删除已过下载期限的导出文件
+func removeExpiredExports(db *gorm.DB) error {
+	var expired []model.PrivacyRequest
+	if err := db.Where("type = ? AND file_path <> '' AND expires_at < ?", PrivacyRequestExport, time.Now()).
+		Find(&expired).Error; err != nil {
+		return err
+	}
+	return removeExportFiles(db, expired)
+}
+
+// removeUserExports 删除用户全部的导出文件
+func removeUserExports(db *gorm.DB, userID uint) error {
+	var exports []model.PrivacyRequest
+	if err := db.Where("user_id = ? AND type = ? AND file_path <> ''", userID, PrivacyRequestExport).
+		Find(&exports).Error; err != nil {
+		return err
+	}
+	return removeExportFiles(db, exports)
+}
+
+// removeExportFiles 删除导出文件并清空记录中的路径
+func removeExportFiles(db *gorm.DB, exports []model.PrivacyRequest) error {
+	for _, req := range exports {
+		if err := os.Remove(req.FilePath); err != nil && !os.IsNotExist(err) {
+			log.Printf("删除导出文件 %s 失败: %v", req.FilePath, err)
+			continue
+		}
+		if err := db.Model(&model.PrivacyRequest{}).Where("id = ?", req.ID).Update("file_path", "").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyPrivacyWorker 唤醒后台任务，任务忙时忽略
+func notifyPrivacyWorker() {
+	select {
+	case privacyWake <- struct{}{}:
+	default:
+	}
+}
+
+// getExportDir 导出文件目录
+func getExportDir() string {
+	if dir := config.Get().Privacy.ExportDir; dir != "" {
+		return dir
+	}
+	return "storage/exports"
+}
+
+// getExportTTL 导出文件的保留时间
+func getExportTTL() time.Duration {
+	hours := config.Get().Privacy.ExportTTLHours
+	if hours <= 0 {
+		hours = 72
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// getProcessingTimeout 申请处理中状态的最长时间
+func getProcessingTimeout() time.Duration {
+	minutes := config.Get().Privacy.ProcessingTimeoutMinutes
+	if minutes <= 0 {
+		minutes = 30
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// getErasureGracePeriod 删除申请的宽限期，为 0 时在下一次任务运行时执行
+func getErasureGracePeriod() time.Duration {
+	return time.Duration(config.Get().Privacy.ErasureGraceDays) * 24 * time.Hour
+}
+
+// truncateString 按字节截断字符串，不截断多字节字符
+func truncateString(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
@@ -0,0 +1,188 @@
+package common
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestTruncateString(t *testing.T) {
+	assert.Equal(t, "abc", truncateString("abc", 5))
+	assert.Equal(t, "ab", truncateString("abcdef", 2))
+	// 不截断多字节字符
+	assert.Equal(t, "导", truncateString("导出失败", 4))
+	assert.Equal(t, "导出", truncateString("导出失败", 6))
+}
+
+// usePrivacyConfig 导出文件写入临时目录
+func usePrivacyConfig(t *testing.T, graceDays int) {
+	dir := t.TempDir()
+	useConfig(t, func(cfg *config.Config) {
+		cfg.Privacy.ExportDir = dir
+		cfg.Privacy.ErasureGraceDays = graceDays
+	})
+}
+
+// readExportFile 读取导出包中的一个 JSON 文件
+func readExportFile(t *testing.T, zr *zip.ReadCloser, name string, v interface{}) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		r, err := f.Open()
+		require.NoError(t, err)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, v))
+		return
+	}
+	t.Fatalf("导出包中没有 %s", name)
+}
+
+func TestProcessPrivacyRequests_Export(t *testing.T) {
+	db := useTestDB(t)
+	usePrivacyConfig(t, 30)
+	user := createTestUser(t, db, "13900000001")
+	other := createTestUser(t, db, "13900000002")
+	now := time.Now()
+	require.NoError(t, db.Create(&model.APIKey{UserID: user.ID, Name: "ci", Prefix: "tpl_a", KeyHash: "secret-hash-a", Scopes: "user:read"}).Error)
+	require.NoError(t, db.Create(&model.APIKey{UserID: other.ID, Name: "other", Prefix: "tpl_b", KeyHash: "secret-hash-b"}).Error)
+	require.NoError(t, db.Create(&model.Session{ID: "s1", UserID: user.ID, IP: "10.0.0.1", LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}).Error)
+
+	req, err := RequestDataExport(user.ID)
+	require.NoError(t, err)
+	// 同一用户同时只能有一个未完成的导出
+	_, err = RequestDataExport(user.ID)
+	assert.Error(t, err)
+
+	require.NoError(t, ProcessPrivacyRequests(context.Background()))
+	path, err := DataExportFile(user.ID, req.ID)
+	require.NoError(t, err)
+	_, err = DataExportFile(other.ID, req.ID)
+	assert.Error(t, err)
+
+	zr, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer zr.Close()
+
+	var profile map[string]interface{}
+	readExportFile(t, zr, "user.json", &profile)
+	assert.Equal(t, user.Telephone, profile["telephone"])
+	assert.NotContains(t, profile, "password")
+
+	// 只导出本人的数据，不包含密钥摘要
+	var keys []map[string]interface{}
+	readExportFile(t, zr, "api_keys.json", &keys)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "ci", keys[0]["name"])
+	assert.NotContains(t, keys[0], "key_hash")
+
+	var sessions []map[string]interface{}
+	readExportFile(t, zr, "sessions.json", &sessions)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "10.0.0.1", sessions[0]["ip"])
+}
+
+func TestEraseUser(t *testing.T) {
+	db := useTestDB(t)
+	user := createTestUser(t, db, "13900000003")
+	now := time.Now()
+	require.NoError(t, db.Create(&model.Session{ID: "s2", UserID: user.ID, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}).Error)
+	require.NoError(t, db.Create(&model.ExternalIdentity{UserID: user.ID, Provider: "github", Subject: "42"}).Error)
+	require.NoError(t, db.Create(&model.AuditLog{ActorID: user.ID, Action: "login", IP: "10.0.0.2", UserAgent: "curl"}).Error)
+
+	require.NoError(t, EraseUser(context.Background(), user.ID))
+
+	var erased model.User
+	require.NoError(t, db.Unscoped().First(&erased, user.ID).Error)
+	assert.Equal(t, erasedUsername, erased.Username)
+	assert.NotEqual(t, user.Telephone, erased.Telephone)
+	assert.Empty(t, erased.Password)
+	assert.Equal(t, UserStatusDeleted, erased.Status)
+	assert.True(t, erased.DeletedAt.Valid)
+
+	for _, value := range []interface{}{&model.Session{}, &model.ExternalIdentity{}} {
+		var count int64
+		require.NoError(t, db.Model(value).Where("user_id = ?", user.ID).Count(&count).Error)
+		assert.Zero(t, count)
+	}
+	// 审计日志保留，去掉客户端信息
+	var audit model.AuditLog
+	require.NoError(t, db.Where("actor_id = ?", user.ID).First(&audit).Error)
+	assert.Empty(t, audit.IP)
+	assert.Empty(t, audit.UserAgent)
+
+	// 手机号可以重新注册
+	createTestUser(t, db, user.Telephone)
+}
+
+func TestProcessPrivacyRequests_ErasureGracePeriod(t *testing.T) {
+	db := useTestDB(t)
+	usePrivacyConfig(t, 30)
+	useCaptureSender(t)
+	user := createTestUser(t, db, "13900000004")
+
+	req, err := RequestErasure(context.Background(), user)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), req.ScheduledAt, time.Minute)
+
+	// 宽限期内不执行
+	require.NoError(t, ProcessPrivacyRequests(context.Background()))
+	assert.Equal(t, PrivacyStatusPending, loadPrivacyRequest(t, db, req.ID).Status)
+	var current model.User
+	require.NoError(t, db.First(&current, user.ID).Error)
+	assert.Equal(t, user.Telephone, current.Telephone)
+
+	// 宽限期结束后执行
+	require.NoError(t, db.Model(&model.PrivacyRequest{}).Where("id = ?", req.ID).
+		Update("scheduled_at", time.Now().Add(-time.Minute)).Error)
+	require.NoError(t, ProcessPrivacyRequests(context.Background()))
+	assert.Equal(t, PrivacyStatusCompleted, loadPrivacyRequest(t, db, req.ID).Status)
+	assert.ErrorIs(t, db.First(&current, user.ID).Error, gorm.ErrRecordNotFound)
+}
+
+func TestProcessPrivacyRequests_ClaimOnce(t *testing.T) {
+	db := useTestDB(t)
+	usePrivacyConfig(t, 0)
+	user := createTestUser(t, db, "13900000005")
+	req, err := RequestDataExport(user.ID)
+	require.NoError(t, err)
+
+	// 另一个实例已经认领的申请不会被重复处理
+	require.NoError(t, db.Model(&model.PrivacyRequest{}).Where("id = ?", req.ID).
+		Update("status", PrivacyStatusProcessing).Error)
+	require.NoError(t, ProcessPrivacyRequests(context.Background()))
+	claimed := loadPrivacyRequest(t, db, req.ID)
+	assert.Equal(t, PrivacyStatusProcessing, claimed.Status)
+	assert.Empty(t, claimed.FilePath)
+
+	// 处理中的申请阻止新的申请
+	_, err = RequestDataExport(user.ID)
+	assert.Error(t, err)
+
+	// 处理进程退出后，超过 processing_timeout_minutes 的申请重新处理
+	require.NoError(t, db.Model(&model.PrivacyRequest{}).Where("id = ?", req.ID).
+		UpdateColumn("updated_at", time.Now().Add(-time.Hour)).Error)
+	require.NoError(t, ProcessPrivacyRequests(context.Background()))
+	completed := loadPrivacyRequest(t, db, req.ID)
+	assert.Equal(t, PrivacyStatusCompleted, completed.Status)
+	assert.NotEmpty(t, completed.FilePath)
+}
+
+// loadPrivacyRequest 读取申请的当前状态
+func loadPrivacyRequest(t *testing.T, db *gorm.DB, id uint) model.PrivacyRequest {
+	var req model.PrivacyRequest
+	require.NoError(t, db.First(&req, id).Error)
+	return req
+}
@@ -0,0 +1,237 @@
+package common
+
+// 基于角色的访问控制
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+
+	"gorm.io/gorm"
+)
+
+// 内置角色
+const (
+	RoleAdmin = "admin" // 管理员
+	RoleUser  = "user"  // 普通用户
+)
+
+// 内置权限
+const (
+	PermUserRead   = "user:read"   // 查看用户
+	PermUserWrite  = "user:write"  // 修改用户
+	PermRoleManage = "role:manage" // 管理角色与授权
+	PermPolicyRead = "policy:read" // 查看与试算授权策略
+
+	PermOAuthClientManage = "oauth_client:manage" // 管理第三方应用
+
+	PermUserImpersonate = "user:impersonate" // 代登录用户，默认不授予任何角色
+)
+
+// builtinPermissions 内置权限及说明
+var builtinPermissions = map[string]string{
+	PermUserRead:   "查看用户",
+	PermUserWrite:  "修改用户",
+	PermRoleManage: "管理角色与授权",
+	PermPolicyRead: "查看与试算授权策略",
+
+	PermOAuthClientManage: "管理第三方应用",
+
+	PermUserImpersonate: "代登录用户",
+}
+
+// builtinRoles 内置角色及其权限
+var builtinRoles = map[string][]string{
+	RoleAdmin: {PermUserRead, PermUserWrite, PermRoleManage, PermPolicyRead, PermOAuthClientManage},
+	RoleUser:  {},
+}
+
+// SeedRBAC 初始化内置角色与权限，并为配置中的手机号授予管理员角色
+func SeedRBAC(db *gorm.DB) error {
+	for name, description := range builtinPermissions {
+		permission := model.Permission{Name: name}
+		if err := db.Where(model.Permission{Name: name}).
+			Attrs(model.Permission{Description: description}).
+			FirstOrCreate(&permission).Error; err != nil {
+			return err
+		}
+	}
+
+	for name, permissionNames := range builtinRoles {
+		role := model.Role{Name: name}
+		if err := db.Where(model.Role{Name: name}).FirstOrCreate(&role).Error; err != nil {
+			return err
+		}
+		if len(permissionNames) == 0 {
+			continue
+		}
+		var permissions []model.Permission
+		if err := db.Where("name IN ?", permissionNames).Find(&permissions).Error; err != nil {
+			return err
+		}
+		if err := db.Model(&role).Association("Permissions").Append(&permissions); err != nil {
+			return err
+		}
+	}
+
+	// 引导管理员账号：rbac.admin_telephones 中的用户自动获得管理员角色
+	for _, telephone := range config.Get().RBAC.AdminTelephones {
+		var user model.User
+		if err := db.Where("telephone = ?", telephone).Limit(1).Find(&user).Error; err != nil {
+			return err
+		}
+		if user.ID == 0 {
+			log.Printf("引导管理员 %s 尚未注册，跳过", telephone)
+			continue
+		}
+		if err := assignRole(db, user.ID, RoleAdmin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RoleNames 提取用户已预加载的角色名称
+func RoleNames(user model.User) []string {
+	names := make([]string, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		names = append(names, role.Name)
+	}
+	return names
+}
+
+// HasRole 判断角色列表中是否包含任一指定角色
+func HasRole(userRoles []string, roles ...string) bool {
+	for _, userRole := range userRoles {
+		for _, role := range roles {
+			if userRole == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetRolePermissions 查询一组角色拥有的全部权限名称
+func GetRolePermissions(roles []string) ([]string, error) {
+	if len(roles) == 0 {
+		return nil, nil
+	}
+	var names []string
+	err := GetDB().Model(&model.Permission{}).
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name IN ?", roles).
+		Pluck("permissions.name", &names).Error
+	return names, err
+}
+
+// EffectivePermissions 当前请求实际拥有的权限：角色权限，使用 API 密钥时再与密钥的授权范围取交集
+func EffectivePermissions(claims *Claims) ([]string, error) {
+	granted, err := GetRolePermissions(claims.Roles)
+	if err != nil || claims.APIKeyID == 0 {
+		return granted, err
+	}
+	scopes := make(map[string]struct{}, len(claims.Scopes))
+	for _, scope := range claims.Scopes {
+		scopes[scope] = struct{}{}
+	}
+	effective := make([]string, 0, len(granted))
+	for _, permission := range granted {
+		if _, ok := scopes[permission]; ok {
+			effective = append(effective, permission)
+		}
+	}
+	return effective, nil
+}
+
+// EffectiveRoles 当前请求实际拥有的角色。使用 API 密钥时，只有授权范围覆盖了角色的全部权限才算拥有该角色，
+// 否则按角色放行的接口会绕过密钥的授权范围
+func EffectiveRoles(claims *Claims) ([]string, error) {
+	if claims.APIKeyID == 0 {
+		return claims.Roles, nil
+	}
+	scopes := make(map[string]struct{}, len(claims.Scopes))
+	for _, scope := range claims.Scopes {
+		scopes[scope] = struct{}{}
+	}
+	effective := make([]string, 0, len(claims.Roles))
+	for _, role := range claims.Roles {
+		permissions, err := GetRolePermissions([]string{role})
+		if err != nil {
+			return nil, err
+		}
+		covered := true
+		for _, permission := range permissions {
+			if _, ok := scopes[permission]; !ok {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			effective = append(effective, role)
+		}
+	}
+	return effective, nil
+}
+
+// AssignRole 为用户授予角色
+func AssignRole(userID uint, roleName string) error {
+	return assignRole(GetDB(), userID, roleName)
+}
+
+// assignRole 在指定连接上为用户授予角色
+func assignRole(db *gorm.DB, userID uint, roleName string) error {
+	var role model.Role
+	if err := db.Where("name = ?", roleName).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return NewAppError(CodeDataNotFound, "角色不存在", roleName)
+		}
+		return err
+	}
+	return db.Model(&model.User{ID: userID}).Association("Roles").Append(&role)
+}
+
+// RemoveRole 撤销用户的角色
+func RemoveRole(userID uint, roleName string) error {
+	db := GetDB()
+	var role model.Role
+	if err := db.Where("name = ?", roleName).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return NewAppError(CodeDataNotFound, "角色不存在", roleName)
+		}
+		return err
+	}
+	return db.Model(&model.User{ID: userID}).Association("Roles").Delete(&role)
+}
+
+// CreateRole 创建自定义角色
+func CreateRole(name, description string, permissionNames []string) (*model.Role, error) {
+	db := GetDB()
+	var count int64
+	if err := db.Model(&model.Role{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, NewAppError(CodeDataExists, "角色已存在", name)
+	}
+
+	var permissions []model.Permission
+	if len(permissionNames) > 0 {
+		if err := db.Where("name IN ?", permissionNames).Find(&permissions).Error; err != nil {
+			return nil, err
+		}
+		if len(permissions) != len(permissionNames) {
+			return nil, NewAppError(CodeInvalidParams, "包含不存在的权限", fmt.Sprint(permissionNames))
+		}
+	}
+
+	role := model.Role{Name: name, Description: description, Permissions: permissions}
+	if err := db.Create(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
@@ -0,0 +1,26 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEffectiveRoles_APIKeyScopes(t *testing.T) {
+	useTestDB(t)
+	roles := []string{RoleAdmin, RoleUser}
+
+	granted, err := EffectiveRoles(&Claims{UserId: 1, Roles: roles})
+	require.NoError(t, err)
+	assert.Equal(t, roles, granted)
+
+	// 授权范围只包含部分管理员权限的密钥不算管理员
+	granted, err = EffectiveRoles(&Claims{UserId: 1, Roles: roles, APIKeyID: 1, Scopes: []string{PermUserRead}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{RoleUser}, granted)
+
+	granted, err = EffectiveRoles(&Claims{UserId: 1, Roles: roles, APIKeyID: 1, Scopes: builtinRoles[RoleAdmin]})
+	require.NoError(t, err)
+	assert.Equal(t, roles, granted)
+}
@@ -0,0 +1,209 @@
+package common
+
+// 刷新令牌的发放、轮换与重放检测
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+	"theing/gin-template/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TokenPair 登录或刷新后返回给客户端的令牌对
+type TokenPair struct {
+	AccessToken  string `json:"token"`         // 访问令牌，字段名保持与旧版登录接口一致
+	RefreshToken string `json:"refresh_token"` // 不透明的刷新令牌
+	TokenType    string `json:"token_type"`    // 固定为 Bearer
+	ExpiresIn    int64  `json:"expires_in"`    // 访问令牌有效期（秒）
+
+	PasswordExpired bool `json:"password_expired,omitempty"` // 密码已超过有效期，前端应引导用户修改
+}
+
+// 获取刷新令牌过期时间
+func getRefreshTokenExpirationTime() time.Duration {
+	hours := config.Get().JWT.RefreshExpireHours
+	if hours <= 0 {
+		hours = 720 // 默认 30 天
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// IssueTokenPair 登录成功后发放访问令牌，并开启一个新的刷新令牌族和对应的会话，amr 为本次登录使用的认证方式
+func IssueTokenPair(user model.User, client ClientInfo, amr ...string) (*TokenPair, error) {
+	familyID, err := utils.GenerateOpaqueToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	var pair *TokenPair
+	err = GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := createSession(tx, user.ID, familyID, client); err != nil {
+			return err
+		}
+		pair, err = issueTokenPair(tx, user, familyID, amr)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	pair.PasswordExpired = PasswordExpired(user)
+	return pair, nil
+}
+
+// issueTokenPair 在指定的令牌族下发放令牌对
+func issueTokenPair(db *gorm.DB, user model.User, familyID string, amr []string) (*TokenPair, error) {
+	// 访问令牌携带会话 ID（即令牌族 ID），撤销会话时据此让访问令牌失效
+	accessToken, err := releaseToken(&Claims{
+		UserId:    user.ID,
+		Roles:     RoleNames(user),
+		AMR:       amr,
+		SessionID: familyID,
+	}, getJWTExpirationTime())
+	if err != nil {
+		return nil, err
+	}
+
+	rawToken, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken := model.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(rawToken),
+		AMR:       strings.Join(amr, ","),
+		ExpiresAt: time.Now().Add(getRefreshTokenExpirationTime()),
+	}
+	if err := db.Create(&refreshToken).Error; err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: rawToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(getJWTExpirationTime().Seconds()),
+	}, nil
+}
+
+// RotateRefreshToken 用刷新令牌换取新的令牌对，旧的刷新令牌随即失效。
+// 已经被使用过的刷新令牌再次出现时，视为令牌泄露，撤销整个令牌族。
+func RotateRefreshToken(rawToken string) (*TokenPair, error) {
+	var pair *TokenPair
+	var reusedFamily string
+
+	err := GetDB().Transaction(func(tx *gorm.DB) error {
+		var stored model.RefreshToken
+		if err := tx.Where("token_hash = ?", utils.HashToken(rawToken)).First(&stored).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRefreshTokenInvalid
+			}
+			return err
+		}
+
+		if stored.UsedAt != nil {
+			reusedFamily = stored.FamilyID
+			return ErrRefreshTokenReused
+		}
+		if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+			return ErrRefreshTokenInvalid
+		}
+
+		// 带条件更新，防止并发请求同时使用同一个刷新令牌
+		result := tx.Model(&model.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", stored.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			reusedFamily = stored.FamilyID
+			return ErrRefreshTokenReused
+		}
+
+		var user model.User
+		if err := tx.Preload("Roles").First(&user, stored.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRefreshTokenInvalid
+			}
+			return err
+		}
+		if err := CheckUserActive(user); err != nil {
+			return err
+		}
+
+		if err := extendSession(tx, stored.FamilyID); err != nil {
+			return err
+		}
+		var err error
+		pair, err = issueTokenPair(tx, user, stored.FamilyID, splitAMR(stored.AMR))
+		return err
+	})
+
+	if reusedFamily != "" {
+		// 事务已经回滚，在事务外撤销整个令牌族
+		log.Printf("检测到刷新令牌重放，撤销令牌族: %s", reusedFamily)
+		GetMetrics().RecordJWTValidationError("refresh_token_reused")
+		if revokeErr := RevokeRefreshTokenFamily(reusedFamily); revokeErr != nil {
+			log.Printf("撤销令牌族失败: %v", revokeErr)
+		}
+		if revokeErr := revokeSessionTokens(context.Background(), reusedFamily); revokeErr != nil {
+			log.Printf("撤销会话访问令牌失败: %v", revokeErr)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// RevokeRefreshTokenFamily 撤销令牌族下所有尚未撤销的刷新令牌及对应的会话
+func RevokeRefreshTokenFamily(familyID string) error {
+	db := GetDB()
+	if err := db.Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return markSessionsRevoked(db, "id = ?", familyID)
+}
+
+// RevokeRefreshTokenByValue 撤销刷新令牌所在的令牌族，只允许令牌所属用户操作
+func RevokeRefreshTokenByValue(userID uint, rawToken string) error {
+	var stored model.RefreshToken
+	err := GetDB().Where("token_hash = ? AND user_id = ?", utils.HashToken(rawToken), userID).
+		Limit(1).Find(&stored).Error
+	if err != nil {
+		return err
+	}
+	if stored.ID == 0 {
+		return ErrRefreshTokenInvalid
+	}
+	return RevokeRefreshTokenFamily(stored.FamilyID)
+}
+
+// RevokeUserRefreshTokens 撤销用户所有尚未撤销的刷新令牌及会话
+func RevokeUserRefreshTokens(userID uint) error {
+	db := GetDB()
+	if err := db.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return markSessionsRevoked(db, "user_id = ?", userID)
+}
+
+// splitAMR 解析保存在刷新令牌中的认证方式
+func splitAMR(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,126 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"theing/gin-template/model"
+	"theing/gin-template/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// accessTokenRevoked 解析访问令牌并检查是否已被撤销
+func accessTokenRevoked(t *testing.T, token string) bool {
+	t.Helper()
+	_, claims, err := ParseToken(token)
+	require.NoError(t, err)
+	revoked, err := IsTokenRevoked(context.Background(), claims)
+	require.NoError(t, err)
+	return revoked
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	useKeys(t, "HS256", "")
+	db := useTestDB(t)
+	user := createTestUser(t, db, "13940000001")
+
+	first, err := IssueTokenPair(user, ClientInfo{UserAgent: "test", IP: "127.0.0.1"}, AMRPassword)
+	require.NoError(t, err)
+	second, err := RotateRefreshToken(first.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	// 新令牌仍属于同一个令牌族和会话，认证方式沿用登录时的记录
+	_, firstClaims, err := ParseToken(first.AccessToken)
+	require.NoError(t, err)
+	_, secondClaims, err := ParseToken(second.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, firstClaims.SessionID, secondClaims.SessionID)
+	assert.Equal(t, []string{AMRPassword}, secondClaims.AMR)
+
+	third, err := RotateRefreshToken(second.RefreshToken)
+	require.NoError(t, err)
+	assert.False(t, accessTokenRevoked(t, third.AccessToken))
+
+	_, err = RotateRefreshToken("unknown")
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+}
+
+func TestRotateRefreshToken_ReplayRevokesFamily(t *testing.T) {
+	useKeys(t, "HS256", "")
+	db := useTestDB(t)
+	user := createTestUser(t, db, "13940000002")
+
+	first, err := IssueTokenPair(user, ClientInfo{})
+	require.NoError(t, err)
+	second, err := RotateRefreshToken(first.RefreshToken)
+	require.NoError(t, err)
+
+	// 已使用的刷新令牌再次出现，整个令牌族、会话和会话下的访问令牌都失效
+	_, err = RotateRefreshToken(first.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	_, err = RotateRefreshToken(second.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+	assert.True(t, accessTokenRevoked(t, second.AccessToken))
+
+	_, claims, err := ParseToken(second.AccessToken)
+	require.NoError(t, err)
+	var session model.Session
+	require.NoError(t, db.First(&session, "id = ?", claims.SessionID).Error)
+	assert.NotNil(t, session.RevokedAt)
+
+	// 同一用户的其他登录不受影响
+	other, err := IssueTokenPair(user, ClientInfo{})
+	require.NoError(t, err)
+	_, err = RotateRefreshToken(other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestRotateRefreshToken_Expired(t *testing.T) {
+	useKeys(t, "HS256", "")
+	db := useTestDB(t)
+	user := createTestUser(t, db, "13940000003")
+
+	pair, err := IssueTokenPair(user, ClientInfo{})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&model.RefreshToken{}).
+		Where("token_hash = ?", utils.HashToken(pair.RefreshToken)).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+
+	_, err = RotateRefreshToken(pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+
+	// 用户停用后不能再刷新
+	active, err := IssueTokenPair(user, ClientInfo{})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&user).Update("status", UserStatusSuspended).Error)
+	_, err = RotateRefreshToken(active.RefreshToken)
+	assert.Error(t, err)
+}
+
+func TestRevokeRefreshTokenByValue(t *testing.T) {
+	useKeys(t, "HS256", "")
+	db := useTestDB(t)
+	user := createTestUser(t, db, "13940000004")
+	other := createTestUser(t, db, "13940000005")
+
+	first, err := IssueTokenPair(user, ClientInfo{})
+	require.NoError(t, err)
+	second, err := RotateRefreshToken(first.RefreshToken)
+	require.NoError(t, err)
+
+	// 只能撤销自己的令牌
+	assert.ErrorIs(t, RevokeRefreshTokenByValue(other.ID, second.RefreshToken), ErrRefreshTokenInvalid)
+
+	// 用族内任意一个令牌都可以撤销整个令牌族
+	require.NoError(t, RevokeRefreshTokenByValue(user.ID, first.RefreshToken))
+	_, err = RotateRefreshToken(second.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+
+	var count int64
+	require.NoError(t, db.Model(&model.Session{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&count).Error)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,164 @@
+package common
+
+// 访问令牌撤销
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"theing/gin-template/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevocationStore 令牌撤销存储
+type RevocationStore interface {
+	// Revoke 撤销一个键，直到 expiresAt 之后记录才可以丢弃
+	Revoke(ctx context.Context, key string, revokedAt, expiresAt time.Time) error
+	// RevokedAt 返回键被撤销的时间，未撤销时返回零值
+	RevokedAt(ctx context.Context, key string) (time.Time, error)
+}
+
+// CacheRevocationStore 基于缓存的撤销存储
+type CacheRevocationStore struct {
+	cache CacheClient
+}
+
+// DBRevocationStore 基于数据库的撤销存储
+type DBRevocationStore struct {
+	db *gorm.DB
+}
+
+// GetRevocationStore Redis 可用时使用缓存，否则退回到数据库
+func GetRevocationStore() RevocationStore {
+	if Cache != nil {
+		return &CacheRevocationStore{cache: Cache}
+	}
+	return &DBRevocationStore{db: GetDB()}
+}
+
+// Revoke 写入撤销记录，缓存过期时间与令牌剩余有效期一致
+func (s *CacheRevocationStore) Revoke(ctx context.Context, key string, revokedAt, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.cache.Set(ctx, revocationCacheKey(key), revokedAt.Unix(), ttl)
+}
+
+// RevokedAt 查询撤销时间
+func (s *CacheRevocationStore) RevokedAt(ctx context.Context, key string) (time.Time, error) {
+	value, err := s.cache.Get(ctx, revocationCacheKey(key))
+	if err != nil {
+		if IsCacheMiss(err) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("撤销记录格式错误: %v", err)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// Revoke 写入撤销记录，同时清理已过期的记录
+func (s *DBRevocationStore) Revoke(ctx context.Context, key string, revokedAt, expiresAt time.Time) error {
+	db := s.db.WithContext(ctx)
+	db.Where("expires_at < ?", time.Now()).Delete(&model.TokenRevocation{})
+
+	record := model.TokenRevocation{TokenKey: key, RevokedAt: revokedAt, ExpiresAt: expiresAt}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_at", "expires_at"}),
+	}).Create(&record).Error
+}
+
+// RevokedAt 查询撤销时间
+func (s *DBRevocationStore) RevokedAt(ctx context.Context, key string) (time.Time, error) {
+	var record model.TokenRevocation
+	err := s.db.WithContext(ctx).
+		Where("token_key = ? AND expires_at > ?", key, time.Now()).
+		Limit(1).Find(&record).Error
+	if err != nil {
+		return time.Time{}, err
+	}
+	if record.ID == 0 {
+		return time.Time{}, nil
+	}
+	return record.RevokedAt, nil
+}
+
+// revocationCacheKey 撤销记录的缓存键
+func revocationCacheKey(key string) string {
+	return "revoked:" + key
+}
+
+// tokenRevocationKey 单个令牌的撤销键
+func tokenRevocationKey(jti string) string {
+	return "jti:" + jti
+}
+
+// sessionRevocationKey 会话下全部令牌的撤销键
+func sessionRevocationKey(sessionID string) string {
+	return "sid:" + sessionID
+}
+
+// userRevocationKey 用户全部令牌的撤销键
+func userRevocationKey(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// RevokeToken 撤销单个访问令牌
+func RevokeToken(ctx context.Context, claims *Claims) error {
+	if claims.Id == "" {
+		return nil
+	}
+	return GetRevocationStore().Revoke(ctx, tokenRevocationKey(claims.Id), time.Now(), time.Unix(claims.ExpiresAt, 0))
+}
+
+// RevokeUserTokens 撤销用户在此刻之前签发的所有访问令牌，即“退出所有设备”
+func RevokeUserTokens(ctx context.Context, userID uint) error {
+	now := time.Now()
+	// 记录只需保留到此刻签发的令牌全部过期
+	return GetRevocationStore().Revoke(ctx, userRevocationKey(userID), now, now.Add(maxAccessTokenTTL()))
+}
+
+// maxAccessTokenTTL 各类访问令牌中最长的有效期，撤销记录和退役密钥至少要保留这么久
+func maxAccessTokenTTL() time.Duration {
+	return max(getJWTExpirationTime(), getOAuthAccessTTL(), GetImpersonationMaxDuration())
+}
+
+// IsTokenRevoked 检查访问令牌是否已被撤销
+func IsTokenRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	store := GetRevocationStore()
+
+	if claims.Id != "" {
+		revokedAt, err := store.RevokedAt(ctx, tokenRevocationKey(claims.Id))
+		if err != nil {
+			return false, err
+		}
+		if !revokedAt.IsZero() {
+			return true, nil
+		}
+	}
+
+	if claims.SessionID != "" {
+		revokedAt, err := store.RevokedAt(ctx, sessionRevocationKey(claims.SessionID))
+		if err != nil {
+			return false, err
+		}
+		if !revokedAt.IsZero() {
+			return true, nil
+		}
+	}
+
+	// iat 只精确到秒，同一秒内签发的令牌也视为已撤销
+	revokedAt, err := store.RevokedAt(ctx, userRevocationKey(claims.UserId))
+	if err != nil {
+		return false, err
+	}
+	return !revokedAt.IsZero() && claims.IssuedAt <= revokedAt.Unix(), nil
+}
@@ -0,0 +1,94 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// revocationStores Redis 可用与不可用两种情况，分别使用缓存和数据库保存撤销记录
+var revocationStores = map[string]func(t *testing.T){
+	"cache": func(t *testing.T) {
+		Cache = NewMemoryCache()
+		t.Cleanup(func() { Cache = nil })
+		require.IsType(t, &CacheRevocationStore{}, GetRevocationStore())
+	},
+	"db": func(t *testing.T) {
+		require.IsType(t, &DBRevocationStore{}, GetRevocationStore())
+	},
+}
+
+func TestIsTokenRevoked(t *testing.T) {
+	for name, useStore := range revocationStores {
+		t.Run(name, func(t *testing.T) {
+			useTestDB(t)
+			useStore(t)
+			ctx := context.Background()
+			revoked := func(claims *Claims) bool {
+				result, err := IsTokenRevoked(ctx, claims)
+				require.NoError(t, err)
+				return result
+			}
+
+			// 单个令牌
+			token := &Claims{UserId: 1}
+			token.Id = "jti-1"
+			token.ExpiresAt = time.Now().Add(time.Hour).Unix()
+			require.NoError(t, RevokeToken(ctx, token))
+			assert.True(t, revoked(token))
+			other := &Claims{UserId: 1}
+			other.Id = "jti-2"
+			assert.False(t, revoked(other))
+			// 没有 jti 的令牌无法单独撤销
+			require.NoError(t, RevokeToken(ctx, &Claims{UserId: 1}))
+
+			// 会话下的全部令牌
+			require.NoError(t, revokeSessionTokens(ctx, "sid-1"))
+			assert.True(t, revoked(&Claims{UserId: 2, SessionID: "sid-1"}))
+			assert.False(t, revoked(&Claims{UserId: 2, SessionID: "sid-2"}))
+
+			// 用户在撤销时刻及之前签发的令牌，iat 只精确到秒，同一秒签发的也算
+			require.NoError(t, RevokeUserTokens(ctx, 3))
+			revokedAt, err := GetRevocationStore().RevokedAt(ctx, userRevocationKey(3))
+			require.NoError(t, err)
+			require.False(t, revokedAt.IsZero())
+			issuedAt := func(iat int64) *Claims {
+				claims := &Claims{UserId: 3}
+				claims.IssuedAt = iat
+				return claims
+			}
+			assert.True(t, revoked(issuedAt(revokedAt.Unix()-60)))
+			assert.True(t, revoked(issuedAt(revokedAt.Unix())))
+			assert.False(t, revoked(issuedAt(revokedAt.Unix()+1)))
+			assert.False(t, revoked(&Claims{UserId: 4}))
+		})
+	}
+}
+
+func TestRevocationStore_Expiry(t *testing.T) {
+	for name, useStore := range revocationStores {
+		t.Run(name, func(t *testing.T) {
+			useTestDB(t)
+			useStore(t)
+			ctx := context.Background()
+			store := GetRevocationStore()
+
+			// 过期的记录不再生效
+			now := time.Now()
+			require.NoError(t, store.Revoke(ctx, "jti:expired", now.Add(-time.Hour), now.Add(-time.Minute)))
+			revokedAt, err := store.RevokedAt(ctx, "jti:expired")
+			require.NoError(t, err)
+			assert.True(t, revokedAt.IsZero())
+
+			// 重复撤销时以最后一次为准
+			require.NoError(t, store.Revoke(ctx, "user:9", now.Add(-time.Minute), now.Add(time.Hour)))
+			require.NoError(t, store.Revoke(ctx, "user:9", now, now.Add(time.Hour)))
+			revokedAt, err = store.RevokedAt(ctx, "user:9")
+			require.NoError(t, err)
+			assert.Equal(t, now.Unix(), revokedAt.Unix())
+		})
+	}
+}
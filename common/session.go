@@ -0,0 +1,138 @@
+package common
+
+// 登录会话与设备管理
+
+import (
+	"context"
+	"errors"
+	"log"
+	"theing/gin-template/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sessionTouchInterval 最近活跃时间的更新间隔，避免每个请求都写库
+const sessionTouchInterval = time.Minute
+
+// ClientInfo 发起登录的客户端信息
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// createSession 在指定连接上为新的令牌族创建会话
+func createSession(db *gorm.DB, userID uint, sessionID string, client ClientInfo) error {
+	userAgent := client.UserAgent
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	now := time.Now()
+	return db.Create(&model.Session{
+		ID:         sessionID,
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         client.IP,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(getRefreshTokenExpirationTime()),
+	}).Error
+}
+
+// extendSession 刷新令牌轮换后顺延会话
+func extendSession(db *gorm.DB, sessionID string) error {
+	now := time.Now()
+	return db.Model(&model.Session{}).Where("id = ?", sessionID).Updates(map[string]interface{}{
+		"last_seen_at": now,
+		"expires_at":   now.Add(getRefreshTokenExpirationTime()),
+	}).Error
+}
+
+// TouchSession 记录会话最近活跃时间，间隔内重复调用不会写库
+func TouchSession(sessionID string) {
+	now := time.Now()
+	err := GetDB().Model(&model.Session{}).
+		Where("id = ? AND last_seen_at < ?", sessionID, now.Add(-sessionTouchInterval)).
+		Update("last_seen_at", now).Error
+	if err != nil {
+		log.Printf("更新会话活跃时间失败: %v", err)
+	}
+}
+
+// ListActiveSessions 查询用户未撤销且未过期的会话，最近活跃的在前
+func ListActiveSessions(userID uint) ([]model.Session, error) {
+	var sessions []model.Session
+	err := GetDB().Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession 撤销用户的某个会话，会话下的刷新令牌和访问令牌立即失效
+func RevokeSession(ctx context.Context, userID uint, sessionID string) error {
+	var session model.Session
+	err := GetDB().Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return NewAppError(CodeDataNotFound, "会话不存在或已失效", "")
+		}
+		return err
+	}
+	if err := RevokeRefreshTokenFamily(session.ID); err != nil {
+		return err
+	}
+	return revokeSessionTokens(ctx, session.ID)
+}
+
+// RevokeUserSessions 撤销用户的全部会话，exceptID 不为空时保留该会话，返回撤销的数量
+func RevokeUserSessions(ctx context.Context, userID uint, exceptID string) (int, error) {
+	sessions, err := ListActiveSessions(userID)
+	if err != nil {
+		return 0, err
+	}
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID == exceptID {
+			continue
+		}
+		if err := RevokeSession(ctx, userID, session.ID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// UpdateSessionMetrics 按用户类型统计活跃会话数并更新监控指标
+func UpdateSessionMetrics() error {
+	db := GetDB()
+	active := db.Model(&model.Session{}).Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+
+	var total int64
+	if err := active.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return err
+	}
+	var admins int64
+	adminUsers := db.Table("user_roles").Select("user_roles.user_id").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("roles.name = ?", RoleAdmin)
+	if err := active.Session(&gorm.Session{}).Where("user_id IN (?)", adminUsers).Count(&admins).Error; err != nil {
+		return err
+	}
+
+	metrics := GetMetrics()
+	metrics.UpdateActiveSessions(RoleAdmin, int(admins))
+	metrics.UpdateActiveSessions(RoleUser, int(total-admins))
+	return nil
+}
+
+// revokeSessionTokens 撤销会话下已签发的访问令牌，记录保留到最后一个访问令牌过期
+func revokeSessionTokens(ctx context.Context, sessionID string) error {
+	now := time.Now()
+	return GetRevocationStore().Revoke(ctx, sessionRevocationKey(sessionID), now, now.Add(getJWTExpirationTime()))
+}
+
+// markSessionsRevoked 在指定条件下标记会话已撤销，与刷新令牌的撤销保持一致
+func markSessionsRevoked(db *gorm.DB, query string, args ...interface{}) error {
+	return db.Model(&model.Session{}).Where(query, args...).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,101 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"theing/gin-template/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issueSession 为用户登录一次，返回令牌对和会话 ID
+func issueSession(t *testing.T, user model.User) (*TokenPair, string) {
+	t.Helper()
+	pair, err := IssueTokenPair(user, ClientInfo{UserAgent: "test"})
+	require.NoError(t, err)
+	_, claims, err := ParseToken(pair.AccessToken)
+	require.NoError(t, err)
+	return pair, claims.SessionID
+}
+
+func TestRevokeSession(t *testing.T) {
+	useKeys(t, "HS256", "")
+	db := useTestDB(t)
+	owner := createTestUser(t, db, "13950000001")
+	other := createTestUser(t, db, "13950000002")
+	ctx := context.Background()
+	pair, sessionID := issueSession(t, owner)
+
+	// 其他用户的会话按不存在处理，不影响会话本身
+	assertAppErrorCode(t, RevokeSession(ctx, other.ID, sessionID), CodeDataNotFound)
+	assertAppErrorCode(t, RevokeSession(ctx, owner.ID, "unknown"), CodeDataNotFound)
+	assert.False(t, accessTokenRevoked(t, pair.AccessToken))
+
+	// 撤销后刷新令牌族和会话下的访问令牌都失效
+	require.NoError(t, RevokeSession(ctx, owner.ID, sessionID))
+	assert.True(t, accessTokenRevoked(t, pair.AccessToken))
+	_, err := RotateRefreshToken(pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+	sessions, err := ListActiveSessions(owner.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	assertAppErrorCode(t, RevokeSession(ctx, owner.ID, sessionID), CodeDataNotFound)
+}
+
+func TestRevokeUserSessions_ExceptCurrent(t *testing.T) {
+	useKeys(t, "HS256", "")
+	db := useTestDB(t)
+	user := createTestUser(t, db, "13950000003")
+	other := createTestUser(t, db, "13950000004")
+	ctx := context.Background()
+
+	current, currentID := issueSession(t, user)
+	first, _ := issueSession(t, user)
+	second, _ := issueSession(t, user)
+	untouched, _ := issueSession(t, other)
+
+	count, err := RevokeUserSessions(ctx, user.ID, currentID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.False(t, accessTokenRevoked(t, current.AccessToken))
+	assert.True(t, accessTokenRevoked(t, first.AccessToken))
+	assert.True(t, accessTokenRevoked(t, second.AccessToken))
+	assert.False(t, accessTokenRevoked(t, untouched.AccessToken))
+
+	sessions, err := ListActiveSessions(user.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, currentID, sessions[0].ID)
+
+	// 不保留当前会话时全部撤销
+	count, err = RevokeUserSessions(ctx, user.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.True(t, accessTokenRevoked(t, current.AccessToken))
+}
+
+func TestTouchSession(t *testing.T) {
+	useKeys(t, "HS256", "")
+	db := useTestDB(t)
+	user := createTestUser(t, db, "13950000005")
+	_, sessionID := issueSession(t, user)
+	lastSeen := func() time.Time {
+		var session model.Session
+		require.NoError(t, db.First(&session, "id = ?", sessionID).Error)
+		return session.LastSeenAt
+	}
+
+	// 间隔内不写库
+	before := lastSeen()
+	TouchSession(sessionID)
+	assert.Equal(t, before.Unix(), lastSeen().Unix())
+
+	stale := time.Now().Add(-10 * time.Minute)
+	require.NoError(t, db.Model(&model.Session{}).Where("id = ?", sessionID).Update("last_seen_at", stale).Error)
+	TouchSession(sessionID)
+	assert.WithinDuration(t, time.Now(), lastSeen(), 5*time.Second)
+}
@@ -0,0 +1,114 @@
+package common
+
+// 短信发送
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"theing/gin-template/config"
+	"time"
+)
+
+// SMSSender 短信发送接口，接入短信服务商时实现此接口并通过 SetSMSSender 注册
+type SMSSender interface {
+	Send(ctx context.Context, phone, message string) error
+}
+
+// LogSMSSender 只把短信内容写入日志，用于开发环境
+type LogSMSSender struct{}
+
+// FileSMSSender 把短信以 JSON 行追加到文件，用于开发和测试环境查看验证码
+type FileSMSSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+// smsRecord 文件中的一条短信
+type smsRecord struct {
+	Phone   string    `json:"phone"`
+	Message string    `json:"message"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+var (
+	smsSender   SMSSender
+	smsSenderMu sync.RWMutex
+)
+
+// NewFileSMSSender 创建文件短信发送器
+func NewFileSMSSender(path string) *FileSMSSender {
+	return &FileSMSSender{path: path}
+}
+
+// Send 写入日志
+func (LogSMSSender) Send(ctx context.Context, phone, message string) error {
+	log.Printf("[SMS] 发送至 %s: %s", phone, message)
+	return nil
+}
+
+// Send 追加到文件
+func (s *FileSMSSender) Send(ctx context.Context, phone, message string) error {
+	data, err := json.Marshal(smsRecord{Phone: phone, Message: message, SentAt: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// SetSMSSender 注册短信发送器
+func SetSMSSender(sender SMSSender) {
+	smsSenderMu.Lock()
+	smsSender = sender
+	smsSenderMu.Unlock()
+}
+
+// GetSMSSender 获取短信发送器，未注册时按 sms.provider 配置创建
+func GetSMSSender() SMSSender {
+	smsSenderMu.RLock()
+	sender := smsSender
+	smsSenderMu.RUnlock()
+	if sender != nil {
+		return sender
+	}
+
+	sender, err := newSMSSender(config.Get().SMS.Provider)
+	if err != nil {
+		log.Printf("%v，改为写入日志", err)
+		sender = LogSMSSender{}
+	}
+	SetSMSSender(sender)
+	return sender
+}
+
+// newSMSSender 根据名称创建内置的短信发送器
+func newSMSSender(provider string) (SMSSender, error) {
+	switch provider {
+	case "", "log":
+		return LogSMSSender{}, nil
+	case "file":
+		path := config.Get().SMS.FilePath
+		if path == "" {
+			path = "logs/sms.log"
+		}
+		return NewFileSMSSender(path), nil
+	default:
+		return nil, fmt.Errorf("不支持的短信服务商: %s", provider)
+	}
+}
@@ -0,0 +1,91 @@
+package common
+
+// 管理员用户查询：分页、排序与过滤
+
+import (
+	"theing/gin-template/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// userSortColumns 允许排序的字段
+var userSortColumns = map[string]string{
+	"id":         "id",
+	"created_at": "created_at",
+	"username":   "username",
+	"telephone":  "telephone",
+}
+
+// UserQuery 用户列表查询条件，时间范围为闭区间
+type UserQuery struct {
+	Page        int        `form:"page"`
+	PageSize    int        `form:"page_size"`
+	Sort        string     `form:"sort" binding:"omitempty,oneof=id created_at username telephone"`
+	Order       string     `form:"order" binding:"omitempty,oneof=asc desc"`
+	Telephone   string     `form:"telephone"`
+	Username    string     `form:"username"` // 模糊匹配
+	Status      string     `form:"status" binding:"omitempty,oneof=pending active suspended deleted"`
+	CreatedFrom *time.Time `form:"created_from" time_format:"2006-01-02"`
+	CreatedTo   *time.Time `form:"created_to" time_format:"2006-01-02"`
+}
+
+// Normalize 补齐分页和排序的默认值，page 从 1 开始，page_size 最大 100
+func (q *UserQuery) Normalize() {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 || q.PageSize > 100 {
+		q.PageSize = 20
+	}
+	if _, ok := userSortColumns[q.Sort]; !ok {
+		q.Sort = "id"
+	}
+	if q.Order != "asc" {
+		q.Order = "desc"
+	}
+}
+
+// ListUsers 按条件分页查询用户并预加载角色，返回当前页和总数
+func ListUsers(q UserQuery) ([]model.User, int64, error) {
+	q.Normalize()
+
+	query := filterUsers(q)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []model.User
+	err := query.Preload("Roles").
+		Order(userSortColumns[q.Sort] + " " + q.Order).
+		Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).
+		Find(&users).Error
+	return users, total, err
+}
+
+// filterUsers 按查询条件构造用户查询，不含分页和排序
+func filterUsers(q UserQuery) *gorm.DB {
+	query := GetDB().Model(&model.User{})
+	if q.Status == UserStatusDeleted {
+		// 已删除的用户只有按状态查询时才返回
+		query = query.Unscoped()
+	}
+	if q.Telephone != "" {
+		query = query.Where("telephone = ?", q.Telephone)
+	}
+	if q.Username != "" {
+		query = query.Where("username LIKE ?", "%"+q.Username+"%")
+	}
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	}
+	if q.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *q.CreatedFrom)
+	}
+	if q.CreatedTo != nil {
+		// 只给出日期时包含当天
+		query = query.Where("created_at < ?", q.CreatedTo.AddDate(0, 0, 1))
+	}
+	return query
+}
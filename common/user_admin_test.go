@@ -0,0 +1,103 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"theing/gin-template/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listUserPhones 查询用户并返回手机号，顺序与结果一致
+func listUserPhones(t *testing.T, q UserQuery) ([]string, int64) {
+	t.Helper()
+	users, total, err := ListUsers(q)
+	require.NoError(t, err)
+	phones := make([]string, 0, len(users))
+	for _, user := range users {
+		phones = append(phones, user.Telephone)
+	}
+	return phones, total
+}
+
+func TestListUsers_Filters(t *testing.T) {
+	db := useTestDB(t)
+	day := func(s string) *time.Time {
+		d, err := time.ParseInLocation("2006-01-02", s, time.Local)
+		require.NoError(t, err)
+		return &d
+	}
+	seed := []struct {
+		telephone, username, status, created string
+	}{
+		{"13990000001", "alice", UserStatusActive, "2025-01-01"},
+		{"13990000002", "alicia", UserStatusSuspended, "2025-01-15"},
+		{"13990000003", "bob", UserStatusPending, "2025-02-01"},
+		{"13990000004", "carol", UserStatusActive, "2025-02-01"},
+	}
+	for _, s := range seed {
+		user := model.User{Telephone: s.telephone, Username: s.username, Password: "hash", Status: s.status}
+		require.NoError(t, db.Create(&user).Error)
+		require.NoError(t, db.Model(&user).Update("created_at", day(s.created).Add(12*time.Hour)).Error)
+	}
+	require.NoError(t, DeleteUser(context.Background(), 4))
+
+	phones, total := listUserPhones(t, UserQuery{Sort: "id", Order: "asc"})
+	assert.Equal(t, int64(3), total)
+	assert.Equal(t, []string{"13990000001", "13990000002", "13990000003"}, phones)
+
+	phones, _ = listUserPhones(t, UserQuery{Telephone: "13990000002"})
+	assert.Equal(t, []string{"13990000002"}, phones)
+
+	// 用户名模糊匹配
+	phones, total = listUserPhones(t, UserQuery{Username: "ali", Order: "asc"})
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, []string{"13990000001", "13990000002"}, phones)
+
+	phones, _ = listUserPhones(t, UserQuery{Status: UserStatusPending})
+	assert.Equal(t, []string{"13990000003"}, phones)
+
+	// 已删除的用户只有按状态查询时才返回
+	phones, _ = listUserPhones(t, UserQuery{Status: UserStatusDeleted})
+	assert.Equal(t, []string{deletedTelephone(model.User{ID: 4, Telephone: "13990000004"})}, phones)
+
+	// 结束日期包含当天
+	phones, total = listUserPhones(t, UserQuery{CreatedFrom: day("2025-01-15"), CreatedTo: day("2025-02-01"), Order: "asc"})
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, []string{"13990000002", "13990000003"}, phones)
+}
+
+func TestListUsers_SortAndPaging(t *testing.T) {
+	db := useTestDB(t)
+	for i, name := range []string{"erin", "bob", "dave", "alice", "carol"} {
+		user := model.User{Telephone: fmt.Sprintf("1399000010%d", i+1), Username: name, Password: "hash", Status: UserStatusActive}
+		require.NoError(t, db.Create(&user).Error)
+	}
+
+	users, total, err := ListUsers(UserQuery{Sort: "username", Order: "asc", PageSize: 2, Page: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	require.Len(t, users, 2)
+	assert.Equal(t, "carol", users[0].Username)
+	assert.Equal(t, "dave", users[1].Username)
+
+	// 最后一页不足一页，总数不受分页影响
+	users, total, err = ListUsers(UserQuery{Sort: "username", Order: "asc", PageSize: 2, Page: 3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	require.Len(t, users, 1)
+	assert.Equal(t, "erin", users[0].Username)
+
+	// 不在白名单中的排序字段和方向退回默认值
+	q := UserQuery{Sort: "password; DROP TABLE users", Order: "sideways", PageSize: 1000}
+	q.Normalize()
+	assert.Equal(t, UserQuery{Page: 1, PageSize: 20, Sort: "id", Order: "desc"}, q)
+	users, _, err = ListUsers(UserQuery{Sort: "password"})
+	require.NoError(t, err)
+	require.Len(t, users, 5)
+	assert.Equal(t, "carol", users[0].Username)
+}
@@ -0,0 +1,537 @@
+package common
+
+// 用户批量导入与导出：支持 CSV 和 XLSX，导入按手机号新增，指定更新时也更新已存在的用户
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+	"theing/gin-template/utils"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// 导入导出文件格式
+const (
+	UserFileFormatCSV  = "csv"
+	UserFileFormatXLSX = "xlsx"
+)
+
+// utf8BOM Excel 打开 UTF-8 编码的 CSV 时依赖 BOM 识别编码
+const utf8BOM = "\ufeff"
+
+// userImportColumns 导入文件可识别的列，其余列忽略，导出的文件可以直接再导入
+var userImportColumns = map[string]bool{
+	"telephone": true,
+	"name":      true,
+	"password":  true,
+	"roles":     true,
+}
+
+// userExportColumns 导出文件的列
+var userExportColumns = []string{"id", "name", "telephone", "status", "roles", "phone_verified_at", "created_at"}
+
+// RegistrationInput 注册和批量导入共用的用户资料校验规则
+type RegistrationInput struct {
+	Name      string `form:"name" json:"name" binding:"max=20"`
+	Telephone string `form:"telephone" json:"telephone" binding:"required,len=11,numeric"`
+}
+
+// UserImportError 导入文件中某一行的错误，row 为文件中的行号（表头为第 1 行）
+type UserImportError struct {
+	Row       int    `json:"row"`
+	Telephone string `json:"telephone,omitempty"`
+	Field     string `json:"field,omitempty"`
+	Message   string `json:"message"`
+}
+
+// UserImportReport 导入结果，dry_run 时 created 和 updated 为预计的数量，
+// skipped 为未指定更新时跳过的已存在用户
+type UserImportReport struct {
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Skipped int               `json:"skipped"`
+	Failed  int               `json:"failed"`
+	DryRun  bool              `json:"dry_run"`
+	Errors  []UserImportError `json:"errors"`
+}
+
+// UserImportOptions 导入选项
+type UserImportOptions struct {
+	DryRun bool
+	// Update 为 true 时更新已存在用户的用户名、密码和角色，否则已存在的用户不做任何修改
+	Update bool
+	// GrantRoles 为 true 时才能通过 roles 列授予角色，调用者需要 role:manage 权限
+	GrantRoles bool
+}
+
+// userImportRow 导入文件中的一行
+type userImportRow struct {
+	line     int
+	input    RegistrationInput
+	password string
+	roles    []string
+	existing *model.User
+}
+
+// UserFileFormat 确定导入文件的格式，优先使用显式指定的格式，否则按扩展名判断
+func UserFileFormat(format, filename string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(filename), ".")
+	}
+	switch format = strings.ToLower(format); format {
+	case UserFileFormatCSV, UserFileFormatXLSX:
+		return format, nil
+	}
+	return "", NewAppError(CodeInvalidParams, "不支持的文件格式", "仅支持 csv 和 xlsx")
+}
+
+// GetUserImportMaxRows 单次导入的最大行数（不含表头）
+func GetUserImportMaxRows() int {
+	if rows := config.Get().UserImport.MaxRows; rows > 0 {
+		return rows
+	}
+	return 5000
+}
+
+// GetUserImportMaxFileSize 导入文件的最大字节数
+func GetUserImportMaxFileSize() int64 {
+	if mb := int64(config.Get().UserImport.MaxFileSizeMB); mb > 0 {
+		return mb << 20
+	}
+	return 10 << 20
+}
+
+// userImportXLSXLimits 读取 xlsx 导入文件的限制：行数含表头，每行平均不超过 64 个单元格
+func userImportXLSXLimits() utils.XLSXLimits {
+	maxRows := GetUserImportMaxRows() + 1
+	limits := utils.XLSXLimits{MaxPartSize: 50 << 20, MaxRows: maxRows, MaxCells: maxRows * 64}
+	if mb := int64(config.Get().UserImport.MaxUnzippedSizeMB); mb > 0 {
+		limits.MaxPartSize = mb << 20
+	}
+	return limits
+}
+
+// ReadUserImportFile 读取导入文件的全部行
+func ReadUserImportFile(r io.ReaderAt, size int64, format string) ([][]string, error) {
+	if size > GetUserImportMaxFileSize() {
+		return nil, NewAppError(CodeInvalidParams, "文件过大", fmt.Sprintf("最大 %d MB", GetUserImportMaxFileSize()>>20))
+	}
+	if format == UserFileFormatXLSX {
+		rows, err := utils.ReadXLSX(r, size, userImportXLSXLimits())
+		if errors.Is(err, utils.ErrXLSXTooLarge) {
+			return nil, NewAppError(CodeInvalidParams, "文件内容超过导入限制", err.Error())
+		}
+		if err != nil {
+			return nil, NewAppError(CodeInvalidParams, "无法读取文件", err.Error())
+		}
+		return rows, nil
+	}
+
+	reader := csv.NewReader(io.NewSectionReader(r, 0, size))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, NewAppError(CodeInvalidParams, "无法读取文件", err.Error())
+	}
+	if len(rows) > 0 && len(rows[0]) > 0 {
+		rows[0][0] = strings.TrimPrefix(rows[0][0], utf8BOM)
+	}
+	return rows, nil
+}
+
+// ImportUsers 按手机号导入用户：不存在的新建，已存在的只在 opts.Update 时更新用户名、密码和角色。
+// 新用户提供密码时直接启用，否则为待激活状态，需要通过短信验证码设置密码。
+// 已存在用户的密码或角色变更后撤销其令牌，与修改密码、授予角色的接口一致。
+// 有错误的行跳过，其余行逐行写入；dry_run 时只校验不写入。
+func ImportUsers(ctx context.Context, rows [][]string, opts UserImportOptions) (*UserImportReport, error) {
+	if len(rows) == 0 {
+		return nil, NewAppError(CodeInvalidParams, "文件为空", "")
+	}
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !userImportColumns[name] {
+			continue
+		}
+		if _, ok := columns[name]; ok {
+			return nil, NewAppError(CodeInvalidParams, "表头有重复的列", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["telephone"]; !ok {
+		return nil, NewAppError(CodeInvalidParams, "缺少 telephone 列", "")
+	}
+
+	report := &UserImportReport{DryRun: opts.DryRun, Errors: make([]UserImportError, 0)}
+	var records []userImportRow
+	for i, row := range rows[1:] {
+		if isBlankRow(row) {
+			continue
+		}
+		cell := func(name string) string {
+			if idx, ok := columns[name]; ok && idx < len(row) {
+				return strings.TrimSpace(row[idx])
+			}
+			return ""
+		}
+		records = append(records, userImportRow{
+			line:     i + 2,
+			input:    RegistrationInput{Name: cell("name"), Telephone: cell("telephone")},
+			password: cell("password"),
+			roles:    splitRoles(cell("roles")),
+		})
+	}
+	report.Total = len(records)
+	if maxRows := GetUserImportMaxRows(); report.Total > maxRows {
+		return nil, NewAppError(CodeInvalidParams, "导入行数超过上限", fmt.Sprintf("最多 %d 行", maxRows))
+	}
+
+	roles, err := roleNameSet()
+	if err != nil {
+		return nil, err
+	}
+	valid, err := validateUserImportRows(records, roles, opts, report)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range valid {
+		if record.existing != nil && !opts.Update {
+			report.Skipped++
+			continue
+		}
+		if !opts.DryRun {
+			if err := applyUserImportRow(record); err != nil {
+				report.addError(record, "", importErrorMessage(err))
+				continue
+			}
+			if record.existing != nil {
+				revokeImportedUserTokens(ctx, record)
+			}
+		}
+		if record.existing != nil {
+			report.Updated++
+		} else {
+			report.Created++
+		}
+	}
+	report.Failed = report.Total - report.Created - report.Updated - report.Skipped
+	return report, nil
+}
+
+// revokeImportedUserTokens 已存在的用户被修改密码时撤销全部登录，被授予角色时撤销访问令牌以便按新角色重新签发
+func revokeImportedUserTokens(ctx context.Context, record userImportRow) {
+	userID := record.existing.ID
+	if record.password != "" {
+		if _, err := RevokeUserSessions(ctx, userID, ""); err != nil {
+			log.Printf("撤销用户 %d 的会话失败: %v", userID, err)
+		}
+		if err := RevokeUserRefreshTokens(userID); err != nil {
+			log.Printf("撤销用户 %d 的刷新令牌失败: %v", userID, err)
+		}
+	}
+	if record.password != "" || len(record.roles) > 0 {
+		if err := RevokeUserTokens(ctx, userID); err != nil {
+			log.Printf("撤销用户 %d 的令牌失败: %v", userID, err)
+		}
+	}
+}
+
+// validateUserImportRows 校验每一行，错误记入报告，返回通过校验的行
+func validateUserImportRows(records []userImportRow, roles map[string]bool, opts UserImportOptions, report *UserImportReport) ([]userImportRow, error) {
+	db := GetDB()
+	seen := make(map[string]int, len(records))
+	valid := make([]userImportRow, 0, len(records))
+	for _, record := range records {
+		failed := len(report.Errors)
+
+		if err := binding.Validator.ValidateStruct(record.input); err != nil {
+			var fieldErrs validator.ValidationErrors
+			if !errors.As(err, &fieldErrs) {
+				return nil, err
+			}
+			for _, fe := range fieldErrs {
+				report.addError(record, strings.ToLower(fe.Field()), fieldErrorMessage(fe))
+			}
+		}
+		if line, ok := seen[record.input.Telephone]; ok && record.input.Telephone != "" {
+			report.addError(record, "telephone", fmt.Sprintf("与第 %d 行的手机号重复", line))
+		} else {
+			seen[record.input.Telephone] = record.line
+		}
+		if len(record.roles) > 0 && !opts.GrantRoles {
+			report.addError(record, "roles", "授予角色需要 "+PermRoleManage+" 权限")
+		}
+		for _, role := range record.roles {
+			if !roles[role] {
+				report.addError(record, "roles", "角色不存在: "+role)
+			}
+		}
+
+		var user model.User
+		if err := db.Where("telephone = ?", record.input.Telephone).Limit(1).Find(&user).Error; err != nil {
+			return nil, err
+		}
+		subject := PasswordSubject{Username: record.input.Name, Telephone: record.input.Telephone}
+		if user.ID != 0 {
+			record.existing = &user
+			subject.UserID = user.ID
+			if subject.Username == "" {
+				subject.Username = user.Username
+			}
+		}
+		if record.password != "" && (record.existing == nil || opts.Update) {
+			if err := ValidatePassword(record.password, subject); err != nil {
+				report.addError(record, "password", importErrorMessage(err))
+			}
+		}
+		if len(report.Errors) == failed {
+			valid = append(valid, record)
+		}
+	}
+	return valid, nil
+}
+
+// applyUserImportRow 在事务中写入一行
+func applyUserImportRow(record userImportRow) error {
+	return GetDB().Transaction(func(tx *gorm.DB) error {
+		userID := uint(0)
+		if record.existing != nil {
+			userID = record.existing.ID
+			if record.input.Name != "" && record.input.Name != record.existing.Username {
+				if err := tx.Model(&model.User{}).Where("id = ?", userID).Update("username", record.input.Name).Error; err != nil {
+					return err
+				}
+			}
+			if record.password != "" {
+				if err := SetUserPassword(tx, userID, record.password); err != nil {
+					return err
+				}
+			}
+		} else {
+			user, err := createImportedUser(tx, record)
+			if err != nil {
+				return err
+			}
+			userID = user.ID
+		}
+
+		for _, role := range record.roles {
+			if err := assignRole(tx, userID, role); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// createImportedUser 新建导入的用户并授予普通用户角色
+func createImportedUser(tx *gorm.DB, record userImportRow) (*model.User, error) {
+	name := record.input.Name
+	if name == "" {
+		name = utils.RandomString(10)
+	}
+	user := model.User{
+		Username:  name,
+		Telephone: record.input.Telephone,
+		Status:    UserStatusActive,
+	}
+	password := record.password
+	if password == "" {
+		// 未提供密码时生成无法告知用户的随机密码，用户通过短信验证码重置密码后激活
+		user.Status = UserStatusPending
+		password = utils.RandomString(32)
+	}
+	hashed, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	user.Password = hashed
+	user.PasswordChangedAt = &now
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	if record.password != "" {
+		if err := RecordPasswordHistory(tx, user.ID, hashed); err != nil {
+			return nil, err
+		}
+	}
+	if err := assignRole(tx, user.ID, RoleUser); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ExportUsers 按查询条件把用户流式写出为 CSV 或 XLSX，分页参数不生效
+func ExportUsers(w io.Writer, format string, q UserQuery) error {
+	var rows interface {
+		WriteRow([]string) error
+		Close() error
+	}
+	if format == UserFileFormatXLSX {
+		xw, err := utils.NewXLSXWriter(w, "users")
+		if err != nil {
+			return err
+		}
+		rows = xw
+	} else {
+		if _, err := io.WriteString(w, utf8BOM); err != nil {
+			return err
+		}
+		rows = &csvRowWriter{w: csv.NewWriter(w)}
+	}
+
+	if err := rows.WriteRow(userExportColumns); err != nil {
+		return err
+	}
+	var users []model.User
+	result := filterUsers(q).Preload("Roles").FindInBatches(&users, 500, func(tx *gorm.DB, batch int) error {
+		for _, user := range users {
+			if err := rows.WriteRow(userExportRow(user)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	return rows.Close()
+}
+
+// userExportRow 导出文件中的一行，与 userExportColumns 对应
+func userExportRow(user model.User) []string {
+	formatTime := func(t *time.Time) string {
+		if t == nil || t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	status := user.Status
+	if status == "" {
+		status = UserStatusActive
+	}
+	return []string{
+		strconv.FormatUint(uint64(user.ID), 10),
+		user.Username,
+		user.Telephone,
+		status,
+		strings.Join(RoleNames(user), ";"),
+		formatTime(user.PhoneVerifiedAt),
+		formatTime(&user.CreatedAt),
+	}
+}
+
+// csvRowWriter 与 XLSXWriter 相同接口的 CSV 写入
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) WriteRow(values []string) error {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = csvSafeCell(value)
+	}
+	return c.w.Write(escaped)
+}
+
+func (c *csvRowWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// csvSafeCell 防止 CSV 注入：表格软件会把以这些字符开头的单元格当作公式执行，前面加单引号使其按文本显示。
+// XLSX 的单元格一律写成内联字符串，不需要处理。
+func csvSafeCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// addError 记录一行的错误
+func (r *UserImportReport) addError(record userImportRow, field, message string) {
+	r.Errors = append(r.Errors, UserImportError{
+		Row:       record.line,
+		Telephone: record.input.Telephone,
+		Field:     field,
+		Message:   message,
+	})
+}
+
+// roleNameSet 全部角色名
+func roleNameSet() (map[string]bool, error) {
+	var names []string
+	if err := GetDB().Model(&model.Role{}).Pluck("name", &names).Error; err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set, nil
+}
+
+// splitRoles 解析以分号分隔的角色列表
+func splitRoles(value string) []string {
+	var roles []string
+	for _, role := range strings.Split(value, ";") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// isBlankRow 整行都是空白
+func isBlankRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldErrorMessage 字段校验失败的提示
+func fieldErrorMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "不能为空"
+	case "len":
+		return fmt.Sprintf("长度必须为 %s 位", fe.Param())
+	case "max":
+		return fmt.Sprintf("长度不能超过 %s", fe.Param())
+	case "numeric":
+		return "只能包含数字"
+	}
+	return fe.Error()
+}
+
+// importErrorMessage 写入报告的错误信息，密码策略等业务错误带上具体原因
+func importErrorMessage(err error) string {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		if appErr.Details != "" {
+			return appErr.Message + ": " + appErr.Details
+		}
+		return appErr.Message
+	}
+	log.Printf("导入用户失败: %v", err)
+	return "写入失败"
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+
+	"theing/gin-template/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserFileFormat(t *testing.T) {
+	format, err := UserFileFormat("", "users.XLSX")
+	require.NoError(t, err)
+	assert.Equal(t, UserFileFormatXLSX, format)
+
+	format, err = UserFileFormat("csv", "users.xlsx")
+	require.NoError(t, err)
+	assert.Equal(t, UserFileFormatCSV, format)
+
+	_, err = UserFileFormat("", "users.txt")
+	assertAppErrorCode(t, err, CodeInvalidParams)
+}
+
+func TestReadUserImportFile_CSV(t *testing.T) {
+	content := utf8BOM + "Telephone,name,roles\n13800000000, 张三 ,admin; user\n"
+	rows, err := ReadUserImportFile(strings.NewReader(content), int64(len(content)), UserFileFormatCSV)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"Telephone", "name", "roles"}, {"13800000000", "张三 ", "admin; user"}}, rows)
+	assert.Equal(t, []string{"admin", "user"}, splitRoles(rows[1][2]))
+}
+
+func TestImportUsers_InvalidHeader(t *testing.T) {
+	_, err := ImportUsers(context.Background(), [][]string{{"name", "password"}}, UserImportOptions{DryRun: true})
+	assertAppErrorCode(t, err, CodeInvalidParams)
+
+	_, err = ImportUsers(context.Background(), nil, UserImportOptions{DryRun: true})
+	assertAppErrorCode(t, err, CodeInvalidParams)
+}
+
+func TestExportUsers_EscapesFormulas(t *testing.T) {
+	db := useTestDB(t)
+	user := createTestUser(t, db, "13930000001")
+	formula := `=HYPERLINK("https://evil.example.com","点击")`
+	require.NoError(t, db.Model(&user).Update("username", formula).Error)
+
+	var csvOut bytes.Buffer
+	require.NoError(t, ExportUsers(&csvOut, UserFileFormatCSV, UserQuery{}))
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(csvOut.String(), utf8BOM))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "'"+formula, records[1][1])
+	assert.Equal(t, "13930000001", records[1][2])
+
+	// XLSX 单元格是内联字符串，原样保留也不会被当作公式
+	var xlsxOut bytes.Buffer
+	require.NoError(t, ExportUsers(&xlsxOut, UserFileFormatXLSX, UserQuery{}))
+	rows, err := utils.ReadXLSX(bytes.NewReader(xlsxOut.Bytes()), int64(xlsxOut.Len()), utils.XLSXLimits{MaxPartSize: 1 << 20, MaxRows: 10, MaxCells: 100})
+	require.NoError(t, err)
+	assert.Equal(t, formula, rows[1][1])
+	zr, err := zip.NewReader(bytes.NewReader(xlsxOut.Bytes()), int64(xlsxOut.Len()))
+	require.NoError(t, err)
+	found := false
+	for _, f := range zr.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			found = true
+			rc, err := f.Open()
+			require.NoError(t, err)
+			sheet, err := io.ReadAll(rc)
+			rc.Close()
+			require.NoError(t, err)
+			assert.NotContains(t, string(sheet), "<f>")
+			assert.Contains(t, string(sheet), `t="inlineStr"`)
+		}
+	}
+	assert.True(t, found)
+
+	for _, value := range []string{"+1", "-1", "@SUM(A1)", "\tx", "\rx"} {
+		assert.Equal(t, "'"+value, csvSafeCell(value))
+	}
+	assert.Equal(t, "张三", csvSafeCell("张三"))
+	assert.Equal(t, "", csvSafeCell(""))
+}
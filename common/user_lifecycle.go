@@ -0,0 +1,120 @@
+package common
+
+// 账号生命周期：待激活、正常、停用与注销（软删除）
+
+import (
+	"context"
+	"fmt"
+	"theing/gin-template/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 账号状态
+const (
+	UserStatusPending   = "pending"   // 待激活，需通过短信验证码设置密码
+	UserStatusActive    = "active"    // 正常
+	UserStatusSuspended = "suspended" // 已停用，无法登录
+	UserStatusDeleted   = "deleted"   // 已注销或被删除，记录软删除保留
+)
+
+// CheckUserActive 检查账号状态，只有正常状态的账号可以登录和访问接口
+func CheckUserActive(user model.User) error {
+	switch {
+	case user.DeletedAt.Valid || user.Status == UserStatusDeleted:
+		return ErrAccountDeleted
+	case user.Status == UserStatusSuspended:
+		return ErrAccountSuspended
+	case user.Status == UserStatusPending:
+		return ErrAccountPending
+	}
+	return nil
+}
+
+// SetUserStatus 管理员修改账号状态，只能改为正常或停用；停用时撤销该用户全部的登录、令牌和 API 密钥
+func SetUserStatus(ctx context.Context, userID uint, status string) error {
+	if status != UserStatusActive && status != UserStatusSuspended {
+		return NewAppError(CodeInvalidParams, "不支持的账号状态", status)
+	}
+	if err := GetDB().Model(&model.User{}).Where("id = ?", userID).Update("status", status).Error; err != nil {
+		return err
+	}
+	if status == UserStatusSuspended {
+		return revokeUserAccess(ctx, userID)
+	}
+	return nil
+}
+
+// ActivatePendingUser 待激活的账号通过短信验证后转为正常状态，其他状态不变
+func ActivatePendingUser(db *gorm.DB, userID uint) error {
+	return db.Model(&model.User{}).
+		Where("id = ? AND status = ?", userID, UserStatusPending).
+		Updates(map[string]interface{}{
+			"status":            UserStatusActive,
+			"phone_verified_at": time.Now(),
+		}).Error
+}
+
+// ForcePasswordReset 要求用户修改密码：撤销现有登录，之后签发的令牌带 password_expired 标记。
+// 用户设置新密码之前，AuthMiddleware 只允许访问修改密码和退出登录的接口
+func ForcePasswordReset(ctx context.Context, userID uint) error {
+	if err := GetDB().Model(&model.User{}).Where("id = ?", userID).Update("password_reset_required", true).Error; err != nil {
+		return err
+	}
+	return revokeUserAccess(ctx, userID)
+}
+
+// DeleteUser 注销账号：撤销全部登录后软删除，状态改为 deleted。
+// 手机号有唯一索引，改写为带删除标记的值，原手机号可以重新注册。
+func DeleteUser(ctx context.Context, userID uint) error {
+	if err := revokeUserAccess(ctx, userID); err != nil {
+		return err
+	}
+	return GetDB().Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.Limit(1).Find(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.ID == 0 {
+			return ErrUserNotFound
+		}
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"status":    UserStatusDeleted,
+			"telephone": deletedTelephone(user),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&user).Error
+	})
+}
+
+// deletedTelephone 注销后保存的手机号，保留原号码便于审计
+func deletedTelephone(user model.User) string {
+	return fmt.Sprintf("%s#deleted-%d", user.Telephone, user.ID)
+}
+
+// revokeUserAccess 撤销用户的会话、刷新令牌、访问令牌、API 密钥、第三方应用令牌和尚未兑换的授权码
+func revokeUserAccess(ctx context.Context, userID uint) error {
+	if _, err := RevokeUserSessions(ctx, userID, ""); err != nil {
+		return err
+	}
+	if err := RevokeUserRefreshTokens(userID); err != nil {
+		return err
+	}
+	if err := RevokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	if err := RevokeUserAPIKeys(userID); err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := GetDB().Model(&model.OAuthAuthorizationCode{}).
+		Where("user_id = ? AND used_at IS NULL AND expires_at > ?", userID, now).
+		Update("expires_at", now).Error; err != nil {
+		return err
+	}
+	return GetDB().Model(&model.OAuthRefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
@@ -0,0 +1,102 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"theing/gin-template/model"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCheckUserActive(t *testing.T) {
+	assert.NoError(t, CheckUserActive(model.User{Status: UserStatusActive}))
+	// 迁移前的旧数据没有状态，视为正常
+	assert.NoError(t, CheckUserActive(model.User{}))
+
+	cases := map[string]model.User{
+		UserStatusPending:   {Status: UserStatusPending},
+		UserStatusSuspended: {Status: UserStatusSuspended},
+		UserStatusDeleted:   {Status: UserStatusDeleted},
+	}
+	for status, user := range cases {
+		err := CheckUserActive(user)
+		assertAppErrorCode(t, err, CodeAccountInactive)
+		assert.Equal(t, map[string]string{"status": status}, err.(*AppError).Data)
+	}
+
+	// 已软删除的账号无论状态字段如何都视为已注销
+	deleted := model.User{Status: UserStatusActive, DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}
+	assert.Equal(t, ErrAccountDeleted, CheckUserActive(deleted))
+}
+
+func TestDeletedTelephone(t *testing.T) {
+	assert.Equal(t, "13800000000#deleted-7", deletedTelephone(model.User{ID: 7, Telephone: "13800000000"}))
+}
+
+// userAccess 用户持有的各类凭据
+type userAccess struct {
+	pair   *TokenPair
+	apiKey string
+}
+
+// grantUserAccess 为用户登录一次并创建 API 密钥
+func grantUserAccess(t *testing.T, user model.User) userAccess {
+	t.Helper()
+	pair, err := IssueTokenPair(user, ClientInfo{})
+	require.NoError(t, err)
+	_, apiKey, err := CreateAPIKey(user, "ci", nil, 0)
+	require.NoError(t, err)
+	return userAccess{pair: pair, apiKey: apiKey}
+}
+
+// assertAccessRevoked 断言访问令牌、刷新令牌、会话和 API 密钥全部失效
+func assertAccessRevoked(t *testing.T, user model.User, access userAccess) {
+	t.Helper()
+	assert.True(t, accessTokenRevoked(t, access.pair.AccessToken))
+	_, err := RotateRefreshToken(access.pair.RefreshToken)
+	assert.Error(t, err)
+	sessions, err := ListActiveSessions(user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+	_, err = AuthenticateAPIKey(access.apiKey, "127.0.0.1")
+	assert.Equal(t, ErrAPIKeyInvalid, err)
+}
+
+func TestSetUserStatus_RevokesAccess(t *testing.T) {
+	useKeys(t, "HS256", "")
+	db := useTestDB(t)
+	ctx := context.Background()
+	user := createTestUser(t, db, "13990000201")
+	other := createTestUser(t, db, "13990000202")
+	access := grantUserAccess(t, user)
+	untouched := grantUserAccess(t, other)
+
+	require.NoError(t, SetUserStatus(ctx, user.ID, UserStatusSuspended))
+	var current model.User
+	require.NoError(t, db.First(&current, user.ID).Error)
+	assert.Equal(t, UserStatusSuspended, current.Status)
+	assertAccessRevoked(t, user, access)
+	assert.False(t, accessTokenRevoked(t, untouched.pair.AccessToken))
+
+	// 只能在正常和停用之间切换
+	assertAppErrorCode(t, SetUserStatus(ctx, user.ID, UserStatusDeleted), CodeInvalidParams)
+	require.NoError(t, SetUserStatus(ctx, user.ID, UserStatusActive))
+	require.NoError(t, db.First(&current, user.ID).Error)
+	assert.NoError(t, CheckUserActive(current))
+}
+
+func TestForcePasswordReset_RevokesAccess(t *testing.T) {
+	useKeys(t, "HS256", "")
+	db := useTestDB(t)
+	user := createTestUser(t, db, "13990000203")
+	access := grantUserAccess(t, user)
+
+	require.NoError(t, ForcePasswordReset(context.Background(), user.ID))
+	var current model.User
+	require.NoError(t, db.First(&current, user.ID).Error)
+	assert.True(t, current.PasswordResetRequired)
+	assertAccessRevoked(t, user, access)
+}
@@ -0,0 +1,208 @@
+package common
+
+// 短信验证码的发送、限流与校验
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"theing/gin-template/config"
+	"theing/gin-template/utils"
+	"time"
+)
+
+// 验证码用途，不同用途的验证码互不通用
+const (
+	VerifyPurposeRegister      = "register"       // 注册时验证手机号
+	VerifyPurposeResetPassword = "reset_password" // 找回密码
+	VerifyPurposeChangePhone   = "change_phone"   // 更换手机号，发送到新手机号
+)
+
+// verificationMessages 各用途的短信模板
+var verificationMessages = map[string]string{
+	VerifyPurposeRegister:      "您的注册验证码为 %s，%d 分钟内有效。",
+	VerifyPurposeResetPassword: "您正在重置密码，验证码为 %s，%d 分钟内有效。如非本人操作请忽略。",
+	VerifyPurposeChangePhone:   "您正在将此手机号绑定到账号，验证码为 %s，%d 分钟内有效。如非本人操作请忽略。",
+}
+
+// verificationEntry 缓存中保存的验证码，只保存哈希值。错误次数单独计数，见 verificationAttemptsKey
+type verificationEntry struct {
+	CodeHash  string    `json:"code_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// verificationConfig 验证码配置
+type verificationConfig struct {
+	codeLength      int
+	codeTTL         time.Duration
+	maxAttempts     int
+	resendInterval  time.Duration
+	maxSendsPerHour int
+}
+
+// getVerificationConfig 读取验证码配置
+func getVerificationConfig() verificationConfig {
+	settings := config.Get().Verification
+	cfg := verificationConfig{
+		codeLength:      settings.CodeLength,
+		codeTTL:         time.Duration(settings.CodeTTLMinutes) * time.Minute,
+		maxAttempts:     settings.MaxAttempts,
+		resendInterval:  time.Duration(settings.ResendSeconds) * time.Second,
+		maxSendsPerHour: settings.MaxSendsPerHour,
+	}
+	if cfg.codeLength <= 0 {
+		cfg.codeLength = 6
+	}
+	if cfg.codeTTL <= 0 {
+		cfg.codeTTL = 5 * time.Minute
+	}
+	if cfg.maxAttempts <= 0 {
+		cfg.maxAttempts = 5
+	}
+	if cfg.resendInterval <= 0 {
+		cfg.resendInterval = 60 * time.Second
+	}
+	if cfg.maxSendsPerHour <= 0 {
+		cfg.maxSendsPerHour = 5
+	}
+	return cfg
+}
+
+// RegisterRequiresVerification 注册时是否要求短信验证码
+func RegisterRequiresVerification() bool {
+	return config.Get().Verification.RequireOnRegister
+}
+
+// IsValidVerifyPurpose 判断验证码用途是否受支持
+func IsValidVerifyPurpose(purpose string) bool {
+	_, ok := verificationMessages[purpose]
+	return ok
+}
+
+// SendVerificationCode 生成并发送验证码，同一手机号受重发间隔和每小时次数限制
+func SendVerificationCode(ctx context.Context, purpose, phone string) error {
+	template, ok := verificationMessages[purpose]
+	if !ok {
+		return NewAppError(CodeInvalidParams, "不支持的验证码用途", purpose)
+	}
+	cfg := getVerificationConfig()
+	cache := GetCache()
+	now := time.Now()
+
+	// 发送频率限制按手机号计算，与用途无关。计数在缓存中原子递增，并发请求不会绕过限制
+	resendKey := "verify:resend:" + phone
+	sent, err := cache.Incr(ctx, resendKey, cfg.resendInterval)
+	if err != nil {
+		return err
+	}
+	if sent > 1 {
+		return throttleError(CodeTooManyRequests, "验证码发送过于频繁", cfg.resendInterval)
+	}
+	hourly, err := cache.Incr(ctx, "verify:send:"+phone, time.Hour)
+	if err != nil {
+		return err
+	}
+	if hourly > int64(cfg.maxSendsPerHour) {
+		return throttleError(CodeTooManyRequests, "验证码发送次数已达上限", time.Hour)
+	}
+
+	code, err := utils.GenerateNumericCode(cfg.codeLength)
+	if err != nil {
+		return err
+	}
+	entry := verificationEntry{
+		CodeHash:  hashVerificationCode(purpose, phone, code),
+		ExpiresAt: now.Add(cfg.codeTTL),
+	}
+	// 新验证码重新计算错误次数
+	if err := cache.Delete(ctx, verificationAttemptsKey(purpose, phone)); err != nil {
+		return err
+	}
+	if err := saveCacheJSON(ctx, verificationKey(purpose, phone), entry, cfg.codeTTL); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf(template, code, int(cfg.codeTTL.Minutes()))
+	if err := GetSMSSender().Send(ctx, phone, message); err != nil {
+		_ = cache.Delete(ctx, verificationKey(purpose, phone))
+		// 发送失败时允许立即重试，每小时的次数仍然计入
+		_ = cache.Delete(ctx, resendKey)
+		return NewAppError(CodeServiceUnavailable, "短信发送失败", err.Error())
+	}
+	return nil
+}
+
+// VerifyCode 校验验证码，校验成功后验证码作废，错误次数过多时也会作废。
+// 每次校验先原子递增错误计数再比较，并发请求同样受 max_attempts 限制
+func VerifyCode(ctx context.Context, purpose, phone, code string) error {
+	key := verificationKey(purpose, phone)
+	var entry verificationEntry
+	if err := loadCacheJSON(ctx, key, &entry); err != nil {
+		return err
+	}
+	now := time.Now()
+	if entry.CodeHash == "" || now.After(entry.ExpiresAt) {
+		return ErrVerificationCodeInvalid
+	}
+
+	cache := GetCache()
+	attemptsKey := verificationAttemptsKey(purpose, phone)
+	attempts, err := cache.Incr(ctx, attemptsKey, entry.ExpiresAt.Sub(now))
+	if err != nil {
+		return err
+	}
+	maxAttempts := int64(getVerificationConfig().maxAttempts)
+	if attempts > maxAttempts {
+		_ = cache.Delete(ctx, key)
+		return NewAppError(CodeVerificationCodeInvalid, "验证码错误次数过多，请重新获取", "")
+	}
+
+	expected := []byte(entry.CodeHash)
+	actual := []byte(hashVerificationCode(purpose, phone, code))
+	if subtle.ConstantTimeCompare(expected, actual) == 1 {
+		_ = cache.Delete(ctx, attemptsKey)
+		return cache.Delete(ctx, key)
+	}
+	if attempts >= maxAttempts {
+		_ = cache.Delete(ctx, key)
+		return NewAppError(CodeVerificationCodeInvalid, "验证码错误次数过多，请重新获取", "")
+	}
+	return ErrVerificationCodeInvalid
+}
+
+// verificationAttemptsKey 验证码校验次数的缓存键
+func verificationAttemptsKey(purpose, phone string) string {
+	return fmt.Sprintf("verify:attempts:%s:%s", purpose, phone)
+}
+
+// verificationKey 验证码的缓存键
+func verificationKey(purpose, phone string) string {
+	return fmt.Sprintf("verify:code:%s:%s", purpose, phone)
+}
+
+// hashVerificationCode 验证码哈希，绑定用途和手机号
+func hashVerificationCode(purpose, phone, code string) string {
+	return utils.HashToken(purpose + ":" + phone + ":" + code)
+}
+
+// loadCacheJSON 读取 JSON 缓存，不存在时保持 dest 不变
+func loadCacheJSON(ctx context.Context, key string, dest interface{}) error {
+	value, err := GetCache().Get(ctx, key)
+	if err != nil {
+		if IsCacheMiss(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal([]byte(value), dest)
+}
+
+// saveCacheJSON 写入 JSON 缓存
+func saveCacheJSON(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return GetCache().Set(ctx, key, string(data), ttl)
+}
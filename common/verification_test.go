@@ -0,0 +1,146 @@
+package common
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+
+	"theing/gin-template/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureSMSSender 记录发送的短信，供测试读取验证码
+type captureSMSSender struct {
+	mu       sync.Mutex
+	messages map[string]string
+}
+
+func (s *captureSMSSender) Send(ctx context.Context, phone, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[phone] = message
+	return nil
+}
+
+// code 提取最近一条短信中的验证码
+func (s *captureSMSSender) code(t *testing.T, phone string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := regexp.MustCompile(`\d{6}`).FindString(s.messages[phone])
+	require.NotEmpty(t, code)
+	return code
+}
+
+// useCaptureSender 替换短信发送器，测试结束后恢复
+func useCaptureSender(t *testing.T) *captureSMSSender {
+	sender := &captureSMSSender{messages: make(map[string]string)}
+	SetSMSSender(sender)
+	t.Cleanup(func() { SetSMSSender(nil) })
+	return sender
+}
+
+func TestVerificationCode_SingleUse(t *testing.T) {
+	sender := useCaptureSender(t)
+	ctx := context.Background()
+	phone := "13700000001"
+
+	require.NoError(t, SendVerificationCode(ctx, VerifyPurposeResetPassword, phone))
+	code := sender.code(t, phone)
+
+	// 不同用途的验证码互不通用
+	assert.Equal(t, ErrVerificationCodeInvalid, VerifyCode(ctx, VerifyPurposeRegister, phone, code))
+
+	require.NoError(t, VerifyCode(ctx, VerifyPurposeResetPassword, phone, code))
+	assert.Equal(t, ErrVerificationCodeInvalid, VerifyCode(ctx, VerifyPurposeResetPassword, phone, code))
+}
+
+func TestVerificationCode_AttemptLimit(t *testing.T) {
+	sender := useCaptureSender(t)
+	useConfig(t, func(cfg *config.Config) { cfg.Verification.MaxAttempts = 3 })
+	ctx := context.Background()
+	phone := "13700000002"
+
+	require.NoError(t, SendVerificationCode(ctx, VerifyPurposeRegister, phone))
+	code := sender.code(t, phone)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	assert.Equal(t, ErrVerificationCodeInvalid, VerifyCode(ctx, VerifyPurposeRegister, phone, wrong))
+	assert.Equal(t, ErrVerificationCodeInvalid, VerifyCode(ctx, VerifyPurposeRegister, phone, wrong))
+	err := VerifyCode(ctx, VerifyPurposeRegister, phone, wrong)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "错误次数过多")
+
+	// 达到错误次数后正确的验证码也已作废
+	assert.Equal(t, ErrVerificationCodeInvalid, VerifyCode(ctx, VerifyPurposeRegister, phone, code))
+}
+
+func TestVerificationCode_ConcurrentAttempts(t *testing.T) {
+	sender := useCaptureSender(t)
+	useConfig(t, func(cfg *config.Config) { cfg.Verification.MaxAttempts = 3 })
+	ctx := context.Background()
+	phone := "13700000006"
+	require.NoError(t, SendVerificationCode(ctx, VerifyPurposeResetPassword, phone))
+	code := sender.code(t, phone)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	// 并发的错误猜测都计入次数，超过 max_attempts 后验证码作废
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = VerifyCode(ctx, VerifyPurposeResetPassword, phone, wrong)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, ErrVerificationCodeInvalid, VerifyCode(ctx, VerifyPurposeResetPassword, phone, code))
+}
+
+func TestVerificationCode_SendRateLimit(t *testing.T) {
+	useCaptureSender(t)
+	ctx := context.Background()
+	phone := "13700000003"
+
+	require.NoError(t, SendVerificationCode(ctx, VerifyPurposeRegister, phone))
+	err := SendVerificationCode(ctx, VerifyPurposeResetPassword, phone)
+	require.Error(t, err)
+	appErr, ok := err.(*AppError)
+	require.True(t, ok)
+	assert.Equal(t, CodeTooManyRequests, appErr.Code)
+	assert.Equal(t, "60", appErr.Details)
+}
+
+func TestFileSMSSender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sms", "sms.log")
+	sender := NewFileSMSSender(path)
+	require.NoError(t, sender.Send(context.Background(), "13700000004", "hello"))
+	require.NoError(t, sender.Send(context.Background(), "13700000005", "world"))
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []smsRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record smsRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 2)
+	assert.Equal(t, "13700000005", records[1].Phone)
+	assert.Equal(t, "world", records[1].Message)
+}
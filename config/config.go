@@ -0,0 +1,436 @@
+package config
+
+// 类型化的应用配置：默认值、application.yml、environments.yml 中当前环境的配置、
+// application.<env>.yml 和 TPL_ 前缀的环境变量依次覆盖，加载后统一校验
+
+import (
+	"net"
+	"strconv"
+	"sync/atomic"
+)
+
+// 运行环境
+const (
+	EnvDevelopment = "development"
+	EnvTesting     = "testing"
+	EnvProduction  = "production"
+)
+
+// Config 应用配置，各包通过 Get() 读取，不再直接访问 viper。
+// 标记为 reload:"restart" 的配置项在启动时使用，运行中修改会被拒绝，需要重启服务；
+// 标记为 secret:"true" 的配置项在输出配置时隐藏
+type Config struct {
+	Env string `mapstructure:"-"` // 当前环境，由 APP_ENV 选择
+
+	Server     ServerConfig     `mapstructure:"server" reload:"restart"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	Redis      RedisConfig      `mapstructure:"redis" reload:"restart"`
+	Cache      CacheConfig      `mapstructure:"cache"`
+	Logging    LoggingConfig    `mapstructure:"logging"`
+	Security   SecurityConfig   `mapstructure:"security"`
+	Monitoring MonitoringConfig `mapstructure:"monitoring" reload:"restart"`
+
+	JWT           JWTConfig           `mapstructure:"jwt"`
+	RBAC          RBACConfig          `mapstructure:"rbac"`
+	MFA           MFAConfig           `mapstructure:"mfa"`
+	Password      PasswordConfig      `mapstructure:"password"`
+	Lockout       LockoutConfig       `mapstructure:"lockout"`
+	Verification  VerificationConfig  `mapstructure:"verification"`
+	SMS           SMSConfig           `mapstructure:"sms"`
+	APIKey        APIKeyConfig        `mapstructure:"api_key"`
+	OIDC          OIDCConfig          `mapstructure:"oidc"`
+	OAuth         OAuthConfig         `mapstructure:"oauth"`
+	Privacy       PrivacyConfig       `mapstructure:"privacy"`
+	Impersonation ImpersonationConfig `mapstructure:"impersonation"`
+	UserImport    UserImportConfig    `mapstructure:"user_import"`
+	Policy        PolicyConfig        `mapstructure:"policy"`
+}
+
+// ServerConfig HTTP 服务，超时单位为秒
+type ServerConfig struct {
+	Mode           string `mapstructure:"mode"` // debug、release 或 test
+	Host           string `mapstructure:"host"`
+	Port           int    `mapstructure:"port"`
+	ReadTimeout    int    `mapstructure:"read_timeout"`
+	WriteTimeout   int    `mapstructure:"write_timeout"`
+	MaxHeaderBytes int    `mapstructure:"max_header_bytes"`
+	IdleTimeout    int    `mapstructure:"idle_timeout"`
+	// 收到退出信号后先将就绪检查置为不可用，等待 shutdown_delay 让负载均衡摘除实例，
+	// 再在 shutdown_timeout 内处理完进行中的请求，最后在 component_shutdown_timeout 内关闭各组件
+	ShutdownDelay            int `mapstructure:"shutdown_delay"`
+	ShutdownTimeout          int `mapstructure:"shutdown_timeout"`
+	ComponentShutdownTimeout int `mapstructure:"component_shutdown_timeout"`
+}
+
+// DatabaseConfig 数据库连接，conn_max_lifetime 单位为秒
+type DatabaseConfig struct {
+	Driver          string `mapstructure:"driver" reload:"restart"` // mysql 或 postgres
+	Host            string `mapstructure:"host" reload:"restart"`
+	Port            int    `mapstructure:"port" reload:"restart"`
+	User            string `mapstructure:"user" reload:"restart"`
+	Password        string `mapstructure:"password" reload:"restart" secret:"true"`
+	Name            string `mapstructure:"name" reload:"restart"`
+	SSLMode         string `mapstructure:"ssl_mode" reload:"restart"` // 仅 postgres
+	Charset         string `mapstructure:"charset" reload:"restart"`  // 仅 mysql
+	TimeZone        string `mapstructure:"time_zone" reload:"restart"`
+	MaxIdleConns    int    `mapstructure:"max_idle_conns"`
+	MaxOpenConns    int    `mapstructure:"max_open_conns"`
+	ConnMaxLifetime int    `mapstructure:"conn_max_lifetime"`
+	LogSQL          bool   `mapstructure:"log_sql"`      // 打印执行的 SQL
+	AutoMigrate     bool   `mapstructure:"auto_migrate"` // 启动时执行未完成的数据库迁移
+}
+
+// RedisConfig Redis 连接，超时单位为秒
+type RedisConfig struct {
+	Host         string `mapstructure:"host"`
+	Port         int    `mapstructure:"port"`
+	Password     string `mapstructure:"password" secret:"true"`
+	DB           int    `mapstructure:"db"`
+	PoolSize     int    `mapstructure:"pool_size"`
+	MinIdleConns int    `mapstructure:"min_idle_conns"`
+	DialTimeout  int    `mapstructure:"dial_timeout"`
+	ReadTimeout  int    `mapstructure:"read_timeout"`
+	WriteTimeout int    `mapstructure:"write_timeout"`
+}
+
+// CacheConfig 缓存键前缀和默认过期时间（秒）
+type CacheConfig struct {
+	Prefix            string `mapstructure:"prefix" reload:"restart"`
+	DefaultExpiration int    `mapstructure:"default_expiration"`
+}
+
+// LoggingConfig 日志输出，文件的切割与清理交给 logrotate 等外部工具
+type LoggingConfig struct {
+	Level  string `mapstructure:"level"`  // debug、info、warn、error 或 fatal
+	Format string `mapstructure:"format"` // json 或 text
+	Output string `mapstructure:"output"` // stdout、stderr 或 file
+	File   string `mapstructure:"file"`   // output 为 file 时的文件路径
+}
+
+// SecurityConfig 跨域、限流、安全头部等安全设置
+type SecurityConfig struct {
+	CORS                  CORSConfig      `mapstructure:"cors"`
+	RateLimit             RateLimitConfig `mapstructure:"rate_limit"`
+	ContentSecurityPolicy string          `mapstructure:"content_security_policy"`
+	TrustedProxies        []string        `mapstructure:"trusted_proxies" reload:"restart"`
+}
+
+// CORSConfig 跨域设置，max_age 单位为秒
+type CORSConfig struct {
+	AllowedOrigins   []string `mapstructure:"allowed_origins"`
+	AllowedMethods   []string `mapstructure:"allowed_methods"`
+	AllowedHeaders   []string `mapstructure:"allowed_headers"`
+	ExposeHeaders    []string `mapstructure:"expose_headers"`
+	AllowCredentials bool     `mapstructure:"allow_credentials"`
+	MaxAge           int      `mapstructure:"max_age"`
+}
+
+// RateLimitConfig 按 IP 限流
+type RateLimitConfig struct {
+	Enabled           bool `mapstructure:"enabled"`
+	RequestsPerMinute int  `mapstructure:"requests_per_minute"`
+	Burst             int  `mapstructure:"burst"`
+}
+
+// MonitoringConfig 监控端点
+type MonitoringConfig struct {
+	Enabled    bool           `mapstructure:"enabled"`
+	Prometheus EndpointConfig `mapstructure:"prometheus"`
+	Pprof      EndpointConfig `mapstructure:"pprof"`
+}
+
+// EndpointConfig 可开关的端点
+type EndpointConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Path    string `mapstructure:"path"`
+}
+
+// JWTConfig 访问令牌签名与有效期
+type JWTConfig struct {
+	Secret              string   `mapstructure:"secret" reload:"restart" secret:"true"` // HS256 使用的密钥
+	ExpireHours         int      `mapstructure:"expire_hours"`                          // 未设置 access_expire_minutes 时使用
+	AccessExpireMinutes int      `mapstructure:"access_expire_minutes"`                 // 优先于 expire_hours
+	RefreshExpireHours  int      `mapstructure:"refresh_expire_hours"`
+	Issuer              string   `mapstructure:"issuer"`
+	Algorithm           string   `mapstructure:"algorithm" reload:"restart"` // HS256、RS256、ES256 或 EdDSA
+	ActiveKid           string   `mapstructure:"active_kid" reload:"restart"`
+	Keys                []JWTKey `mapstructure:"keys" reload:"restart"`
+}
+
+// JWTKey 非对称签名密钥
+type JWTKey struct {
+	Kid            string `mapstructure:"kid"`
+	Algorithm      string `mapstructure:"algorithm"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+	RetiredAt      string `mapstructure:"retired_at"` // RFC3339，退役后只用于验证
+}
+
+// RBACConfig 角色权限
+type RBACConfig struct {
+	AdminTelephones []string `mapstructure:"admin_telephones"` // 启动时自动授予管理员角色的手机号
+}
+
+// MFAConfig 两步验证
+type MFAConfig struct {
+	Issuer               string `mapstructure:"issuer"` // 为空时使用 jwt.issuer
+	PendingExpireMinutes int    `mapstructure:"pending_expire_minutes"`
+	Skew                 int    `mapstructure:"skew"`
+	RequireAdmin         bool   `mapstructure:"require_admin"`
+}
+
+// PasswordConfig 密码哈希与密码策略
+type PasswordConfig struct {
+	Algorithm string               `mapstructure:"algorithm"` // argon2id 或 bcrypt
+	Argon2id  Argon2idConfig       `mapstructure:"argon2id"`
+	Bcrypt    BcryptConfig         `mapstructure:"bcrypt"`
+	Policy    PasswordPolicyConfig `mapstructure:"policy"`
+}
+
+// Argon2idConfig argon2id 参数
+type Argon2idConfig struct {
+	MemoryKiB   int `mapstructure:"memory_kib"`
+	Iterations  int `mapstructure:"iterations"`
+	Parallelism int `mapstructure:"parallelism"`
+	SaltLength  int `mapstructure:"salt_length"`
+	KeyLength   int `mapstructure:"key_length"`
+}
+
+// BcryptConfig bcrypt 参数
+type BcryptConfig struct {
+	Cost int `mapstructure:"cost"`
+}
+
+// PasswordPolicyConfig 密码策略
+type PasswordPolicyConfig struct {
+	MinLength           int    `mapstructure:"min_length"`
+	MaxLength           int    `mapstructure:"max_length"`
+	RequireLowercase    bool   `mapstructure:"require_lowercase"`
+	RequireUppercase    bool   `mapstructure:"require_uppercase"`
+	RequireDigit        bool   `mapstructure:"require_digit"`
+	RequireSpecial      bool   `mapstructure:"require_special"`
+	MinCharacterClasses int    `mapstructure:"min_character_classes"`
+	BreachedListFile    string `mapstructure:"breached_list_file"`
+	HistorySize         int    `mapstructure:"history_size"`
+	MaxAgeDays          int    `mapstructure:"max_age_days"`
+	CheckSimilarity     bool   `mapstructure:"check_similarity"`
+}
+
+// LockoutConfig 登录失败保护
+type LockoutConfig struct {
+	MaxAccountFailures int `mapstructure:"max_account_failures"`
+	MaxIPFailures      int `mapstructure:"max_ip_failures"`
+	DelayAfter         int `mapstructure:"delay_after"`
+	MaxDelaySeconds    int `mapstructure:"max_delay_seconds"`
+	WindowMinutes      int `mapstructure:"window_minutes"`
+	LockoutMinutes     int `mapstructure:"lockout_minutes"`
+}
+
+// VerificationConfig 短信验证码
+type VerificationConfig struct {
+	RequireOnRegister bool `mapstructure:"require_on_register"` // 注册时是否要求短信验证码
+	CodeLength        int  `mapstructure:"code_length"`
+	CodeTTLMinutes    int  `mapstructure:"code_ttl_minutes"`
+	MaxAttempts       int  `mapstructure:"max_attempts"`
+	ResendSeconds     int  `mapstructure:"resend_seconds"`
+	MaxSendsPerHour   int  `mapstructure:"max_sends_per_hour"`
+}
+
+// SMSConfig 短信发送
+type SMSConfig struct {
+	Provider string `mapstructure:"provider"` // log 或 file
+	FilePath string `mapstructure:"file_path"`
+}
+
+// APIKeyConfig 个人 API 密钥
+type APIKeyConfig struct {
+	MaxPerUser int `mapstructure:"max_per_user"`
+}
+
+// OIDCConfig 第三方登录
+type OIDCConfig struct {
+	StateTTLMinutes int            `mapstructure:"state_ttl_minutes"`
+	Providers       []OIDCProvider `mapstructure:"providers"`
+}
+
+// OIDCProvider 第三方登录提供方
+type OIDCProvider struct {
+	Name         string   `mapstructure:"name"`                        // 提供方名称，用于路由，如 google
+	DisplayName  string   `mapstructure:"display_name"`                // 展示给用户的名称
+	Issuer       string   `mapstructure:"issuer"`                      // 用于自动发现配置
+	ClientID     string   `mapstructure:"client_id"`                   // 客户端 ID
+	ClientSecret string   `mapstructure:"client_secret" secret:"true"` // 客户端密钥
+	RedirectURL  string   `mapstructure:"redirect_url"`                // 回调地址，需要在提供方登记
+	Scopes       []string `mapstructure:"scopes"`                      // 额外申请的范围，openid 会自动加入
+	AutoCreate   bool     `mapstructure:"auto_create"`                 // 未绑定时是否按已验证的手机号自动创建用户
+}
+
+// OAuthConfig 内置 OAuth2 授权服务
+type OAuthConfig struct {
+	Issuer              string `mapstructure:"issuer"` // 为空时按请求推断
+	CodeTTLSeconds      int    `mapstructure:"code_ttl_seconds"`
+	AccessExpireMinutes int    `mapstructure:"access_expire_minutes"` // 为 0 时与用户访问令牌一致
+	RefreshExpireHours  int    `mapstructure:"refresh_expire_hours"`
+}
+
+// PrivacyConfig 个人数据导出与删除
+type PrivacyConfig struct {
+	ExportDir             string `mapstructure:"export_dir"`
+	ExportTTLHours        int    `mapstructure:"export_ttl_hours"`
+	ErasureGraceDays      int    `mapstructure:"erasure_grace_days"` // 0 表示尽快执行
+	WorkerIntervalSeconds int    `mapstructure:"worker_interval_seconds" reload:"restart"`
+	// 处理中超过该时间的申请视为处理进程已退出，重新放回待处理队列
+	ProcessingTimeoutMinutes int `mapstructure:"processing_timeout_minutes"`
+}
+
+// ImpersonationConfig 管理员代登录
+type ImpersonationConfig struct {
+	DefaultMinutes int `mapstructure:"default_minutes"`
+	MaxMinutes     int `mapstructure:"max_minutes"`
+}
+
+// UserImportConfig 用户批量导入
+type UserImportConfig struct {
+	MaxRows           int `mapstructure:"max_rows"`
+	MaxFileSizeMB     int `mapstructure:"max_file_size_mb"`
+	MaxUnzippedSizeMB int `mapstructure:"max_unzipped_size_mb"` // xlsx 中单个文件解压后的大小上限
+}
+
+// PolicyConfig 授权策略
+type PolicyConfig struct {
+	File string `mapstructure:"file"`
+}
+
+// Default 默认配置，与之前各包中的默认值一致
+func Default() *Config {
+	return &Config{
+		Env: EnvDevelopment,
+		Server: ServerConfig{
+			Mode:                     "debug",
+			Host:                     "0.0.0.0",
+			Port:                     8080,
+			ReadTimeout:              30,
+			WriteTimeout:             30,
+			MaxHeaderBytes:           1 << 20,
+			IdleTimeout:              120,
+			ShutdownTimeout:          30,
+			ComponentShutdownTimeout: 10,
+		},
+		Database: DatabaseConfig{
+			Driver:          "postgres",
+			Host:            "localhost",
+			Port:            5432,
+			SSLMode:         "disable",
+			Charset:         "utf8mb4",
+			TimeZone:        "Asia/Shanghai",
+			MaxIdleConns:    10,
+			MaxOpenConns:    100,
+			ConnMaxLifetime: 3600,
+			AutoMigrate:     true,
+		},
+		Redis: RedisConfig{
+			Host:         "localhost",
+			Port:         6379,
+			PoolSize:     10,
+			MinIdleConns: 5,
+			DialTimeout:  5,
+			ReadTimeout:  3,
+			WriteTimeout: 3,
+		},
+		Cache: CacheConfig{Prefix: "gin_template:", DefaultExpiration: 3600},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
+			Output: "stdout",
+			File:   "logs/app.log",
+		},
+		Security: SecurityConfig{
+			CORS: CORSConfig{
+				AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+				AllowedHeaders: []string{"Content-Type", "Authorization", "X-Requested-With"},
+				MaxAge:         86400,
+			},
+			RateLimit:             RateLimitConfig{RequestsPerMinute: 100, Burst: 200},
+			ContentSecurityPolicy: "default-src 'self'",
+		},
+		Monitoring: MonitoringConfig{
+			Enabled:    true,
+			Prometheus: EndpointConfig{Enabled: true, Path: "/api/metrics"},
+			Pprof:      EndpointConfig{Path: "/debug/pprof"},
+		},
+		JWT: JWTConfig{
+			ExpireHours:        168,
+			RefreshExpireHours: 720,
+			Issuer:             "gin-template",
+			Algorithm:          "HS256",
+		},
+		MFA: MFAConfig{PendingExpireMinutes: 5, Skew: 1},
+		Password: PasswordConfig{
+			Algorithm: "argon2id",
+			Argon2id:  Argon2idConfig{MemoryKiB: 64 * 1024, Iterations: 3, Parallelism: 2, SaltLength: 16, KeyLength: 32},
+			Bcrypt:    BcryptConfig{Cost: 12},
+			Policy: PasswordPolicyConfig{
+				MinLength:           8,
+				MaxLength:           128,
+				MinCharacterClasses: 3,
+				HistorySize:         5,
+				CheckSimilarity:     true,
+			},
+		},
+		Lockout: LockoutConfig{
+			MaxAccountFailures: 5,
+			MaxIPFailures:      20,
+			DelayAfter:         3,
+			MaxDelaySeconds:    30,
+			WindowMinutes:      15,
+			LockoutMinutes:     15,
+		},
+		Verification: VerificationConfig{
+			CodeLength:      6,
+			CodeTTLMinutes:  5,
+			MaxAttempts:     5,
+			ResendSeconds:   60,
+			MaxSendsPerHour: 5,
+		},
+		SMS:    SMSConfig{Provider: "log", FilePath: "logs/sms.log"},
+		APIKey: APIKeyConfig{MaxPerUser: 10},
+		OIDC:   OIDCConfig{StateTTLMinutes: 10},
+		OAuth: OAuthConfig{
+			CodeTTLSeconds:      300,
+			AccessExpireMinutes: 60,
+			RefreshExpireHours:  720,
+		},
+		Privacy: PrivacyConfig{
+			ExportDir:                "storage/exports",
+			ExportTTLHours:           72,
+			ErasureGraceDays:         30,
+			WorkerIntervalSeconds:    60,
+			ProcessingTimeoutMinutes: 30,
+		},
+		Impersonation: ImpersonationConfig{DefaultMinutes: 15, MaxMinutes: 30},
+		UserImport:    UserImportConfig{MaxRows: 5000, MaxFileSizeMB: 10, MaxUnzippedSizeMB: 50},
+	}
+}
+
+var (
+	current  atomic.Pointer[Config]
+	defaults = Default()
+)
+
+// Get 当前配置，尚未加载时返回默认配置。返回值只读，修改需要通过 Set 整体替换
+func Get() *Config {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+	return defaults
+}
+
+// Set 替换当前配置，传入 nil 时恢复为默认配置
+func Set(cfg *Config) {
+	current.Store(cfg)
+}
+
+// Address 服务监听地址
+func (s ServerConfig) Address() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
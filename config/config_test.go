@@ -0,0 +1,183 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_SelectsEnvironment(t *testing.T) {
+	cfg, err := Load(".", EnvTesting)
+	require.NoError(t, err)
+	assert.Equal(t, EnvTesting, cfg.Env)
+	assert.Equal(t, "test", cfg.Server.Mode)
+	assert.Equal(t, 8081, cfg.Server.Port)
+	assert.Equal(t, "gin_template_test", cfg.Database.Name)
+	assert.Equal(t, "gin-template-test", cfg.JWT.Issuer)
+	// application.yml 中的共用配置仍然生效
+	assert.Equal(t, 15, cfg.JWT.AccessExpireMinutes)
+	// 列表整体替换默认值
+	assert.Equal(t, []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}, cfg.Security.CORS.AllowedMethods)
+
+	_, err = Load(".", "staging")
+	assert.Error(t, err)
+}
+
+func TestLoad_EnvironmentVariablesOverrideFiles(t *testing.T) {
+	t.Setenv("TPL_DATABASE_PORT", "6000")
+	t.Setenv("TPL_SECURITY_TRUSTED_PROXIES", "10.0.0.1,10.0.0.2")
+	t.Setenv("TPL_PRIVACY_ERASURE_GRACE_DAYS", "0")
+
+	cfg, err := Load(".", "")
+	require.NoError(t, err)
+	assert.Equal(t, EnvDevelopment, cfg.Env)
+	assert.Equal(t, 6000, cfg.Database.Port)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, cfg.Security.TrustedProxies)
+	assert.Equal(t, 0, cfg.Privacy.ErasureGraceDays)
+}
+
+func TestLoad_ExpandsPlaceholders(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "environments.yml", `
+environments:
+  production:
+    database:
+      host: "${TEST_DB_HOST}"
+      name: app
+      port: ${TEST_DB_PORT}
+    jwt:
+      secret: "${TEST_JWT_SECRET}"
+`)
+	t.Setenv("TEST_DB_HOST", "db.internal")
+	t.Setenv("TEST_DB_PORT", "5433")
+
+	_, err := Load(dir, EnvProduction)
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Contains(t, verr.Problems, "jwt.secret: 引用的环境变量 TEST_JWT_SECRET 未设置")
+
+	// 直接设置配置项对应的环境变量时不要求占位符中的变量
+	t.Setenv("TPL_JWT_SECRET", "a-production-secret-that-is-long-enough")
+	cfg, err := Load(dir, EnvProduction)
+	require.NoError(t, err)
+	assert.Equal(t, "db.internal", cfg.Database.Host)
+	assert.Equal(t, 5433, cfg.Database.Port)
+	assert.Equal(t, "a-production-secret-that-is-long-enough", cfg.JWT.Secret)
+}
+
+func TestLoad_ReportsEveryProblem(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "application.yml", `
+db_name: mysql
+password:
+  algorithm: md5
+`)
+	writeFile(t, dir, "environments.yml", `
+environments:
+  production:
+    server:
+      mode: fast
+      port: 70000
+    database:
+      driver: postgres
+      host: localhost
+    jwt:
+      secret: short
+validation:
+  server:
+    port:
+      type: int
+      min: 1
+      max: 65535
+    mode:
+      type: string
+      enum: [debug, release, test]
+`)
+
+	_, err := Load(dir, EnvProduction)
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.ElementsMatch(t, []string{
+		"db_name: 已废弃，请改用 database.driver（环境变量 TPL_DATABASE_DRIVER）",
+		`server.mode: 取值 "fast" 无效，可选: debug, release, test`,
+		"server.port: 不能大于 65535，当前为 70000",
+		"database.name: 不能为空",
+		"jwt.secret: 生产环境的密钥至少需要 32 个字符",
+		`password.algorithm: 取值 "md5" 无效，可选: argon2id, bcrypt`,
+	}, verr.Problems)
+}
+
+// validationProblems 返回配置校验发现的全部问题
+func validationProblems(cfg *Config) []string {
+	var verr *ValidationError
+	if errors.As(cfg.Validate(), &verr) {
+		return verr.Problems
+	}
+	return nil
+}
+
+func TestValidate_ImpersonationLimit(t *testing.T) {
+	cfg := Default()
+	cfg.Impersonation.MaxMinutes = 600
+	assert.Contains(t, validationProblems(cfg), "impersonation.max_minutes: 不能大于 60，当前为 600")
+
+	cfg.Impersonation.MaxMinutes = 60
+	assert.NotContains(t, strings.Join(validationProblems(cfg), "\n"), "impersonation.max_minutes")
+}
+
+func TestValidate_CORSWildcardWithCredentials(t *testing.T) {
+	problem := "security.cors.allowed_origins: allow_credentials 为 true 时不能使用通配符 *，请列出允许的来源"
+
+	cfg := Default()
+	cfg.Security.CORS.AllowedOrigins = []string{"https://app.example.com", "*"}
+	cfg.Security.CORS.AllowCredentials = true
+	assert.Contains(t, validationProblems(cfg), problem)
+
+	cfg.Security.CORS.AllowCredentials = false
+	assert.NotContains(t, validationProblems(cfg), problem)
+	cfg.Security.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	cfg.Security.CORS.AllowCredentials = true
+	assert.NotContains(t, validationProblems(cfg), problem)
+}
+
+func TestGetAndSet(t *testing.T) {
+	t.Cleanup(func() { Set(nil) })
+	assert.Equal(t, Default(), Get())
+
+	cfg := Default()
+	cfg.Server.Port = 9000
+	Set(cfg)
+	assert.Equal(t, 9000, Get().Server.Port)
+	assert.Equal(t, "0.0.0.0:9000", Get().Server.Address())
+
+	Set(nil)
+	assert.Equal(t, 8080, Get().Server.Port)
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "db-password"
+	cfg.JWT.Secret = "jwt-secret"
+	cfg.OIDC.Providers = []OIDCProvider{{Name: "google", ClientID: "id", ClientSecret: "oidc-secret"}}
+
+	redacted := Redacted(cfg)
+	assert.Equal(t, EnvDevelopment, redacted["env"])
+	database := redacted["database"].(map[string]interface{})
+	assert.Equal(t, RedactedValue, database["password"])
+	assert.Equal(t, "localhost", database["host"])
+	// 未设置的密钥保持为空，便于发现遗漏
+	assert.Equal(t, "", redacted["redis"].(map[string]interface{})["password"])
+	assert.Equal(t, RedactedValue, redacted["jwt"].(map[string]interface{})["secret"])
+	provider := redacted["oidc"].(map[string]interface{})["providers"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, RedactedValue, provider["client_secret"])
+	assert.Equal(t, "id", provider["client_id"])
+}
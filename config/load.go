@@ -0,0 +1,280 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix 环境变量前缀，键中的 . 替换为 _，如 TPL_DATABASE_PASSWORD 覆盖 database.password
+const EnvPrefix = "TPL"
+
+// 已废弃的扁平配置项及替代项，仍然出现时启动失败，避免配置被悄悄忽略
+var deprecatedKeys = []struct{ old, replacement string }{
+	{"is_debug", "server.mode"},
+	{"server_port", "server.port"},
+	{"is_print_sql", "database.log_sql"},
+	{"db_name", "database.driver"},
+	{"mysql_host", "database.host"},
+	{"mysql_port", "database.port"},
+	{"mysql_database", "database.name"},
+	{"mysql_username", "database.user"},
+	{"mysql_password", "database.password"},
+	{"mysql_charset", "database.charset"},
+	{"postgres_host", "database.host"},
+	{"postgres_port", "database.port"},
+	{"postgres_database", "database.name"},
+	{"postgres_username", "database.user"},
+	{"postgres_password", "database.password"},
+	{"cache_host", "redis.host"},
+	{"cache_port", "redis.port"},
+	{"cache_password", "redis.password"},
+	{"cache_db", "redis.db"},
+	{"cache_prefix", "cache.prefix"},
+}
+
+// ${VAR} 或 ${provider:ref} 占位符
+var placeholderPattern = regexp.MustCompile(`\$\{(?:([a-z]+):([^}]*)|([A-Za-z_][A-Za-z0-9_]*))\}`)
+
+// Load 从 dir 目录加载 env 环境的配置，env 为空时使用 environments.yml 中的 default_environment。
+// 优先级从低到高：默认值、application.yml、environments.yml 中该环境的配置、application.<env>.yml、环境变量。
+// 配置文件中的字符串可以用 ${VAR} 引用环境变量，用 ${file:...}、${env:...}、${enc:...} 引用密钥。
+// 所有问题汇总在 *ValidationError 中一次返回。
+func Load(dir, env string) (*Config, error) {
+	v := viper.New()
+	registerDefaults(v, "", reflect.ValueOf(*Default()))
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	verr := &ValidationError{}
+	secrets := &secretResolver{dir: dir}
+
+	base, err := readYAML(filepath.Join(dir, "application.yml"))
+	if err != nil {
+		return nil, err
+	}
+	environments, err := readYAML(filepath.Join(dir, "environments.yml"))
+	if err != nil {
+		return nil, err
+	}
+
+	if env == "" {
+		env, _ = environments["default_environment"].(string)
+	}
+	if env == "" {
+		env = EnvDevelopment
+	}
+
+	layers := []map[string]interface{}{base}
+	if envs, ok := environments["environments"].(map[string]interface{}); ok {
+		section, ok := envs[env].(map[string]interface{})
+		if !ok {
+			names := make([]string, 0, len(envs))
+			for name := range envs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("未知的运行环境 %q，可选: %s", env, strings.Join(names, ", "))
+		}
+		layers = append(layers, section)
+	}
+	override, err := readYAML(filepath.Join(dir, "application."+env+".yml"))
+	if err != nil {
+		return nil, err
+	}
+	layers = append(layers, override)
+
+	for _, layer := range layers {
+		expandPlaceholders(layer, "", secrets, verr)
+		if err := v.MergeConfigMap(layer); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range deprecatedKeys {
+		if v.IsSet(key.old) {
+			verr.add(key.old, "已废弃，请改用 %s（环境变量 %s）", key.replacement, EnvKey(key.replacement))
+		}
+	}
+
+	// 列表整体替换而不是与默认值逐项合并
+	cfg := Default()
+	if err := v.Unmarshal(cfg, func(dc *mapstructure.DecoderConfig) { dc.ZeroFields = true }); err != nil {
+		var decodeErr *mapstructure.Error
+		if errors.As(err, &decodeErr) {
+			for _, msg := range decodeErr.Errors {
+				verr.Problems = append(verr.Problems, msg)
+			}
+		} else {
+			verr.Problems = append(verr.Problems, err.Error())
+		}
+	}
+	cfg.Env = env
+
+	if rules, ok := environments["validation"].(map[string]interface{}); ok {
+		checkRules(v, rules, "", verr)
+	}
+	cfg.validate(verr)
+
+	if len(verr.Problems) > 0 {
+		return nil, verr
+	}
+	return cfg, nil
+}
+
+// EnvKey 配置项对应的环境变量名
+func EnvKey(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// registerDefaults 按 mapstructure 标签把默认值逐项注册到 viper，
+// 使每个配置项都能被环境变量覆盖
+func registerDefaults(v *viper.Viper, prefix string, value reflect.Value) {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := tag
+		if prefix != "" {
+			key = prefix + "." + tag
+		}
+		field := value.Field(i)
+		if field.Kind() == reflect.Struct {
+			registerDefaults(v, key, field)
+			continue
+		}
+		v.SetDefault(key, field.Interface())
+	}
+}
+
+// readYAML 读取 YAML 文件，文件不存在时返回空配置
+func readYAML(path string) (map[string]interface{}, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return map[string]interface{}{}, nil
+	}
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("读取配置文件 %s 失败: %v", path, err)
+	}
+	return v.AllSettings(), nil
+}
+
+// expandPlaceholders 替换字符串中的占位符。引用的值无法取得、且没有通过环境变量直接覆盖该配置项时记为问题
+func expandPlaceholders(node map[string]interface{}, prefix string, secrets *secretResolver, verr *ValidationError) {
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := node[key]
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		node[key] = expandValue(value, path, secrets, verr)
+	}
+}
+
+func expandValue(value interface{}, path string, secrets *secretResolver, verr *ValidationError) interface{} {
+	switch val := value.(type) {
+	case map[string]interface{}:
+		expandPlaceholders(val, path, secrets, verr)
+		return val
+	case []interface{}:
+		for i := range val {
+			val[i] = expandValue(val[i], path, secrets, verr)
+		}
+		return val
+	case string:
+		return placeholderPattern.ReplaceAllStringFunc(val, func(match string) string {
+			m := placeholderPattern.FindStringSubmatch(match)
+			provider, ref := m[1], m[2]
+			if provider == "" {
+				provider, ref = "env", m[3]
+			}
+			resolved, err := secrets.resolve(provider, ref)
+			if err == nil {
+				return resolved
+			}
+			if _, ok := os.LookupEnv(EnvKey(path)); !ok {
+				verr.add(path, "%v", err)
+			}
+			return ""
+		})
+	}
+	return value
+}
+
+// checkRules 按 environments.yml 中 validation 部分的规则检查配置值，规则支持 int 的 min/max 和 string 的 enum
+func checkRules(v *viper.Viper, rules map[string]interface{}, prefix string, verr *ValidationError) {
+	keys := make([]string, 0, len(rules))
+	for key := range rules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		rule, ok := rules[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if _, isRule := rule["type"]; !isRule {
+			checkRules(v, rule, path, verr)
+			continue
+		}
+		checkRule(v, rule, path, verr)
+	}
+}
+
+func checkRule(v *viper.Viper, rule map[string]interface{}, path string, verr *ValidationError) {
+	switch rule["type"] {
+	case "int":
+		n, err := toInt(v.Get(path))
+		if err != nil {
+			return // 类型错误在解析配置时已经报告
+		}
+		if min, err := toInt(rule["min"]); err == nil && rule["min"] != nil && n < min {
+			verr.add(path, "不能小于 %d，当前为 %d", min, n)
+		}
+		if max, err := toInt(rule["max"]); err == nil && rule["max"] != nil && n > max {
+			verr.add(path, "不能大于 %d，当前为 %d", max, n)
+		}
+	case "string":
+		values, ok := rule["enum"].([]interface{})
+		if !ok {
+			return
+		}
+		value := fmt.Sprint(v.Get(path))
+		allowed := make([]string, 0, len(values))
+		for _, item := range values {
+			if fmt.Sprint(item) == value {
+				return
+			}
+			allowed = append(allowed, fmt.Sprint(item))
+		}
+		verr.add(path, "取值 %q 无效，可选: %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+// toInt 转换配置中的整数，环境变量和占位符替换后的值是字符串
+func toInt(value interface{}) (int, error) {
+	var n int
+	err := mapstructure.WeakDecode(value, &n)
+	return n, err
+}
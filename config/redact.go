@@ -0,0 +1,53 @@
+package config
+
+import "reflect"
+
+// RedactedValue 输出配置时替代密钥的值
+const RedactedValue = "******"
+
+// Redacted 把配置转换为以 mapstructure 键组织的 map，标记为 secret:"true" 的非空值替换为 ******，
+// 便于打印或输出为 YAML 检查实际生效的配置
+func Redacted(cfg *Config) map[string]interface{} {
+	result := redactStruct(reflect.ValueOf(*cfg))
+	result["env"] = cfg.Env
+	return result
+}
+
+func redactStruct(value reflect.Value) map[string]interface{} {
+	result := make(map[string]interface{})
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+		if field.Tag.Get("secret") == "true" {
+			if value.Field(i).IsZero() {
+				result[key] = ""
+			} else {
+				result[key] = RedactedValue
+			}
+			continue
+		}
+		result[key] = redactValue(value.Field(i))
+	}
+	return result
+}
+
+func redactValue(value reflect.Value) interface{} {
+	switch value.Kind() {
+	case reflect.Struct:
+		return redactStruct(value)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.Struct {
+			return value.Interface()
+		}
+		items := make([]interface{}, value.Len())
+		for i := range items {
+			items[i] = redactStruct(value.Index(i))
+		}
+		return items
+	}
+	return value.Interface()
+}
@@ -0,0 +1,186 @@
+package config
+
+// 配置热更新：监听配置文件和 SIGHUP，重新加载并校验后整体替换当前配置，再通知订阅者
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// 文件变化后等待一段时间再加载，编辑器保存时通常会连续产生多个事件
+const reloadDebounce = 300 * time.Millisecond
+
+// Subscriber 配置变更回调，old 和 new 都是只读的。每次重新加载成功后都会调用，需要自行比较关心的配置项
+type Subscriber func(old, new *Config)
+
+type subscription struct {
+	id   int
+	name string
+	fn   Subscriber
+}
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   []subscription
+	nextSubID     int
+	reloadMu      sync.Mutex
+)
+
+// Subscribe 注册配置变更回调，按注册顺序调用，返回取消订阅的函数
+func Subscribe(name string, fn Subscriber) func() {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	nextSubID++
+	id := nextSubID
+	subscribers = append(subscribers, subscription{id: id, name: name, fn: fn})
+	return func() {
+		subscribersMu.Lock()
+		defer subscribersMu.Unlock()
+		for i, sub := range subscribers {
+			if sub.id == id {
+				subscribers = append(subscribers[:i:i], subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Reload 重新加载 dir 目录下当前环境的配置。新配置校验失败，或修改了需要重启才能生效的配置项时，
+// 保留当前配置并返回错误；否则整体替换当前配置并通知订阅者
+func Reload(dir string) error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	old := Get()
+	cfg, err := Load(dir, old.Env)
+	if err != nil {
+		return err
+	}
+	if changed := RestartRequiredChanges(old, cfg); len(changed) > 0 {
+		return fmt.Errorf("以下配置项修改后需要重启服务才能生效，本次重新加载已拒绝: %s", strings.Join(changed, ", "))
+	}
+	Set(cfg)
+
+	subscribersMu.Lock()
+	subs := append([]subscription(nil), subscribers...)
+	subscribersMu.Unlock()
+	for _, sub := range subs {
+		notify(sub, old, cfg)
+	}
+	return nil
+}
+
+// notify 调用订阅者，单个订阅者出错不影响其他订阅者
+func notify(sub subscription, old, cfg *Config) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("配置订阅者 %s 处理变更失败: %v", sub.name, r)
+		}
+	}()
+	sub.fn(old, cfg)
+}
+
+// RestartRequiredChanges 列出两份配置中不同的、标记为 reload:"restart" 的配置项
+func RestartRequiredChanges(old, new *Config) []string {
+	var changed []string
+	if old.Env != new.Env {
+		changed = append(changed, "APP_ENV")
+	}
+	return append(changed, restartRequiredChanges("", reflect.ValueOf(*old), reflect.ValueOf(*new))...)
+}
+
+func restartRequiredChanges(prefix string, old, new reflect.Value) []string {
+	var changed []string
+	t := old.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := tag
+		if prefix != "" {
+			key = prefix + "." + tag
+		}
+		oldField, newField := old.Field(i), new.Field(i)
+		if field.Tag.Get("reload") == "restart" {
+			if !reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+				changed = append(changed, key)
+			}
+			continue
+		}
+		if oldField.Kind() == reflect.Struct {
+			changed = append(changed, restartRequiredChanges(key, oldField, newField)...)
+		}
+	}
+	return changed
+}
+
+// Watch 监听 dir 目录下的配置文件和 SIGHUP 信号，变化时重新加载配置，ctx 取消后退出。
+// 文件监听不可用时只响应 SIGHUP
+func Watch(ctx context.Context, dir string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var events chan fsnotify.Event
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = watcher.Add(dir)
+	}
+	if err != nil {
+		log.Printf("监听配置目录 %s 失败，仅支持通过 SIGHUP 重新加载: %v", dir, err)
+	} else {
+		events = watcher.Events
+	}
+
+	go func() {
+		defer signal.Stop(hup)
+		if watcher != nil {
+			defer watcher.Close()
+		}
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				reloadAndLog(dir, "收到 SIGHUP")
+			case event := <-events:
+				if isConfigFile(event.Name, Get().Env) {
+					debounce = time.After(reloadDebounce)
+				}
+			case <-debounce:
+				debounce = nil
+				reloadAndLog(dir, "配置文件变化")
+			}
+		}
+	}()
+}
+
+// isConfigFile 是否为参与加载的配置文件或主密钥文件。Kubernetes 挂载的 ConfigMap 通过替换 ..data 链接更新
+func isConfigFile(path, env string) bool {
+	switch filepath.Base(path) {
+	case "application.yml", "environments.yml", "application." + env + ".yml", "master.key", "..data":
+		return true
+	}
+	return false
+}
+
+func reloadAndLog(dir, reason string) {
+	if err := Reload(dir); err != nil {
+		log.Printf("%s，重新加载配置失败，继续使用当前配置: %v", reason, err)
+		return
+	}
+	log.Printf("%s，配置已重新加载", reason)
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reloadDir 复制仓库中的配置到临时目录并设为当前配置
+func reloadDir(t *testing.T) string {
+	dir := t.TempDir()
+	for _, name := range []string{"application.yml", "environments.yml"} {
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o644))
+	}
+	cfg, err := Load(dir, EnvTesting)
+	require.NoError(t, err)
+	Set(cfg)
+	t.Cleanup(func() { Set(nil) })
+	return dir
+}
+
+func TestReload_AppliesChangesAndNotifiesSubscribers(t *testing.T) {
+	dir := reloadDir(t)
+	var oldLimit, newLimit int
+	unsubscribe := Subscribe("test", func(old, cfg *Config) {
+		oldLimit, newLimit = old.Security.RateLimit.RequestsPerMinute, cfg.Security.RateLimit.RequestsPerMinute
+	})
+	defer unsubscribe()
+
+	writeFile(t, dir, "application.testing.yml", `
+security:
+  rate_limit:
+    requests_per_minute: 30
+  cors:
+    allowed_origins: ["https://app.example.com"]
+`)
+	require.NoError(t, Reload(dir))
+	assert.Equal(t, 200, oldLimit)
+	assert.Equal(t, 30, newLimit)
+	assert.Equal(t, 30, Get().Security.RateLimit.RequestsPerMinute)
+	assert.Equal(t, []string{"https://app.example.com"}, Get().Security.CORS.AllowedOrigins)
+}
+
+func TestReload_RejectsRestartRequiredAndInvalidChanges(t *testing.T) {
+	dir := reloadDir(t)
+	before := Get()
+	notified := false
+	unsubscribe := Subscribe("test", func(_, _ *Config) { notified = true })
+	defer unsubscribe()
+
+	writeFile(t, dir, "application.testing.yml", `
+server:
+  port: 9090
+security:
+  rate_limit:
+    requests_per_minute: 30
+`)
+	err := Reload(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server")
+
+	writeFile(t, dir, "application.testing.yml", `
+password:
+  algorithm: md5
+`)
+	var verr *ValidationError
+	require.ErrorAs(t, Reload(dir), &verr)
+
+	assert.Same(t, before, Get())
+	assert.False(t, notified)
+}
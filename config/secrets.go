@@ -0,0 +1,155 @@
+package config
+
+// 配置中的密钥引用：${file:/path} 读取文件内容，${env:NAME} 读取环境变量（与 ${NAME} 相同），
+// ${enc:...} 使用本地主密钥以 AES-GCM 解密。密钥明文因此不需要写入配置文件
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"theing/gin-template/utils"
+)
+
+const (
+	// MasterKeyEnv 主密钥（base64 编码的 32 字节）的环境变量，优先于主密钥文件
+	MasterKeyEnv = "TPL_MASTER_KEY"
+	// MasterKeyFileEnv 主密钥文件路径的环境变量，未设置时使用配置目录下的 master.key
+	MasterKeyFileEnv = "TPL_MASTER_KEY_FILE"
+
+	masterKeySize = 32
+)
+
+// ${enc:...} 加密值
+var encryptedPattern = regexp.MustCompile(`\$\{enc:([^}]*)\}`)
+
+// MasterKeyFile 配置目录 dir 对应的主密钥文件路径
+func MasterKeyFile(dir string) string {
+	if file := os.Getenv(MasterKeyFileEnv); file != "" {
+		return file
+	}
+	return filepath.Join(dir, "master.key")
+}
+
+// LoadMasterKey 读取主密钥，依次查找 TPL_MASTER_KEY 和主密钥文件
+func LoadMasterKey(dir string) ([]byte, error) {
+	if encoded := os.Getenv(MasterKeyEnv); encoded != "" {
+		return ParseMasterKey(encoded)
+	}
+	file := MasterKeyFile(dir)
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("未找到主密钥，请设置 %s 或创建 %s", MasterKeyEnv, file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取主密钥文件 %s 失败: %v", file, err)
+	}
+	return ParseMasterKey(string(data))
+}
+
+// ParseMasterKey 解析 base64 编码的主密钥
+func ParseMasterKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != masterKeySize {
+		return nil, fmt.Errorf("主密钥必须是 base64 编码的 %d 字节", masterKeySize)
+	}
+	return key, nil
+}
+
+// GenerateMasterKey 生成新的主密钥，返回 base64 编码
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, masterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("生成主密钥失败: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// EncryptSecret 用主密钥加密明文，返回可以直接写入配置文件的 ${enc:...}
+func EncryptSecret(key []byte, plaintext string) (string, error) {
+	ciphertext, err := utils.EncryptAESGCM(key, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return "${enc:" + ciphertext + "}", nil
+}
+
+// DecryptSecret 解密 ${enc:...}，也接受不带包装的密文
+func DecryptSecret(key []byte, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if m := encryptedPattern.FindStringSubmatch(value); m != nil && m[0] == value {
+		value = m[1]
+	}
+	plaintext, err := utils.DecryptAESGCM(key, value)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// ReencryptSecrets 把内容中所有 ${enc:...} 从旧主密钥重新加密为新主密钥，返回新内容和加密值的数量。
+// 任一值无法用旧主密钥解密时返回错误，不做部分替换
+func ReencryptSecrets(content, oldKey, newKey []byte) ([]byte, int, error) {
+	var firstErr error
+	count := 0
+	result := encryptedPattern.ReplaceAllFunc(content, func(match []byte) []byte {
+		if firstErr != nil {
+			return match
+		}
+		plaintext, err := DecryptSecret(oldKey, string(match))
+		if err == nil {
+			var encrypted string
+			encrypted, err = EncryptSecret(newKey, plaintext)
+			if err == nil {
+				count++
+				return []byte(encrypted)
+			}
+		}
+		firstErr = err
+		return match
+	})
+	if firstErr != nil {
+		return nil, 0, firstErr
+	}
+	return result, count, nil
+}
+
+// secretResolver 解析配置中的占位符，主密钥只在遇到 ${enc:...} 时读取一次
+type secretResolver struct {
+	dir       string
+	key       []byte
+	keyErr    error
+	keyLoaded bool
+}
+
+// resolve 按 provider 取得引用的值，provider 为 file、env 或 enc
+func (r *secretResolver) resolve(provider, ref string) (string, error) {
+	switch provider {
+	case "env":
+		if value, ok := os.LookupEnv(ref); ok {
+			return value, nil
+		}
+		return "", fmt.Errorf("引用的环境变量 %s 未设置", ref)
+	case "file":
+		data, err := os.ReadFile(ref)
+		if err != nil {
+			return "", fmt.Errorf("读取密钥文件 %s 失败: %v", ref, err)
+		}
+		// 密钥文件通常以换行结尾
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case "enc":
+		if !r.keyLoaded {
+			r.key, r.keyErr = LoadMasterKey(r.dir)
+			r.keyLoaded = true
+		}
+		if r.keyErr != nil {
+			return "", r.keyErr
+		}
+		return DecryptSecret(r.key, ref)
+	}
+	return "", fmt.Errorf("不支持的密钥引用 ${%s:...}，可选: env、file、enc", provider)
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_ResolvesSecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	masterKey, err := GenerateMasterKey()
+	require.NoError(t, err)
+	key, err := ParseMasterKey(masterKey)
+	require.NoError(t, err)
+	encrypted, err := EncryptSecret(key, "a-production-secret-that-is-long-enough")
+	require.NoError(t, err)
+
+	writeFile(t, dir, "db_password", "s3cret\n")
+	writeFile(t, dir, "environments.yml", `
+environments:
+  production:
+    database:
+      host: "${env:TEST_DB_HOST}"
+      name: app
+      password: "${file:`+filepath.Join(dir, "db_password")+`}"
+    jwt:
+      secret: "`+encrypted+`"
+`)
+	t.Setenv("TEST_DB_HOST", "db.internal")
+
+	// 没有主密钥时报告问题
+	_, err = Load(dir, EnvProduction)
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Contains(t, verr.Problems[0], "jwt.secret: 未找到主密钥")
+
+	t.Setenv(MasterKeyEnv, masterKey)
+	cfg, err := Load(dir, EnvProduction)
+	require.NoError(t, err)
+	assert.Equal(t, "db.internal", cfg.Database.Host)
+	assert.Equal(t, "s3cret", cfg.Database.Password)
+	assert.Equal(t, "a-production-secret-that-is-long-enough", cfg.JWT.Secret)
+}
+
+func TestReencryptSecrets(t *testing.T) {
+	oldKey, newKey := make([]byte, masterKeySize), make([]byte, masterKeySize)
+	newKey[0] = 1
+	encrypted, err := EncryptSecret(oldKey, "value")
+	require.NoError(t, err)
+
+	content, count, err := ReencryptSecrets([]byte("password: \""+encrypted+"\"\nhost: ${DB_HOST}\n"), oldKey, newKey)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Contains(t, string(content), "host: ${DB_HOST}")
+
+	_, _, err = ReencryptSecrets(content, oldKey, newKey)
+	assert.Error(t, err, "旧主密钥无法解密已轮换的值")
+
+	rotated := encryptedPattern.FindString(string(content))
+	plaintext, err := DecryptSecret(newKey, rotated)
+	require.NoError(t, err)
+	assert.Equal(t, "value", plaintext)
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// 生产环境 HS256 密钥的最小长度
+const minProductionSecretLength = 32
+
+// 代登录令牌携带签发时的角色，有效期不宜过长
+const maxImpersonationMinutes = 60
+
+// ValidationError 配置校验失败，Problems 列出所有无效或缺失的配置项
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "配置无效:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+func (e *ValidationError) add(key, format string, args ...interface{}) {
+	e.Problems = append(e.Problems, key+": "+fmt.Sprintf(format, args...))
+}
+
+// Validate 检查配置之间的约束，返回 *ValidationError
+func (c *Config) Validate() error {
+	verr := &ValidationError{}
+	c.validate(verr)
+	if len(verr.Problems) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func (c *Config) validate(verr *ValidationError) {
+	positive(verr, "server.shutdown_timeout", c.Server.ShutdownTimeout)
+	positive(verr, "server.component_shutdown_timeout", c.Server.ComponentShutdownTimeout)
+	if c.Server.ShutdownDelay < 0 {
+		verr.add("server.shutdown_delay", "不能小于 0")
+	}
+
+	cors := c.Security.CORS
+	if cors.AllowCredentials && slices.Contains(cors.AllowedOrigins, "*") {
+		verr.add("security.cors.allowed_origins", "allow_credentials 为 true 时不能使用通配符 *，请列出允许的来源")
+	}
+
+	oneOf(verr, "database.driver", c.Database.Driver, "mysql", "postgres")
+	required(verr, "database.host", c.Database.Host)
+	required(verr, "database.name", c.Database.Name)
+	required(verr, "redis.host", c.Redis.Host)
+	positive(verr, "database.conn_max_lifetime", c.Database.ConnMaxLifetime)
+	if c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		verr.add("database.max_idle_conns", "不能大于 database.max_open_conns")
+	}
+
+	oneOf(verr, "jwt.algorithm", c.JWT.Algorithm, "HS256", "RS256", "ES256", "EdDSA")
+	if c.JWT.Algorithm == "HS256" {
+		required(verr, "jwt.secret", c.JWT.Secret)
+		if c.Env == EnvProduction && c.JWT.Secret != "" && len(c.JWT.Secret) < minProductionSecretLength {
+			verr.add("jwt.secret", "生产环境的密钥至少需要 %d 个字符", minProductionSecretLength)
+		}
+	} else {
+		required(verr, "jwt.active_kid", c.JWT.ActiveKid)
+		if len(c.JWT.Keys) == 0 {
+			verr.add("jwt.keys", "非对称算法需要配置签名密钥")
+		}
+	}
+	for i, key := range c.JWT.Keys {
+		required(verr, fmt.Sprintf("jwt.keys[%d].kid", i), key.Kid)
+		if key.PrivateKeyFile == "" && key.PublicKeyFile == "" {
+			verr.add(fmt.Sprintf("jwt.keys[%d]", i), "需要配置 private_key_file 或 public_key_file")
+		}
+	}
+	positive(verr, "jwt.refresh_expire_hours", c.JWT.RefreshExpireHours)
+
+	oneOf(verr, "password.algorithm", c.Password.Algorithm, "argon2id", "bcrypt")
+	if c.Password.Bcrypt.Cost < 4 || c.Password.Bcrypt.Cost > 31 {
+		verr.add("password.bcrypt.cost", "必须在 4 到 31 之间，当前为 %d", c.Password.Bcrypt.Cost)
+	}
+	policy := c.Password.Policy
+	if policy.MinLength > policy.MaxLength {
+		verr.add("password.policy.min_length", "不能大于 password.policy.max_length")
+	}
+	if policy.MinCharacterClasses < 0 || policy.MinCharacterClasses > 4 {
+		verr.add("password.policy.min_character_classes", "必须在 0 到 4 之间，当前为 %d", policy.MinCharacterClasses)
+	}
+
+	oneOf(verr, "sms.provider", c.SMS.Provider, "", "log", "file")
+	if c.SMS.Provider == "file" {
+		required(verr, "sms.file_path", c.SMS.FilePath)
+	}
+
+	names := make(map[string]bool, len(c.OIDC.Providers))
+	for i, provider := range c.OIDC.Providers {
+		prefix := fmt.Sprintf("oidc.providers[%d]", i)
+		required(verr, prefix+".name", provider.Name)
+		required(verr, prefix+".issuer", provider.Issuer)
+		required(verr, prefix+".client_id", provider.ClientID)
+		required(verr, prefix+".redirect_url", provider.RedirectURL)
+		if provider.Name != "" && names[provider.Name] {
+			verr.add(prefix+".name", "提供方名称 %s 重复", provider.Name)
+		}
+		names[provider.Name] = true
+	}
+
+	if c.Impersonation.DefaultMinutes > c.Impersonation.MaxMinutes {
+		verr.add("impersonation.default_minutes", "不能大于 impersonation.max_minutes")
+	}
+	positive(verr, "impersonation.max_minutes", c.Impersonation.MaxMinutes)
+	if c.Impersonation.MaxMinutes > maxImpersonationMinutes {
+		verr.add("impersonation.max_minutes", "不能大于 %d，当前为 %d", maxImpersonationMinutes, c.Impersonation.MaxMinutes)
+	}
+	positive(verr, "user_import.max_rows", c.UserImport.MaxRows)
+	positive(verr, "user_import.max_file_size_mb", c.UserImport.MaxFileSizeMB)
+	positive(verr, "user_import.max_unzipped_size_mb", c.UserImport.MaxUnzippedSizeMB)
+	if c.Privacy.ErasureGraceDays < 0 {
+		verr.add("privacy.erasure_grace_days", "不能为负数")
+	}
+	positive(verr, "privacy.worker_interval_seconds", c.Privacy.WorkerIntervalSeconds)
+	positive(verr, "privacy.processing_timeout_minutes", c.Privacy.ProcessingTimeoutMinutes)
+}
+
+func required(verr *ValidationError, key, value string) {
+	if strings.TrimSpace(value) == "" {
+		verr.add(key, "不能为空")
+	}
+}
+
+func positive(verr *ValidationError, key string, value int) {
+	if value <= 0 {
+		verr.add(key, "必须大于 0，当前为 %d", value)
+	}
+}
+
+func oneOf(verr *ValidationError, key, value string, allowed ...string) {
+	for _, item := range allowed {
+		if value == item {
+			return
+		}
+	}
+	verr.add(key, "取值 %q 无效，可选: %s", value, strings.Join(allowed, ", "))
+}
@@ -0,0 +1,145 @@
+package controller
+
+// 个人 API 密钥管理
+
+import (
+	"strconv"
+	"theing/gin-template/common"
+	"theing/gin-template/model"
+	"theing/gin-template/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyDto 返回给客户端的密钥信息，不包含密钥本身
+type APIKeyDto struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ToAPIKeyDto 将 model.APIKey 转换为 APIKeyDto
+func ToAPIKeyDto(key model.APIKey) APIKeyDto {
+	return APIKeyDto{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     common.SplitScopes(key.Scopes),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		LastUsedIP: key.LastUsedIP,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+// 当前用户的密钥列表
+func ListAPIKeys(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+
+	keys, err := common.ListAPIKeys(user.ID)
+	if err != nil {
+		failWithErr(c, err, "查询API密钥失败")
+		return
+	}
+	list := make([]APIKeyDto, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, ToAPIKeyDto(key))
+	}
+	response.Success(c, list, "")
+}
+
+// 创建密钥，明文密钥只在本次响应中返回
+func CreateAPIKey(c *gin.Context) {
+	type PostAPIKey struct {
+		Name          string   `json:"name" binding:"required,max=64"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days" binding:"min=0,max=3650"` // 0 表示长期有效，最长 10 年
+	}
+	var req PostAPIKey
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	user, err := userWithRoles(c)
+	if err != nil {
+		failWithErr(c, err, "创建API密钥失败")
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	key, rawKey, err := common.CreateAPIKey(user, req.Name, req.Scopes, ttl)
+	if err != nil {
+		failWithErr(c, err, "创建API密钥失败")
+		return
+	}
+	response.Success(c, gin.H{"key": rawKey, "api_key": ToAPIKeyDto(*key)}, "API密钥已创建，请立即保存，之后将无法再次查看")
+}
+
+// 修改密钥名称或授权范围
+func UpdateAPIKey(c *gin.Context) {
+	type PatchAPIKey struct {
+		Name   *string  `json:"name" binding:"omitempty,min=1,max=64"`
+		Scopes []string `json:"scopes"`
+	}
+	var req PatchAPIKey
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	keyID, ok := apiKeyID(c)
+	if !ok {
+		return
+	}
+	user, err := userWithRoles(c)
+	if err != nil {
+		failWithErr(c, err, "修改API密钥失败")
+		return
+	}
+
+	key, err := common.UpdateAPIKey(user, keyID, req.Name, req.Scopes)
+	if err != nil {
+		failWithErr(c, err, "修改API密钥失败")
+		return
+	}
+	response.Success(c, ToAPIKeyDto(*key), "API密钥已更新")
+}
+
+// 撤销密钥，撤销后立即失效
+func RevokeAPIKey(c *gin.Context) {
+	keyID, ok := apiKeyID(c)
+	if !ok {
+		return
+	}
+	user := c.MustGet("user").(model.User)
+
+	if err := common.RevokeAPIKey(user.ID, keyID); err != nil {
+		failWithErr(c, err, "撤销API密钥失败")
+		return
+	}
+	response.Success(c, nil, "API密钥已撤销")
+}
+
+// apiKeyID 解析路径中的密钥 ID
+func apiKeyID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", "无效的密钥ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// userWithRoles 重新加载当前用户及其角色，用于校验授权范围
+func userWithRoles(c *gin.Context) (model.User, error) {
+	user := c.MustGet("user").(model.User)
+	err := common.GetDB().Preload("Roles").First(&user, user.ID).Error
+	return user, err
+}
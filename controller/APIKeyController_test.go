@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"theing/gin-template/common"
+	"theing/gin-template/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAPIKey_RejectsOutOfRangeExpiry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware())
+	router.POST("/api/auth/api-keys", CreateAPIKey)
+
+	// 天数过大时换算成时长会溢出，可能得到已过期或永不过期的密钥
+	for _, days := range []string{"-1", "3651", "106751", "9223372036854775807"} {
+		code, _ := callJSON(t, router, http.MethodPost, "/api/auth/api-keys", `{"name":"ci","expires_in_days":`+days+`}`)
+		assert.Equal(t, int(common.CodeInvalidParams), code, days)
+	}
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"net/http"
 	"theing/gin-template/common"
 	"theing/gin-template/response"
 
@@ -20,6 +21,18 @@ func HealthCheck(c *gin.Context) {
 	}
 }
 
+// Readiness 就绪检查，启动完成前和开始退出后返回 503，负载均衡据此摘除实例
+func Readiness(c *gin.Context) {
+	if !common.IsReady() {
+		appErr := common.NewAppError(common.CodeServiceUnavailable, "服务未就绪", "")
+		// 5xxx 错误码默认映射为 500，未就绪需要明确返回 503，以免被当作实例故障
+		appErr.HTTPStatus = http.StatusServiceUnavailable
+		response.FailWithError(c, appErr)
+		return
+	}
+	response.Success(c, gin.H{"status": "ready"}, "服务就绪")
+}
+
 // DatabaseHealth 数据库健康检查
 func DatabaseHealth(c *gin.Context) {
 	// 获取完整系统状态，然后提取数据库部分
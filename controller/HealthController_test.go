@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"theing/gin-template/common"
+	"theing/gin-template/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware())
+	router.GET("/api/health/ready", Readiness)
+	t.Cleanup(func() { common.SetReady(false) })
+
+	probe := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/health/ready", nil))
+		return w.Code
+	}
+
+	// 未就绪或开始退出后返回 503，负载均衡据此摘除实例而不是判定为故障
+	common.SetReady(false)
+	assert.Equal(t, http.StatusServiceUnavailable, probe())
+
+	common.SetReady(true)
+	assert.Equal(t, http.StatusOK, probe())
+}
@@ -0,0 +1,21 @@
+package controller
+
+import (
+	"net/http"
+	"theing/gin-template/common"
+	"theing/gin-template/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS 发布 JWT 验证公钥，按 RFC 7517 格式直接返回，不包装统一响应结构
+func JWKS(c *gin.Context) {
+	jwks, err := common.GetJWKS()
+	if err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInternalError, "JWT密钥加载失败", err.Error()))
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwks)
+}
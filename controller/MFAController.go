@@ -0,0 +1,116 @@
+package controller
+
+// 两步验证
+
+import (
+	"theing/gin-template/common"
+	"theing/gin-template/model"
+	"theing/gin-template/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 提交验证码的请求
+type PostMFACode struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// 开始启用两步验证，返回密钥、otpauth 地址和二维码
+func MFAEnroll(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+
+	enrollment, err := common.BeginMFAEnrollment(user)
+	if err != nil {
+		failWithErr(c, err, "生成两步验证密钥失败")
+		return
+	}
+	response.Success(c, enrollment, "请使用验证器应用扫描二维码")
+}
+
+// 提交首个验证码以启用两步验证，返回一次性恢复码
+func MFAActivate(c *gin.Context) {
+	var req PostMFACode
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	user := c.MustGet("user").(model.User)
+
+	codes, err := common.ActivateMFA(user.ID, req.Code)
+	if err != nil {
+		failWithErr(c, err, "启用两步验证失败")
+		return
+	}
+	response.Success(c, gin.H{"recovery_codes": codes}, "两步验证已启用，请妥善保存恢复码")
+}
+
+// 关闭两步验证，需要提交当前验证码
+func MFADisable(c *gin.Context) {
+	var req PostMFACode
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	user := c.MustGet("user").(model.User)
+
+	if err := common.VerifyMFACode(user.ID, req.Code); err != nil {
+		failWithErr(c, err, "关闭两步验证失败")
+		return
+	}
+	if err := common.DisableMFA(user.ID); err != nil {
+		failWithErr(c, err, "关闭两步验证失败")
+		return
+	}
+	response.Success(c, nil, "两步验证已关闭")
+}
+
+// 重新生成恢复码，需要提交当前验证码
+func MFARecoveryCodes(c *gin.Context) {
+	var req PostMFACode
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	user := c.MustGet("user").(model.User)
+
+	if err := common.VerifyMFACode(user.ID, req.Code); err != nil {
+		failWithErr(c, err, "生成恢复码失败")
+		return
+	}
+	codes, err := common.RegenerateRecoveryCodes(user.ID)
+	if err != nil {
+		failWithErr(c, err, "生成恢复码失败")
+		return
+	}
+	response.Success(c, gin.H{"recovery_codes": codes}, "恢复码已重新生成，旧的恢复码已失效")
+}
+
+// 两步登录的第二步：提交临时令牌和验证码（或恢复码），换取正式令牌
+func MFAVerify(c *gin.Context) {
+	type PostMFAVerify struct {
+		MFAToken     string `json:"mfa_token" binding:"required"`
+		Code         string `json:"code" binding:"required_without=RecoveryCode"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	var req PostMFAVerify
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+
+	pair, err := common.CompleteMFALogin(c.Request.Context(), req.MFAToken, req.Code, req.RecoveryCode, clientInfo(c))
+	if err != nil {
+		failWithErr(c, err, "两步验证失败")
+		return
+	}
+	response.Success(c, pair, "登录成功")
+}
+
+// failWithErr 输出应用错误，其他错误按内部错误处理
+func failWithErr(c *gin.Context, err error, msg string) {
+	if appErr, ok := err.(*common.AppError); ok {
+		response.FailWithError(c, appErr)
+		return
+	}
+	response.FailWithError(c, common.NewAppError(common.CodeInternalError, msg, err.Error()))
+}
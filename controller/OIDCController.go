@@ -0,0 +1,176 @@
+package controller
+
+// OIDC 第三方登录与账号绑定
+
+import (
+	"net/http"
+	"strconv"
+	"theing/gin-template/common"
+	"theing/gin-template/model"
+	"theing/gin-template/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExternalIdentityDto 返回给客户端的绑定信息
+type ExternalIdentityDto struct {
+	ID          uint       `json:"id"`
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email"`
+	Name        string     `json:"name"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ToExternalIdentityDto 将 model.ExternalIdentity 转换为 ExternalIdentityDto
+func ToExternalIdentityDto(identity model.ExternalIdentity) ExternalIdentityDto {
+	return ExternalIdentityDto{
+		ID:          identity.ID,
+		Provider:    identity.Provider,
+		Email:       identity.Email,
+		Name:        identity.Name,
+		LastLoginAt: identity.LastLoginAt,
+		CreatedAt:   identity.CreatedAt,
+	}
+}
+
+// oidcBindingCookie 保存发起授权的浏览器绑定值，只在 OIDC 路由下发送
+const (
+	oidcBindingCookie     = "oidc_binding"
+	oidcBindingCookiePath = "/api/auth/oidc"
+)
+
+// setOIDCBinding 把授权流程绑定到当前浏览器，回调时校验，防止登录或绑定 CSRF。
+// 提供方回调是跨站的顶层跳转，SameSite=Lax 的 Cookie 仍会带上
+func setOIDCBinding(c *gin.Context, binding string) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcBindingCookie, binding, int(common.GetOIDCStateTTL().Seconds()), oidcBindingCookiePath, "", secure, true)
+}
+
+// takeOIDCBinding 读取并清除浏览器绑定值
+func takeOIDCBinding(c *gin.Context) string {
+	binding, _ := c.Cookie(oidcBindingCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcBindingCookie, "", -1, oidcBindingCookiePath, "", false, true)
+	return binding
+}
+
+// 已配置的第三方登录方式
+func OIDCProviders(c *gin.Context) {
+	response.Success(c, common.OIDCProviders(), "")
+}
+
+// 跳转到第三方登录页面
+func OIDCLogin(c *gin.Context) {
+	auth, err := common.BeginOIDCAuth(c.Request.Context(), c.Param("provider"), 0)
+	if err != nil {
+		failWithErr(c, err, "发起第三方登录失败")
+		return
+	}
+	setOIDCBinding(c, auth.Binding)
+	c.Redirect(http.StatusFound, auth.URL)
+}
+
+// 已登录用户发起绑定，返回授权地址由前端跳转。跨域调用时需要携带凭据（credentials: include），
+// 以便浏览器保存绑定 Cookie
+func OIDCLink(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+
+	auth, err := common.BeginOIDCAuth(c.Request.Context(), c.Param("provider"), user.ID)
+	if err != nil {
+		failWithErr(c, err, "发起账号绑定失败")
+		return
+	}
+	setOIDCBinding(c, auth.Binding)
+	response.Success(c, gin.H{"authorization_url": auth.URL}, "")
+}
+
+// 第三方登录回调：绑定流程完成绑定，登录流程发放令牌（开启两步验证时先返回临时令牌）
+func OIDCCallback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		response.FailWithError(c, common.NewAppError(common.CodeExternalLoginFailed, "第三方登录失败", errCode+": "+c.Query("error_description")))
+		return
+	}
+
+	binding := takeOIDCBinding(c)
+	identity, linkUserID, err := common.ExchangeOIDCCode(c.Request.Context(), c.Param("provider"), c.Query("state"), binding, c.Query("code"))
+	if err != nil {
+		failWithErr(c, err, "第三方登录失败")
+		return
+	}
+
+	if linkUserID != 0 {
+		if err := common.LinkOIDCIdentity(linkUserID, identity); err != nil {
+			failWithErr(c, err, "绑定第三方账号失败")
+			return
+		}
+		response.Success(c, nil, "绑定成功")
+		return
+	}
+
+	user, err := common.ResolveOIDCUser(identity)
+	if err != nil {
+		failWithErr(c, err, "第三方登录失败")
+		return
+	}
+	if err := common.CheckUserActive(user); err != nil {
+		failWithErr(c, err, "第三方登录失败")
+		return
+	}
+
+	mfaEnabled, err := common.IsMFAEnabled(user.ID)
+	if err != nil {
+		failWithErr(c, err, "查询两步验证失败")
+		return
+	}
+	if mfaEnabled {
+		challenge, err := common.IssueMFAChallenge(user, common.AMROIDC)
+		if err != nil {
+			failWithErr(c, err, "token 发放失败")
+			return
+		}
+		response.Success(c, challenge, "请完成两步验证")
+		return
+	}
+
+	pair, err := common.IssueTokenPair(user, clientInfo(c), common.AMROIDC)
+	if err != nil {
+		failWithErr(c, err, "token 发放失败")
+		return
+	}
+	response.Success(c, pair, "登录成功")
+}
+
+// 当前用户绑定的第三方账号
+func ListExternalIdentities(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+
+	identities, err := common.ListExternalIdentities(user.ID)
+	if err != nil {
+		failWithErr(c, err, "查询绑定记录失败")
+		return
+	}
+	list := make([]ExternalIdentityDto, 0, len(identities))
+	for _, identity := range identities {
+		list = append(list, ToExternalIdentityDto(identity))
+	}
+	response.Success(c, list, "")
+}
+
+// 解除第三方账号绑定
+func UnlinkExternalIdentity(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", "无效的绑定ID"))
+		return
+	}
+
+	if err := common.UnlinkExternalIdentity(user.ID, uint(id)); err != nil {
+		failWithErr(c, err, "解除绑定失败")
+		return
+	}
+	response.Success(c, nil, "已解除绑定")
+}
@@ -0,0 +1,105 @@
+package controller
+
+// 个人数据导出与删除申请
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"theing/gin-template/common"
+	"theing/gin-template/dto"
+	"theing/gin-template/model"
+	"theing/gin-template/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 查询自己的导出和删除申请
+func ListPrivacyRequests(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+
+	requests, err := common.ListUserPrivacyRequests(user.ID)
+	if err != nil {
+		failWithErr(c, err, "查询申请失败")
+		return
+	}
+	list := make([]dto.PrivacyRequestDto, 0, len(requests))
+	for _, req := range requests {
+		list = append(list, dto.ToPrivacyRequestDto(req))
+	}
+	response.Success(c, list, "查询成功")
+}
+
+// 申请导出个人数据，后台生成 ZIP 后通过下载接口获取
+func RequestDataExport(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+
+	req, err := common.RequestDataExport(user.ID)
+	if err != nil {
+		failWithErr(c, err, "提交导出申请失败")
+		return
+	}
+	response.Success(c, dto.ToPrivacyRequestDto(*req), "导出申请已提交，生成后可以下载")
+}
+
+// 下载已生成的导出文件
+func DownloadDataExport(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", "无效的申请ID"))
+		return
+	}
+
+	path, err := common.DataExportFile(user.ID, uint(id))
+	if err != nil {
+		failWithErr(c, err, "下载失败")
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.FileAttachment(path, fmt.Sprintf("personal-data-%d%s", id, filepath.Ext(path)))
+}
+
+// 申请删除账号及个人数据，需要提供当前密码；宽限期结束后执行，期间可以撤回
+func RequestErasure(c *gin.Context) {
+	type PostErasure struct {
+		Password string `json:"password" binding:"required"`
+	}
+	var body PostErasure
+	if err := c.ShouldBindJSON(&body); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	user := c.MustGet("user").(model.User)
+
+	ctx := c.Request.Context()
+	clientIP := c.ClientIP()
+	if appErr := common.CheckLoginAllowed(ctx, user.Telephone, clientIP); appErr != nil {
+		response.FailWithError(c, appErr)
+		return
+	}
+	if !common.CheckUserPassword(&user, body.Password) {
+		common.RecordLoginFailure(ctx, user.Telephone, clientIP, "password")
+		response.FailWithError(c, common.ErrPasswordError)
+		return
+	}
+	common.RecordLoginSuccess(ctx, user.Telephone)
+
+	req, err := common.RequestErasure(ctx, user)
+	if err != nil {
+		failWithErr(c, err, "提交删除申请失败")
+		return
+	}
+	response.Success(c, dto.ToPrivacyRequestDto(*req), "删除申请已提交")
+}
+
+// 撤回尚未执行的删除申请
+func CancelErasure(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+
+	if err := common.CancelErasure(user.ID); err != nil {
+		failWithErr(c, err, "撤回删除申请失败")
+		return
+	}
+	response.Success(c, nil, "删除申请已撤回")
+}
@@ -0,0 +1,176 @@
+package controller
+
+// 个人资料、修改密码与更换手机号
+
+import (
+	"log"
+	"theing/gin-template/common"
+	"theing/gin-template/dto"
+	"theing/gin-template/middleware"
+	"theing/gin-template/model"
+	"theing/gin-template/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 修改个人资料，只更新提交了的字段
+func UpdateProfile(c *gin.Context) {
+	type PatchProfile struct {
+		Name   *string `json:"name" binding:"omitempty,min=1,max=20"`
+		Avatar *string `json:"avatar" binding:"omitempty,max=255,url"`
+		Bio    *string `json:"bio" binding:"omitempty,max=255"`
+	}
+	var req PatchProfile
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	user := c.MustGet("user").(model.User)
+
+	// 默认策略 user-own-profile 允许用户修改自己的资料，可以在策略文件中追加限制
+	accessReq, ok := middleware.BuildAccessRequest(c, "profile:update", map[string]interface{}{"type": "user", "id": user.ID})
+	if !ok || !common.Authorize(accessReq).Allowed {
+		response.FailWithError(c, common.ErrForbidden)
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["username"] = *req.Name
+	}
+	if req.Avatar != nil {
+		updates["avatar"] = *req.Avatar
+	}
+	if req.Bio != nil {
+		updates["bio"] = *req.Bio
+	}
+	if len(updates) == 0 {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "没有需要修改的字段", ""))
+		return
+	}
+
+	DB := common.GetDB()
+	if err := DB.Model(&user).Updates(updates).Error; err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "修改资料失败", err.Error()))
+		return
+	}
+	response.Success(c, gin.H{"user": dto.ToUserDto(user)}, "修改成功")
+}
+
+// 修改密码，需要提供当前密码，成功后其他设备上的登录全部失效
+func ChangePassword(c *gin.Context) {
+	type PostChangePassword struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+	var req PostChangePassword
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	user := c.MustGet("user").(model.User)
+	claims := c.MustGet("claims").(*common.Claims)
+
+	// 当前密码错误与登录失败一样计数，防止持有令牌的人猜测密码
+	ctx := c.Request.Context()
+	clientIP := c.ClientIP()
+	if appErr := common.CheckLoginAllowed(ctx, user.Telephone, clientIP); appErr != nil {
+		response.FailWithError(c, appErr)
+		return
+	}
+	if !common.CheckUserPassword(&user, req.CurrentPassword) {
+		common.RecordLoginFailure(ctx, user.Telephone, clientIP, "password")
+		response.FailWithError(c, common.ErrPasswordError)
+		return
+	}
+	common.RecordLoginSuccess(ctx, user.Telephone)
+
+	subject := common.PasswordSubject{UserID: user.ID, Username: user.Username, Telephone: user.Telephone}
+	if err := common.ValidatePassword(req.NewPassword, subject); err != nil {
+		failWithErr(c, err, "密码校验失败")
+		return
+	}
+	if err := common.SetUserPassword(common.GetDB(), user.ID, req.NewPassword); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "修改密码失败", err.Error()))
+		return
+	}
+
+	// 保留当前会话，撤销其他会话
+	revoked, err := common.RevokeUserSessions(ctx, user.ID, claims.SessionID)
+	if err != nil {
+		log.Printf("撤销其他会话失败: %v", err)
+	}
+	response.Success(c, gin.H{"revoked_sessions": revoked}, "密码已修改，其他设备需要重新登录")
+}
+
+// 更换手机号第一步：向新手机号发送验证码
+func RequestPhoneChange(c *gin.Context) {
+	type PostPhoneChange struct {
+		Telephone string `json:"telephone" binding:"required,len=11,numeric"`
+	}
+	var req PostPhoneChange
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	user := c.MustGet("user").(model.User)
+
+	if err := common.RequestPhoneChange(c.Request.Context(), user, req.Telephone); err != nil {
+		failWithErr(c, err, "验证码发送失败")
+		return
+	}
+	response.Success(c, nil, "验证码已发送")
+}
+
+// 更换手机号第二步：提交新手机号收到的验证码
+func ConfirmPhoneChange(c *gin.Context) {
+	type PostPhoneConfirm struct {
+		Telephone string `json:"telephone" binding:"required,len=11,numeric"`
+		Code      string `json:"code" binding:"required"`
+	}
+	var req PostPhoneConfirm
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	user := c.MustGet("user").(model.User)
+
+	if err := common.ChangeUserPhone(c.Request.Context(), user, req.Telephone, req.Code); err != nil {
+		failWithErr(c, err, "更换手机号失败")
+		return
+	}
+	user.Telephone = req.Telephone
+	response.Success(c, gin.H{"user": dto.ToUserDto(user)}, "手机号已更换")
+}
+
+// 注销账号，需要提供当前密码；注销后所有登录失效，手机号可以重新注册
+func DeactivateAccount(c *gin.Context) {
+	type PostDeactivate struct {
+		Password string `json:"password" binding:"required"`
+	}
+	var req PostDeactivate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	user := c.MustGet("user").(model.User)
+
+	ctx := c.Request.Context()
+	clientIP := c.ClientIP()
+	if appErr := common.CheckLoginAllowed(ctx, user.Telephone, clientIP); appErr != nil {
+		response.FailWithError(c, appErr)
+		return
+	}
+	if !common.CheckUserPassword(&user, req.Password) {
+		common.RecordLoginFailure(ctx, user.Telephone, clientIP, "password")
+		response.FailWithError(c, common.ErrPasswordError)
+		return
+	}
+	common.RecordLoginSuccess(ctx, user.Telephone)
+
+	if err := common.DeleteUser(ctx, user.ID); err != nil {
+		failWithErr(c, err, "注销账号失败")
+		return
+	}
+	response.Success(c, nil, "账号已注销")
+}
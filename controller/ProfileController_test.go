@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"theing/gin-template/common"
+	"theing/gin-template/config"
+	"theing/gin-template/middleware"
+	"theing/gin-template/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// useConfig 临时修改全局配置，测试结束后恢复
+func useConfig(t *testing.T, update func(cfg *config.Config)) {
+	previous := config.Get()
+	cfg := *previous
+	update(&cfg)
+	config.Set(&cfg)
+	t.Cleanup(func() { config.Set(previous) })
+}
+
+// profileRouter 以指定用户和会话访问个人资料接口，代替 AuthMiddleware 写入上下文
+func profileRouter(t *testing.T, user model.User, sessionID string) *gin.Engine {
+	// 测试在 controller 目录下运行，需要指明策略文件的位置
+	useConfig(t, func(cfg *config.Config) { cfg.Policy.File = "../config/policies.yml" })
+	require.NoError(t, common.InitPolicies())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware(), func(c *gin.Context) {
+		c.Set("user", user)
+		c.Set("claims", &common.Claims{UserId: user.ID, Roles: []string{common.RoleUser}, SessionID: sessionID})
+		c.Next()
+	})
+	router.PATCH("/api/auth/profile", UpdateProfile)
+	router.POST("/api/auth/password", ChangePassword)
+	return router
+}
+
+// createUserWithPassword 创建使用指定密码的正常用户
+func createUserWithPassword(t *testing.T, db *gorm.DB, telephone, password string) model.User {
+	hashed, err := common.HashPassword(password)
+	require.NoError(t, err)
+	user := model.User{Username: "user" + telephone[7:], Telephone: telephone, Password: hashed, Status: common.UserStatusActive}
+	require.NoError(t, db.Create(&user).Error)
+	return user
+}
+
+func TestUpdateProfile_Partial(t *testing.T) {
+	db := useTestDB(t)
+	user := createUserWithPassword(t, db, "13980000001", "Old-Passw0rd")
+	require.NoError(t, db.Model(&user).Update("avatar", "https://example.com/a.png").Error)
+	router := profileRouter(t, user, "")
+	reload := func() model.User {
+		var current model.User
+		require.NoError(t, db.First(&current, user.ID).Error)
+		return current
+	}
+
+	// 只修改提交了的字段
+	code, _ := callJSON(t, router, http.MethodPatch, "/api/auth/profile", `{"bio":"你好"}`)
+	require.Equal(t, 0, code)
+	current := reload()
+	assert.Equal(t, "你好", current.Bio)
+	assert.Equal(t, user.Username, current.Username)
+	assert.Equal(t, "https://example.com/a.png", current.Avatar)
+
+	code, _ = callJSON(t, router, http.MethodPatch, "/api/auth/profile", `{"name":"新名字","bio":""}`)
+	require.Equal(t, 0, code)
+	current = reload()
+	assert.Equal(t, "新名字", current.Username)
+	assert.Empty(t, current.Bio)
+
+	code, _ = callJSON(t, router, http.MethodPatch, "/api/auth/profile", `{}`)
+	assert.Equal(t, int(common.CodeInvalidParams), code)
+	code, _ = callJSON(t, router, http.MethodPatch, "/api/auth/profile", `{"name":""}`)
+	assert.Equal(t, int(common.CodeInvalidParams), code)
+	code, _ = callJSON(t, router, http.MethodPatch, "/api/auth/profile", `{"avatar":"not a url"}`)
+	assert.Equal(t, int(common.CodeInvalidParams), code)
+	assert.Equal(t, "新名字", reload().Username)
+}
+
+func TestChangePassword_WrongPasswordCountsTowardsLockout(t *testing.T) {
+	db := useTestDB(t)
+	useConfig(t, func(cfg *config.Config) {
+		cfg.Lockout.MaxAccountFailures = 2
+		cfg.Lockout.DelayAfter = 10
+	})
+	user := createUserWithPassword(t, db, "13980000002", "Old-Passw0rd")
+	router := profileRouter(t, user, "")
+	t.Cleanup(func() {
+		common.UnlockLogin(context.Background(), common.LockoutScopeAccount, user.Telephone)
+		common.UnlockLogin(context.Background(), common.LockoutScopeIP, "192.0.2.1")
+	})
+	change := func(current string) int {
+		code, _ := callJSON(t, router, http.MethodPost, "/api/auth/password",
+			`{"current_password":"`+current+`","new_password":"N3w-Passw0rd!"}`)
+		return code
+	}
+
+	assert.Equal(t, int(common.CodePasswordError), change("wrong-1"))
+	assert.Equal(t, int(common.CodePasswordError), change("wrong-2"))
+	// 达到阈值后即使密码正确也被锁定
+	assert.Equal(t, int(common.CodeAccountLocked), change("Old-Passw0rd"))
+
+	var current model.User
+	require.NoError(t, db.First(&current, user.ID).Error)
+	assert.True(t, common.CheckUserPassword(&current, "Old-Passw0rd"))
+}
+
+func TestChangePassword_RevokesOtherSessions(t *testing.T) {
+	db := useTestDB(t)
+	user := createUserWithPassword(t, db, "13980000003", "Old-Passw0rd")
+	current := loginSession(t, user)
+	loginSession(t, user)
+	loginSession(t, user)
+	router := profileRouter(t, user, current)
+
+	// 新密码不满足策略时不修改
+	code, _ := callJSON(t, router, http.MethodPost, "/api/auth/password", `{"current_password":"Old-Passw0rd","new_password":"short"}`)
+	assert.Equal(t, int(common.CodePasswordTooWeak), code)
+
+	code, data := callJSON(t, router, http.MethodPost, "/api/auth/password", `{"current_password":"Old-Passw0rd","new_password":"N3w-Passw0rd!"}`)
+	require.Equal(t, 0, code)
+	assert.JSONEq(t, `{"revoked_sessions":2}`, string(data))
+
+	sessions, err := common.ListActiveSessions(user.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, current, sessions[0].ID)
+
+	var updated model.User
+	require.NoError(t, db.First(&updated, user.ID).Error)
+	assert.True(t, common.CheckUserPassword(&updated, "N3w-Passw0rd!"))
+	assert.False(t, common.CheckUserPassword(&updated, "Old-Passw0rd"))
+}
@@ -0,0 +1,99 @@
+package controller
+
+// 登录会话与设备管理
+
+import (
+	"log"
+	"theing/gin-template/common"
+	"theing/gin-template/model"
+	"theing/gin-template/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionDto 返回给客户端的会话信息
+type SessionDto struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // 是否为发起本次请求的会话
+}
+
+// ToSessionDto 将 model.Session 转换为 SessionDto
+func ToSessionDto(session model.Session, currentID string) SessionDto {
+	return SessionDto{
+		ID:         session.ID,
+		UserAgent:  session.UserAgent,
+		IP:         session.IP,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+		Current:    session.ID == currentID,
+	}
+}
+
+// 当前用户的活跃会话列表
+func ListSessions(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+	claims := c.MustGet("claims").(*common.Claims)
+
+	sessions, err := common.ListActiveSessions(user.ID)
+	if err != nil {
+		failWithErr(c, err, "查询会话失败")
+		return
+	}
+	list := make([]SessionDto, 0, len(sessions))
+	for _, session := range sessions {
+		list = append(list, ToSessionDto(session, claims.SessionID))
+	}
+	response.Success(c, list, "")
+}
+
+// 撤销某个会话，对应设备需要重新登录
+func RevokeSession(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+
+	if err := common.RevokeSession(c.Request.Context(), user.ID, c.Param("id")); err != nil {
+		failWithErr(c, err, "撤销会话失败")
+		return
+	}
+	response.Success(c, nil, "会话已撤销")
+}
+
+// 撤销全部会话，except_current=true 时保留当前会话
+func RevokeAllSessions(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+	claims := c.MustGet("claims").(*common.Claims)
+
+	exceptID := ""
+	if c.Query("except_current") == "true" {
+		exceptID = claims.SessionID
+	}
+	count, err := common.RevokeUserSessions(c.Request.Context(), user.ID, exceptID)
+	if err != nil {
+		failWithErr(c, err, "撤销会话失败")
+		return
+	}
+	response.Success(c, gin.H{"revoked": count}, "会话已撤销")
+}
+
+// clientInfo 获取发起请求的客户端信息，用于创建会话
+func clientInfo(c *gin.Context) common.ClientInfo {
+	return common.ClientInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+}
+
+// revokeCurrentSession 退出登录时撤销当前会话，旧版令牌没有会话 ID 时跳过
+func revokeCurrentSession(c *gin.Context, userID uint, claims *common.Claims) {
+	if claims.SessionID == "" {
+		return
+	}
+	if err := common.RevokeSession(c.Request.Context(), userID, claims.SessionID); err != nil {
+		if appErr, ok := err.(*common.AppError); !ok || appErr.Code != common.CodeDataNotFound {
+			log.Printf("撤销会话失败: %v", err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"theing/gin-template/common"
+	"theing/gin-template/middleware"
+	"theing/gin-template/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionRouter 以指定用户和会话访问会话管理接口，代替 AuthMiddleware 写入上下文
+func sessionRouter(user model.User, sessionID string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware(), func(c *gin.Context) {
+		c.Set("user", user)
+		c.Set("claims", &common.Claims{UserId: user.ID, SessionID: sessionID})
+		c.Next()
+	})
+	router.GET("/api/auth/sessions", ListSessions)
+	router.DELETE("/api/auth/sessions", RevokeAllSessions)
+	router.DELETE("/api/auth/sessions/:id", RevokeSession)
+	return router
+}
+
+// callJSON 发起请求，返回响应中的 code 和 data，成功时 data 为业务数据
+func callJSON(t *testing.T, router *gin.Engine, method, path, body string) (int, json.RawMessage) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var result struct {
+		Code int             `json:"code"`
+		Data json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result), w.Body.String())
+	if result.Code != 0 {
+		return result.Code, result.Data
+	}
+	var success struct {
+		Data json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(result.Data, &success))
+	return result.Code, success.Data
+}
+
+// loginSession 为用户登录一次，返回会话 ID
+func loginSession(t *testing.T, user model.User) string {
+	t.Helper()
+	pair, err := common.IssueTokenPair(user, common.ClientInfo{UserAgent: "test"})
+	require.NoError(t, err)
+	_, claims, err := common.ParseToken(pair.AccessToken)
+	require.NoError(t, err)
+	return claims.SessionID
+}
+
+func TestSessions(t *testing.T) {
+	db := useTestDB(t)
+	owner := model.User{Username: "owner", Telephone: "13960000001", Password: "hash", Status: common.UserStatusActive}
+	other := model.User{Username: "other", Telephone: "13960000002", Password: "hash", Status: common.UserStatusActive}
+	require.NoError(t, db.Create(&owner).Error)
+	require.NoError(t, db.Create(&other).Error)
+
+	current := loginSession(t, owner)
+	loginSession(t, owner)
+	otherSession := loginSession(t, other)
+	router := sessionRouter(owner, current)
+
+	code, data := callJSON(t, router, http.MethodGet, "/api/auth/sessions", "")
+	require.Equal(t, 0, code)
+	var sessions []SessionDto
+	require.NoError(t, json.Unmarshal(data, &sessions))
+	require.Len(t, sessions, 2)
+	currentCount := 0
+	for _, session := range sessions {
+		if session.Current {
+			currentCount++
+			assert.Equal(t, current, session.ID)
+		}
+	}
+	assert.Equal(t, 1, currentCount)
+
+	// 其他用户的会话 ID 按不存在处理
+	code, _ = callJSON(t, router, http.MethodDelete, "/api/auth/sessions/"+otherSession, "")
+	assert.Equal(t, int(common.CodeDataNotFound), code)
+
+	code, data = callJSON(t, router, http.MethodDelete, "/api/auth/sessions?except_current=true", "")
+	require.Equal(t, 0, code)
+	assert.JSONEq(t, `{"revoked":1}`, string(data))
+	remaining, err := common.ListActiveSessions(owner.ID)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, current, remaining[0].ID)
+	remaining, err = common.ListActiveSessions(other.ID)
+	require.NoError(t, err)
+	assert.Len(t, remaining, 1)
+}
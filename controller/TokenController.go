@@ -0,0 +1,79 @@
+package controller
+
+// 令牌相关
+
+import (
+	"log"
+	"theing/gin-template/common"
+	"theing/gin-template/model"
+	"theing/gin-template/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 使用刷新令牌换取新的令牌对
+func RefreshToken(c *gin.Context) {
+	type PostRefreshToken struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	var req PostRefreshToken
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+
+	pair, err := common.RotateRefreshToken(req.RefreshToken)
+	if err != nil {
+		if appErr, ok := err.(*common.AppError); ok {
+			response.FailWithError(c, appErr)
+		} else {
+			response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "刷新令牌失败", err.Error()))
+		}
+		return
+	}
+
+	response.Success(c, pair, "刷新成功")
+}
+
+// 退出登录：撤销当前访问令牌和所在会话，若同时提交刷新令牌则撤销其所在的令牌族
+func Logout(c *gin.Context) {
+	type PostLogout struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	var req PostLogout
+	_ = c.ShouldBindJSON(&req) // 请求体可以为空
+
+	user := c.MustGet("user").(model.User)
+	claims := c.MustGet("claims").(*common.Claims)
+
+	if err := common.RevokeToken(c.Request.Context(), claims); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInternalError, "退出登录失败", err.Error()))
+		return
+	}
+	revokeCurrentSession(c, user.ID, claims)
+
+	if req.RefreshToken != "" {
+		if err := common.RevokeRefreshTokenByValue(user.ID, req.RefreshToken); err != nil && err != common.ErrRefreshTokenInvalid {
+			log.Printf("撤销刷新令牌失败: %v", err)
+		}
+	}
+
+	response.Success(c, nil, "退出登录成功")
+}
+
+// 退出所有设备：撤销用户此前签发的全部访问令牌和刷新令牌
+func LogoutAll(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+
+	if err := common.RevokeUserTokens(c.Request.Context(), user.ID); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInternalError, "退出所有设备失败", err.Error()))
+		return
+	}
+	if err := common.RevokeUserRefreshTokens(user.ID); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "撤销刷新令牌失败", err.Error()))
+		return
+	}
+
+	response.Success(c, nil, "已退出所有设备")
+}
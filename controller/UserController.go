@@ -4,15 +4,14 @@ package controller
 
 import (
 	"log"
-	"strings"
 	"theing/gin-template/common"
 	"theing/gin-template/dto"
 	"theing/gin-template/model"
 	"theing/gin-template/response"
 	"theing/gin-template/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -20,28 +19,24 @@ import (
 func Register(c *gin.Context) {
 	DB := common.GetDB() //引入DB实例
 
-	// 获取参数
-	name := c.PostForm("name")
-	telephone := c.PostForm("telephone")
-	password := c.PostForm("password")
+	// 获取参数，兼容表单和 JSON
+	type PostRegister struct {
+		common.RegistrationInput // 用户名和手机号，与批量导入使用相同的校验规则
 
-	// 数据验证
-	if len(telephone) != 11 {
-		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "手机号必须为11位", ""))
-		return
+		Password string `form:"password" json:"password" binding:"required"`
+		Code     string `form:"code" json:"code"` // 开启注册验证时必填
 	}
-
-	// 使用新的密码验证逻辑
-	passwordValidation := utils.ValidatePassword(password)
-	if !passwordValidation.IsValid {
-		errorMsg := "密码不符合要求：" + strings.Join(passwordValidation.Errors, "；")
-		response.FailWithError(c, common.NewAppError(common.CodePasswordTooWeak, errorMsg, strings.Join(passwordValidation.Suggestions, "；")))
+	var req PostRegister
+	if err := c.ShouldBind(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
 		return
 	}
+	name, telephone, password := req.Name, req.Telephone, req.Password
 
-	// 如果密码强度较弱，给出建议但不阻止注册
-	if passwordValidation.Strength != utils.PasswordStrong {
-		log.Printf("用户注册密码强度提醒：%v", strings.Join(passwordValidation.Suggestions, "；"))
+	// 按密码策略校验，不满足时返回逐条原因
+	if err := common.ValidatePassword(password, common.PasswordSubject{Username: name, Telephone: telephone}); err != nil {
+		failWithErr(c, err, "密码校验失败")
+		return
 	}
 
 	// 如果名称没有传入，给一个10位的随机字符串
@@ -49,28 +44,49 @@ func Register(c *gin.Context) {
 		name = utils.RandomString(10)
 	}
 
-	log.Println(name, telephone, password)
 	// 判断手机号是否存在
 	if isTelephoneExist(DB, telephone) {
 		response.FailWithError(c, common.ErrUserExists)
 		return
 	}
 
-	// 创建用户，用户的密码是不能明文保存的，所有所有的密码都应该加密保存，写法如下，都是通用的。
-	hasedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost) // ! 这里的加密方式自己也应该研究一下。
+	// 开启注册验证时，需要提交发送到该手机号的验证码
+	var phoneVerifiedAt *time.Time
+	if common.RegisterRequiresVerification() {
+		if err := common.VerifyCode(c.Request.Context(), common.VerifyPurposeRegister, telephone, req.Code); err != nil {
+			failWithErr(c, err, "验证码校验失败")
+			return
+		}
+		now := time.Now()
+		phoneVerifiedAt = &now
+	}
+
+	// 创建用户，用户的密码是不能明文保存的，按 password.algorithm 配置的算法计算哈希
+	hasedPassword, err := common.HashPassword(password)
 	if err != nil {
 		response.FailWithError(c, common.NewAppError(common.CodeInternalError, "加密错误", err.Error()))
 		return // 返回前端一个错误，这里是一个系统基本的错误。
 	}
+	now := time.Now()
 	newUser := model.User{
 		Username:  name,
 		Telephone: telephone,
-		Password:  string(hasedPassword), // 创建密码的时候不能明文
+		Password:  hasedPassword, // 创建密码的时候不能明文
+
+		PhoneVerifiedAt:   phoneVerifiedAt,
+		PasswordChangedAt: &now,
 	}
 	if err := DB.Create(&newUser).Error; err != nil {
 		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "用户创建失败", err.Error()))
 		return
 	}
+	if err := common.RecordPasswordHistory(DB, newUser.ID, hasedPassword); err != nil {
+		log.Printf("记录密码历史失败: %v", err)
+	}
+	// 新用户默认授予普通用户角色
+	if err := common.AssignRole(newUser.ID, common.RoleUser); err != nil {
+		log.Printf("授予默认角色失败: %v", err)
+	}
 	// 返回结果
 	response.Success(c, gin.H{"user_id": newUser.ID}, "注册成功")
 }
@@ -89,8 +105,6 @@ func UserLogin(c *gin.Context) {
 		return
 	}
 
-	print(login.Password, login.Telephone)
-
 	// 数据验证
 	if len(login.Telephone) != 11 {
 		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "手机号必须为11位", ""))
@@ -103,27 +117,60 @@ func UserLogin(c *gin.Context) {
 		return
 	}
 
-	// 判断手机号是否存在
+	// 账号或 IP 失败次数过多时拒绝登录
+	ctx := c.Request.Context()
+	clientIP := c.ClientIP()
+	if appErr := common.CheckLoginAllowed(ctx, login.Telephone, clientIP); appErr != nil {
+		response.FailWithError(c, appErr)
+		return
+	}
+
+	// 判断手机号是否存在，不存在与密码错误返回相同的错误，避免泄露手机号是否注册
 	DB := common.GetDB() // 引入 DB实例
 	var user model.User
-	if err := DB.Where("telephone = ?", login.Telephone).First(&user).Error; err != nil {
+	if err := DB.Preload("Roles").Where("telephone = ?", login.Telephone).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			response.FailWithError(c, common.ErrUserNotFound)
+			common.EqualizePasswordCheck(login.Password)
+			common.RecordLoginFailure(ctx, login.Telephone, clientIP, "password")
+			response.FailWithError(c, common.ErrInvalidCredentials)
 		} else {
 			response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "查询用户失败", err.Error()))
 		}
 		return
 	}
 
-	// 判断密码是否正确
-	// 判定用户密码的时候就用bcrypt的方法进行判定，第一个参数是原始的加密后的密码，第二个参数就是需要对比的密码
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(login.Password)); err != nil { // 如果有err 就提示密码错误。这里应该也是和if同级的一个代码。
-		response.FailWithError(c, common.ErrPasswordError)
+	// 判断密码是否正确，哈希的算法或参数过时时顺便升级
+	if !common.CheckUserPassword(&user, login.Password) {
+		common.RecordLoginFailure(ctx, login.Telephone, clientIP, "password")
+		response.FailWithError(c, common.ErrInvalidCredentials)
+		return
+	}
+	common.RecordLoginSuccess(ctx, login.Telephone)
+
+	// 停用的账号在密码正确后才提示，避免泄露账号状态
+	if err := common.CheckUserActive(user); err != nil {
+		failWithErr(c, err, "登录失败")
+		return
+	}
+
+	// 开启了两步验证时，先发放临时令牌，验证码通过后再发放正式令牌
+	mfaEnabled, err := common.IsMFAEnabled(user.ID)
+	if err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "查询两步验证失败", err.Error()))
+		return
+	}
+	if mfaEnabled {
+		challenge, err := common.IssueMFAChallenge(user)
+		if err != nil {
+			response.FailWithError(c, common.NewAppError(common.CodeInternalError, "token 发放失败", err.Error()))
+			return
+		}
+		response.Success(c, challenge, "请完成两步验证")
 		return
 	}
 
-	// 发放token
-	token, err := common.ReleaseToken(user)
+	// 发放访问令牌和刷新令牌
+	pair, err := common.IssueTokenPair(user, clientInfo(c), common.AMRPassword)
 	if err != nil {
 		response.FailWithError(c, common.NewAppError(common.CodeInternalError, "token 发放失败", err.Error()))
 		log.Printf("token generate error : %v", err) // 遇到了这个问题记录一下日志。
@@ -131,7 +178,7 @@ func UserLogin(c *gin.Context) {
 	}
 
 	// 返回结果
-	response.Success(c, gin.H{"token": token}, "登录成功")
+	response.Success(c, pair, "登录成功")
 }
 
 func isTelephoneExist(db *gorm.DB, telephone string) bool {
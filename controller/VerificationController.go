@@ -0,0 +1,120 @@
+package controller
+
+// 短信验证码与找回密码
+
+import (
+	"log"
+	"theing/gin-template/common"
+	"theing/gin-template/model"
+	"theing/gin-template/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 发送短信验证码
+func SendVerificationCode(c *gin.Context) {
+	type PostVerificationCode struct {
+		Telephone string `json:"telephone" binding:"required,len=11,numeric"`
+		Purpose   string `json:"purpose" binding:"required"`
+	}
+	var req PostVerificationCode
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	if !common.IsValidVerifyPurpose(req.Purpose) {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "不支持的验证码用途", req.Purpose))
+		return
+	}
+
+	// 注册时手机号已存在、找回密码时手机号未注册都不发送短信，但返回相同的结果，避免泄露手机号是否注册。
+	// 注册接口仍会拒绝已注册的手机号
+	exists := isTelephoneExist(common.GetDB(), req.Telephone)
+	switch req.Purpose {
+	case common.VerifyPurposeRegister:
+		if exists {
+			response.Success(c, nil, "验证码已发送")
+			return
+		}
+	case common.VerifyPurposeResetPassword:
+		if !exists {
+			response.Success(c, nil, "验证码已发送")
+			return
+		}
+	case common.VerifyPurposeChangePhone:
+		// 更换手机号需要登录，通过 /api/auth/phone 发送
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "不支持的验证码用途", req.Purpose))
+		return
+	}
+
+	if err := common.SendVerificationCode(c.Request.Context(), req.Purpose, req.Telephone); err != nil {
+		failWithErr(c, err, "验证码发送失败")
+		return
+	}
+	response.Success(c, nil, "验证码已发送")
+}
+
+// 通过短信验证码重置密码，成功后撤销该用户所有已登录的令牌
+func ResetPassword(c *gin.Context) {
+	type PostResetPassword struct {
+		Telephone string `json:"telephone" binding:"required,len=11,numeric"`
+		Code      string `json:"code" binding:"required"`
+		Password  string `json:"password" binding:"required"`
+	}
+	var req PostResetPassword
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+
+	// 先按密码策略校验，避免验证码被消耗后才发现密码不合格
+	if err := common.ValidatePassword(req.Password, common.PasswordSubject{Telephone: req.Telephone}); err != nil {
+		failWithErr(c, err, "密码校验失败")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := common.VerifyCode(ctx, common.VerifyPurposeResetPassword, req.Telephone, req.Code); err != nil {
+		failWithErr(c, err, "验证码校验失败")
+		return
+	}
+
+	DB := common.GetDB()
+	var user model.User
+	if err := DB.Where("telephone = ?", req.Telephone).Limit(1).Find(&user).Error; err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "查询用户失败", err.Error()))
+		return
+	}
+	if user.ID == 0 {
+		response.FailWithError(c, common.ErrVerificationCodeInvalid)
+		return
+	}
+
+	// 历史密码和用户名需要知道具体用户才能检查
+	if err := common.ValidatePassword(req.Password, common.PasswordSubject{UserID: user.ID, Username: user.Username, Telephone: user.Telephone}); err != nil {
+		failWithErr(c, err, "密码校验失败")
+		return
+	}
+	if err := common.SetUserPassword(DB, user.ID, req.Password); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "重置密码失败", err.Error()))
+		return
+	}
+	// 待激活的账号通过短信验证设置密码后即完成激活
+	if err := common.ActivatePendingUser(DB, user.ID); err != nil {
+		log.Printf("激活用户 %d 失败: %v", user.ID, err)
+	}
+
+	// 密码已变更，之前的登录和 API 密钥全部失效，并解除登录锁定
+	if err := common.RevokeUserRefreshTokens(user.ID); err != nil {
+		log.Printf("撤销刷新令牌失败: %v", err)
+	}
+	if err := common.RevokeUserTokens(ctx, user.ID); err != nil {
+		log.Printf("撤销访问令牌失败: %v", err)
+	}
+	if err := common.RevokeUserAPIKeys(user.ID); err != nil {
+		log.Printf("撤销API密钥失败: %v", err)
+	}
+	common.RecordLoginSuccess(ctx, user.Telephone)
+
+	response.Success(c, nil, "密码已重置，请重新登录")
+}
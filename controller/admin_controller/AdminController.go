@@ -8,7 +8,7 @@ import (
 	"theing/gin-template/response"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // admin登录
@@ -16,8 +16,24 @@ func AdminLogin(c *gin.Context) {
 	// 获取参数
 
 	DB := common.GetDB() // 引入 DB实例
-	tel := c.PostForm("tel")
-	password := c.PostForm("password")
+	type PostAdminLogin struct {
+		Tel      string `form:"tel" json:"tel" binding:"required"`
+		Password string `form:"password" json:"password" binding:"required"`
+	}
+	// 只从请求体读取账号密码，避免凭据出现在 URL 和访问日志里
+	var binder binding.Binding = binding.FormPost
+	switch c.ContentType() {
+	case binding.MIMEJSON:
+		binder = binding.JSON
+	case binding.MIMEMultipartPOSTForm:
+		binder = binding.FormMultipart
+	}
+	var req PostAdminLogin
+	if err := c.ShouldBindWith(&req, binder); err != nil {
+		response.Response(c, http.StatusUnprocessableEntity, 422, nil, "参数错误")
+		return
+	}
+	tel, password := req.Tel, req.Password
 
 	// 数据验证
 	if len(tel) != 11 {
@@ -29,25 +45,62 @@ func AdminLogin(c *gin.Context) {
 		return
 	}
 
-	// 判断手机号是否存在
+	// 账号或 IP 失败次数过多时拒绝登录
+	ctx := c.Request.Context()
+	clientIP := c.ClientIP()
+	if appErr := common.CheckLoginAllowed(ctx, tel, clientIP); appErr != nil {
+		response.FailWithError(c, appErr)
+		return
+	}
+
+	// 判断手机号是否存在，不存在与密码错误返回相同的错误
 	var user model.User
-	DB.Select("username,tel,password").Table("users").Where("tel = ?", tel).First(&user)
-	// DB.Where("tel = ?", tel).First(&user)
+	DB.Preload("Roles").Where("telephone = ?", tel).First(&user)
+	// DB.Where("telephone = ?", tel).First(&user)
 	// DB.Raw("select tel from users where id > ?", userId).Scan(&userList)
 	if user.ID == 0 {
-		response.Response(c, http.StatusUnprocessableEntity, 422, nil, "用户不存在")
+		common.EqualizePasswordCheck(password)
+		common.RecordLoginFailure(ctx, tel, clientIP, "password")
+		response.FailWithError(c, common.ErrInvalidCredentials)
+		return
+	}
+
+	// 判断密码是否正确，哈希的算法或参数过时时顺便升级
+	if !common.CheckUserPassword(&user, password) {
+		common.RecordLoginFailure(ctx, tel, clientIP, "password")
+		response.FailWithError(c, common.ErrInvalidCredentials)
+		return
+	}
+	common.RecordLoginSuccess(ctx, tel)
+	if err := common.CheckUserActive(user); err != nil {
+		failWithErr(c, err, "登录失败")
 		return
 	}
 
-	// 判断密码是否正确
-	// 判定用户密码的时候就用bcrypt的方法进行判定，第一个参数是原始的加密后的密码，第二个参数就是需要对比的密码
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil { // 如果有err 就提示密码错误。这里应该也是和if同级的一个代码。
-		response.Response(c, http.StatusBadRequest, 400, nil, "密码错误")
+	// 只有管理员角色可以登录后台
+	if !common.HasRole(common.RoleNames(user), common.RoleAdmin) {
+		response.FailWithError(c, common.NewAppError(common.CodeForbidden, "无管理员权限", ""))
+		return
+	}
+
+	// 开启了两步验证时，先发放临时令牌，验证码通过后再发放正式令牌
+	mfaEnabled, err := common.IsMFAEnabled(user.ID)
+	if err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "查询两步验证失败", err.Error()))
+		return
+	}
+	if mfaEnabled {
+		challenge, err := common.IssueMFAChallenge(user)
+		if err != nil {
+			response.Response(c, http.StatusInternalServerError, 500, nil, "token 发放失败")
+			return
+		}
+		response.Success(c, challenge, "请完成两步验证")
 		return
 	}
 
-	// 发放token
-	token, err := common.ReleaseToken(user)
+	// 发放访问令牌和刷新令牌
+	pair, err := common.IssueTokenPair(user, common.ClientInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}, common.AMRPassword)
 	if err != nil {
 		response.Response(c, http.StatusInternalServerError, 500, nil, "token 发放失败")
 		log.Printf("token generate error : %v", err) // 遇到了这个问题记录一下日志。
@@ -55,5 +108,5 @@ func AdminLogin(c *gin.Context) {
 	}
 
 	// 返回结果
-	response.Success(c, gin.H{"token": token}, "登录成功")
+	response.Success(c, pair, "登录成功")
 }
@@ -0,0 +1,39 @@
+package admin_controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"theing/gin-template/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminLogin_IgnoresQueryCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware())
+	router.POST("/api/admin/login", AdminLogin)
+
+	// 账号密码只从请求体读取，放在查询参数里按缺少参数处理
+	for _, contentType := range []string{"application/x-www-form-urlencoded", "application/json"} {
+		payload := ""
+		if contentType == "application/json" {
+			payload = "{}"
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/login?tel=13800000000&password=secret123", strings.NewReader(payload))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var body struct {
+			Code int `json:"code"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, 422, body.Code, contentType)
+	}
+}
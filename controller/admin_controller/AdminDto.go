@@ -1 +1,167 @@
 package admin_controller
+
+import (
+	"encoding/json"
+	"strings"
+	"theing/gin-template/model"
+	"time"
+)
+
+// RoleDto 角色数据传输对象
+type RoleDto struct {
+	ID          uint     `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// PermissionDto 权限数据传输对象
+type PermissionDto struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// CreateRoleRequest 创建角色请求
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=50"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+// AssignRoleRequest 授予角色请求
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// ToRoleDto 将 model.Role 转换为 RoleDto，权限需要预加载
+func ToRoleDto(role model.Role) RoleDto {
+	permissions := make([]string, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		permissions = append(permissions, permission.Name)
+	}
+	return RoleDto{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: permissions,
+	}
+}
+
+// EvaluatePolicyRequest 策略试算请求，context 中未提供的属性使用当前环境
+type EvaluatePolicyRequest struct {
+	Subject  map[string]interface{} `json:"subject"`
+	Resource map[string]interface{} `json:"resource"`
+	Action   string                 `json:"action" binding:"required"`
+	Context  map[string]interface{} `json:"context"`
+}
+
+// LockoutEventDto 登录锁定记录
+type LockoutEventDto struct {
+	ID          uint      `json:"id"`
+	Scope       string    `json:"scope"`
+	Identifier  string    `json:"identifier"`
+	IP          string    `json:"ip"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+	Active      bool      `json:"active"` // 是否仍在锁定中
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// UnlockRequest 解除锁定请求
+type UnlockRequest struct {
+	Scope      string `json:"scope" binding:"required,oneof=account ip"`
+	Identifier string `json:"identifier" binding:"required"`
+}
+
+// ToLockoutEventDto 将 model.LockoutEvent 转换为 LockoutEventDto
+func ToLockoutEventDto(event model.LockoutEvent) LockoutEventDto {
+	return LockoutEventDto{
+		ID:          event.ID,
+		Scope:       event.Scope,
+		Identifier:  event.Identifier,
+		IP:          event.IP,
+		Failures:    event.Failures,
+		LockedUntil: event.LockedUntil,
+		Active:      time.Now().Before(event.LockedUntil),
+		CreatedAt:   event.CreatedAt,
+	}
+}
+
+// CreateOAuthClientRequest 登记第三方应用请求
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris"`
+	GrantTypes   []string `json:"grant_types"` // 为空时使用 authorization_code 和 refresh_token
+	Scopes       []string `json:"scopes"`
+	Public       bool     `json:"public"`       // 无法保存密钥的客户端，如单页应用和移动应用，必须使用 PKCE
+	SkipConsent  bool     `json:"skip_consent"` // 自有应用可以跳过用户确认
+}
+
+// OAuthClientDto 第三方应用，不包含密钥
+type OAuthClientDto struct {
+	ClientID     string     `json:"client_id"`
+	Name         string     `json:"name"`
+	RedirectURIs []string   `json:"redirect_uris"`
+	GrantTypes   []string   `json:"grant_types"`
+	Scopes       []string   `json:"scopes"`
+	Public       bool       `json:"public"`
+	SkipConsent  bool       `json:"skip_consent"`
+	CreatedBy    uint       `json:"created_by"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ToOAuthClientDto 将 model.OAuthClient 转换为 OAuthClientDto
+func ToOAuthClientDto(client model.OAuthClient) OAuthClientDto {
+	return OAuthClientDto{
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: splitNonEmpty(client.RedirectURIs, "\n"),
+		GrantTypes:   splitNonEmpty(client.GrantTypes, ","),
+		Scopes:       strings.Fields(client.Scopes),
+		Public:       client.Public,
+		SkipConsent:  client.SkipConsent,
+		CreatedBy:    client.CreatedBy,
+		RevokedAt:    client.RevokedAt,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+// splitNonEmpty 拆分字符串，空字符串返回空切片
+func splitNonEmpty(s, sep string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, sep)
+}
+
+// AuditLogDto 审计日志
+type AuditLogDto struct {
+	ID        uint            `json:"id"`
+	ActorID   uint            `json:"actor_id"`
+	UserID    uint            `json:"user_id"`
+	Action    string          `json:"action"`
+	Detail    json.RawMessage `json:"detail,omitempty"`
+	IP        string          `json:"ip"`
+	UserAgent string          `json:"user_agent"`
+	RequestID string          `json:"request_id"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ToAuditLogDto 将 model.AuditLog 转换为 AuditLogDto
+func ToAuditLogDto(entry model.AuditLog) AuditLogDto {
+	dto := AuditLogDto{
+		ID:        entry.ID,
+		ActorID:   entry.ActorID,
+		UserID:    entry.UserID,
+		Action:    entry.Action,
+		IP:        entry.IP,
+		UserAgent: entry.UserAgent,
+		RequestID: entry.RequestID,
+		CreatedAt: entry.CreatedAt,
+	}
+	if entry.Detail != "" {
+		dto.Detail = json.RawMessage(entry.Detail)
+	}
+	return dto
+}
@@ -0,0 +1,69 @@
+package admin_controller
+
+// 审计日志与代登录
+
+import (
+	"theing/gin-template/common"
+	"theing/gin-template/dto"
+	"theing/gin-template/model"
+	"theing/gin-template/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImpersonateUser 以用户身份签发短期代登录令牌，用于排查用户看到的问题，必须填写原因
+func ImpersonateUser(c *gin.Context) {
+	type PostImpersonate struct {
+		Reason  string `json:"reason" binding:"required,max=255"`
+		Minutes int    `json:"minutes" binding:"omitempty,min=1"` // 有效期（分钟），不填使用默认值
+	}
+	var body PostImpersonate
+	if err := c.ShouldBindJSON(&body); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+
+	token, err := common.StartImpersonation(common.ImpersonationRequest{
+		Actor:       c.MustGet("user").(model.User),
+		ActorClaims: c.MustGet("claims").(*common.Claims),
+		SubjectID:   user.ID,
+		Reason:      body.Reason,
+		Minutes:     body.Minutes,
+		Client:      common.ClientInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()},
+		RequestID:   c.GetString("request_id"),
+	})
+	if err != nil {
+		failWithErr(c, err, "代登录失败")
+		return
+	}
+	response.Success(c, gin.H{
+		"token":      token.Token,
+		"expires_at": token.ExpiresAt,
+		"user":       dto.ToAdminUserDto(token.Subject),
+	}, "代登录令牌已签发")
+}
+
+// ListAuditLogs 分页查询审计日志，支持按操作人、被操作用户和操作类型过滤
+func ListAuditLogs(c *gin.Context) {
+	var query common.AuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	query.Page, query.PageSize = pagination(c)
+
+	logs, total, err := common.ListAuditLogs(query)
+	if err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "查询审计日志失败", err.Error()))
+		return
+	}
+	list := make([]AuditLogDto, 0, len(logs))
+	for _, entry := range logs {
+		list = append(list, ToAuditLogDto(entry))
+	}
+	response.PaginationResponse(c, list, total, query.Page, query.PageSize, "")
+}
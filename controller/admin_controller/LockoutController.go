@@ -0,0 +1,74 @@
+package admin_controller
+
+// 登录锁定记录查询与解锁
+
+import (
+	"strconv"
+	"theing/gin-template/common"
+	"theing/gin-template/model"
+	"theing/gin-template/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListLockoutEvents 分页查询登录锁定记录，支持按 scope、identifier 过滤，active=true 只看仍在锁定中的记录
+func ListLockoutEvents(c *gin.Context) {
+	page, pageSize := pagination(c)
+
+	query := common.GetDB().Model(&model.LockoutEvent{})
+	if scope := c.Query("scope"); scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+	if identifier := c.Query("identifier"); identifier != "" {
+		query = query.Where("identifier = ?", identifier)
+	}
+	if c.Query("active") == "true" {
+		query = query.Where("locked_until > ?", time.Now())
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "查询锁定记录失败", err.Error()))
+		return
+	}
+	var events []model.LockoutEvent
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&events).Error; err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "查询锁定记录失败", err.Error()))
+		return
+	}
+
+	list := make([]LockoutEventDto, 0, len(events))
+	for _, event := range events {
+		list = append(list, ToLockoutEventDto(event))
+	}
+	response.PaginationResponse(c, list, total, page, pageSize, "")
+}
+
+// UnlockLogin 手动解除账号或 IP 的锁定
+func UnlockLogin(c *gin.Context) {
+	var req UnlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+
+	if err := common.UnlockLogin(c.Request.Context(), req.Scope, req.Identifier); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInternalError, "解除锁定失败", err.Error()))
+		return
+	}
+	response.Success(c, nil, "已解除锁定")
+}
+
+// pagination 读取分页参数，page 从 1 开始，page_size 最大 100
+func pagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
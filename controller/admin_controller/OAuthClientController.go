@@ -0,0 +1,61 @@
+package admin_controller
+
+// 第三方应用（OAuth 客户端）管理
+
+import (
+	"theing/gin-template/common"
+	"theing/gin-template/model"
+	"theing/gin-template/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListOAuthClients 第三方应用列表
+func ListOAuthClients(c *gin.Context) {
+	clients, err := common.ListOAuthClients()
+	if err != nil {
+		failWithErr(c, err, "查询第三方应用失败")
+		return
+	}
+	list := make([]OAuthClientDto, 0, len(clients))
+	for _, client := range clients {
+		list = append(list, ToOAuthClientDto(client))
+	}
+	response.Success(c, list, "查询成功")
+}
+
+// CreateOAuthClient 登记第三方应用，客户端密钥只在本次响应中返回
+func CreateOAuthClient(c *gin.Context) {
+	var req CreateOAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+
+	operator := c.MustGet("user").(model.User)
+	client, secret, err := common.RegisterOAuthClient(common.OAuthClientRegistration{
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		GrantTypes:   req.GrantTypes,
+		Scopes:       req.Scopes,
+		Public:       req.Public,
+		SkipConsent:  req.SkipConsent,
+	}, operator.ID)
+	if err != nil {
+		failWithErr(c, err, "登记第三方应用失败")
+		return
+	}
+	response.Success(c, gin.H{
+		"client":        ToOAuthClientDto(*client),
+		"client_secret": secret,
+	}, "登记成功，请妥善保存客户端密钥，之后将无法再次查看")
+}
+
+// RevokeOAuthClient 停用第三方应用，其刷新令牌一并撤销
+func RevokeOAuthClient(c *gin.Context) {
+	if err := common.RevokeOAuthClient(c.Param("client_id")); err != nil {
+		failWithErr(c, err, "停用第三方应用失败")
+		return
+	}
+	response.Success(c, nil, "已停用")
+}
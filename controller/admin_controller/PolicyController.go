@@ -0,0 +1,38 @@
+package admin_controller
+
+// 授权策略查看与试算
+
+import (
+	"theing/gin-template/common"
+	"theing/gin-template/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListPolicies 获取当前生效的策略
+func ListPolicies(c *gin.Context) {
+	response.Success(c, common.GetPolicyEngine().Policies(), "查询成功")
+}
+
+// EvaluatePolicy 试算一次授权请求，返回决定结果的策略以及每条策略的匹配过程
+func EvaluatePolicy(c *gin.Context) {
+	var req EvaluatePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+
+	context := common.DefaultPolicyContext(time.Now(), c.ClientIP())
+	for key, value := range req.Context {
+		context[key] = value
+	}
+
+	decision := common.Authorize(common.AccessRequest{
+		Subject:  req.Subject,
+		Resource: req.Resource,
+		Action:   req.Action,
+		Context:  context,
+	})
+	response.Success(c, decision, "试算完成")
+}
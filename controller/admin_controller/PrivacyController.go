@@ -0,0 +1,32 @@
+package admin_controller
+
+// 个人数据导出与删除申请查询
+
+import (
+	"theing/gin-template/common"
+	"theing/gin-template/dto"
+	"theing/gin-template/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListPrivacyRequests 分页查询个人数据申请，默认只看待处理的，按计划执行时间排序；status=all 查看全部
+func ListPrivacyRequests(c *gin.Context) {
+	page, pageSize := pagination(c)
+	status := c.DefaultQuery("status", common.PrivacyStatusPending)
+	if status == "all" {
+		status = ""
+	}
+
+	requests, total, err := common.ListPrivacyRequests(c.Query("type"), status, page, pageSize)
+	if err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "查询申请失败", err.Error()))
+		return
+	}
+
+	list := make([]dto.PrivacyRequestDto, 0, len(requests))
+	for _, req := range requests {
+		list = append(list, dto.ToPrivacyRequestDto(req))
+	}
+	response.PaginationResponse(c, list, total, page, pageSize, "")
+}
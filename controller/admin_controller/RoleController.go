@@ -0,0 +1,139 @@
+package admin_controller
+
+// 角色与授权管理
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"theing/gin-template/common"
+	"theing/gin-template/model"
+	"theing/gin-template/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListRoles 获取角色列表
+func ListRoles(c *gin.Context) {
+	var roles []model.Role
+	if err := common.GetDB().Preload("Permissions").Order("id").Find(&roles).Error; err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "查询角色失败", err.Error()))
+		return
+	}
+
+	list := make([]RoleDto, 0, len(roles))
+	for _, role := range roles {
+		list = append(list, ToRoleDto(role))
+	}
+	response.Success(c, list, "查询成功")
+}
+
+// CreateRole 创建自定义角色
+func CreateRole(c *gin.Context) {
+	var req CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+
+	role, err := common.CreateRole(req.Name, req.Description, req.Permissions)
+	if err != nil {
+		failWithErr(c, err, "创建角色失败")
+		return
+	}
+	response.Success(c, ToRoleDto(*role), "创建成功")
+}
+
+// ListPermissions 获取权限列表
+func ListPermissions(c *gin.Context) {
+	var permissions []model.Permission
+	if err := common.GetDB().Order("name").Find(&permissions).Error; err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "查询权限失败", err.Error()))
+		return
+	}
+
+	list := make([]PermissionDto, 0, len(permissions))
+	for _, permission := range permissions {
+		list = append(list, PermissionDto{Name: permission.Name, Description: permission.Description})
+	}
+	response.Success(c, list, "查询成功")
+}
+
+// GetUserRoles 获取用户的角色
+func GetUserRoles(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+	response.Success(c, common.RoleNames(user), "查询成功")
+}
+
+// AssignUserRole 为用户授予角色，用户已签发的令牌随即失效
+func AssignUserRole(c *gin.Context) {
+	var req AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+
+	if err := common.AssignRole(user.ID, req.Role); err != nil {
+		failWithErr(c, err, "授予角色失败")
+		return
+	}
+	revokeRoleTokens(c.Request.Context(), user.ID)
+	response.Success(c, nil, "授予成功")
+}
+
+// RemoveUserRole 撤销用户的角色，用户已签发的令牌随即失效
+func RemoveUserRole(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+
+	if err := common.RemoveRole(user.ID, c.Param("role")); err != nil {
+		failWithErr(c, err, "撤销角色失败")
+		return
+	}
+	revokeRoleTokens(c.Request.Context(), user.ID)
+	response.Success(c, nil, "撤销成功")
+}
+
+// findUser 按路径参数 id 查询用户并预加载角色，失败时直接写入响应
+func findUser(c *gin.Context) (model.User, bool) {
+	var user model.User
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "用户ID无效", c.Param("id")))
+		return user, false
+	}
+	if err := common.GetDB().Preload("Roles").Limit(1).Find(&user, id).Error; err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "查询用户失败", err.Error()))
+		return user, false
+	}
+	if user.ID == 0 {
+		response.FailWithError(c, common.ErrUserNotFound)
+		return user, false
+	}
+	return user, true
+}
+
+// revokeRoleTokens 角色变更后撤销用户的访问令牌，令其刷新后获得新的角色
+func revokeRoleTokens(ctx context.Context, userID uint) {
+	if err := common.RevokeUserTokens(ctx, userID); err != nil {
+		log.Printf("撤销用户 %d 的令牌失败: %v", userID, err)
+	}
+}
+
+// failWithErr 输出应用错误，其他错误按数据库错误处理
+func failWithErr(c *gin.Context, err error, msg string) {
+	if appErr, ok := err.(*common.AppError); ok {
+		response.FailWithError(c, appErr)
+		return
+	}
+	response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, msg, err.Error()))
+}
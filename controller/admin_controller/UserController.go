@@ -0,0 +1,182 @@
+package admin_controller
+
+// 用户管理：查询、停用/启用、强制重置密码、删除与批量导入导出
+
+import (
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"theing/gin-template/common"
+	"theing/gin-template/dto"
+	"theing/gin-template/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListUsers 分页查询用户，支持按手机号、用户名、状态和创建日期过滤
+func ListUsers(c *gin.Context) {
+	var query common.UserQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	query.Normalize()
+
+	users, total, err := common.ListUsers(query)
+	if err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, "查询用户失败", err.Error()))
+		return
+	}
+
+	list := make([]dto.AdminUserDto, 0, len(users))
+	for _, user := range users {
+		list = append(list, dto.ToAdminUserDto(user))
+	}
+	response.PaginationResponse(c, list, total, query.Page, query.PageSize, "")
+}
+
+// GetUser 查看用户详情
+func GetUser(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+	response.Success(c, dto.ToAdminUserDto(user), "查询成功")
+}
+
+// DisableUser 停用用户，已登录的设备和 API 密钥立即失效
+func DisableUser(c *gin.Context) {
+	setUserStatus(c, common.UserStatusSuspended, "已停用")
+}
+
+// EnableUser 恢复被停用的用户
+func EnableUser(c *gin.Context) {
+	setUserStatus(c, common.UserStatusActive, "已启用")
+}
+
+// ForcePasswordReset 要求用户修改密码，现有登录全部失效
+func ForcePasswordReset(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+	if err := common.ForcePasswordReset(c.Request.Context(), user.ID); err != nil {
+		failWithErr(c, err, "操作失败")
+		return
+	}
+	response.Success(c, nil, "已要求用户重置密码")
+}
+
+// DeleteUser 删除用户及其登录凭据
+func DeleteUser(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+	if err := common.DeleteUser(c.Request.Context(), user.ID); err != nil {
+		failWithErr(c, err, "删除用户失败")
+		return
+	}
+	response.Success(c, nil, "删除成功")
+}
+
+// setUserStatus 修改路径参数 id 对应用户的状态并返回最新信息
+func setUserStatus(c *gin.Context, status, msg string) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+	if err := common.SetUserStatus(c.Request.Context(), user.ID, status); err != nil {
+		failWithErr(c, err, "修改用户状态失败")
+		return
+	}
+	user.Status = status
+	response.Success(c, dto.ToAdminUserDto(user), msg)
+}
+
+// ImportUsers 通过 CSV 或 XLSX 文件批量导入用户，dry_run=true 时只返回校验结果。
+// 默认只新建用户，update=true 时才修改已存在的用户；通过 roles 列授予角色需要 role:manage 权限
+func ImportUsers(c *gin.Context) {
+	type PostImport struct {
+		File   *multipart.FileHeader `form:"file" binding:"required"`
+		Format string                `form:"format" binding:"omitempty,oneof=csv xlsx"`
+		DryRun bool                  `form:"dry_run"`
+		Update bool                  `form:"update"`
+	}
+	var body PostImport
+	if err := c.ShouldBind(&body); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	format, err := common.UserFileFormat(body.Format, body.File.Filename)
+	if err != nil {
+		failWithErr(c, err, "导入失败")
+		return
+	}
+
+	file, err := body.File.Open()
+	if err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "无法读取文件", err.Error()))
+		return
+	}
+	defer file.Close()
+	rows, err := common.ReadUserImportFile(file, body.File.Size, format)
+	if err != nil {
+		failWithErr(c, err, "导入失败")
+		return
+	}
+
+	claims := c.MustGet("claims").(*common.Claims)
+	permissions, err := common.EffectivePermissions(claims)
+	if err != nil {
+		failWithErr(c, err, "导入失败")
+		return
+	}
+	opts := common.UserImportOptions{
+		DryRun:     body.DryRun,
+		Update:     body.Update,
+		GrantRoles: slices.Contains(permissions, common.PermRoleManage),
+	}
+	report, err := common.ImportUsers(c.Request.Context(), rows, opts)
+	if err != nil {
+		failWithErr(c, err, "导入失败")
+		return
+	}
+	msg := "导入完成"
+	if body.DryRun {
+		msg = "校验完成，未写入数据"
+	}
+	response.Success(c, report, msg)
+}
+
+// ExportUsers 按列表的过滤条件导出用户，format 为 csv（默认）或 xlsx
+func ExportUsers(c *gin.Context) {
+	var query common.UserQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.FailWithError(c, common.NewAppError(common.CodeInvalidParams, "参数错误", err.Error()))
+		return
+	}
+	format, err := common.UserFileFormat(c.DefaultQuery("format", common.UserFileFormatCSV), "")
+	if err != nil {
+		failWithErr(c, err, "导出失败")
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == common.UserFileFormatXLSX {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	filename := fmt.Sprintf("users-%s.%s", time.Now().Format("20060102150405"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	// 数据直接写入响应，开始输出后无法再返回错误信息
+	if err := common.ExportUsers(c.Writer, format, query); err != nil {
+		log.Printf("导出用户失败: %v", err)
+	}
+}
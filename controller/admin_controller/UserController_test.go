@@ -0,0 +1,33 @@
+package admin_controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"theing/gin-template/common"
+	"theing/gin-template/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListUsers_RejectsInvalidQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware())
+	router.GET("/api/admin/users", ListUsers)
+
+	// 排序字段只允许白名单中的列，不会拼进 SQL
+	for _, query := range []string{"sort=password", "sort=id%20desc", "order=up", "status=unknown", "created_from=yesterday"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/users?"+query, nil))
+		var body struct {
+			Code int `json:"code"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, int(common.CodeInvalidParams), body.Code, query)
+	}
+}
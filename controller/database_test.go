@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"path/filepath"
+	"testing"
+
+	"theing/gin-template/common"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useTestDB 使用临时的 SQLite 数据库替换 common.DB，执行全部迁移并初始化内置角色，测试结束后恢复
+func useTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	_, err = common.MigrateUp(db)
+	require.NoError(t, err)
+	require.NoError(t, common.SeedRBAC(db))
+
+	previous := common.DB
+	common.DB = db
+	t.Cleanup(func() {
+		common.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
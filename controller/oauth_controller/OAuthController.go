@@ -0,0 +1,313 @@
+package oauth_controller
+
+// OAuth2 授权服务接口，令牌、自省和撤销接口按 RFC 格式返回
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"theing/gin-template/common"
+	"theing/gin-template/config"
+	"theing/gin-template/model"
+	"theing/gin-template/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authorize 校验授权请求并返回确认页信息，用户之前已同意过这些范围时直接返回跳转地址
+func Authorize(c *gin.Context) {
+	var req common.AuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		oauthFail(c, &common.OAuthError{Code: "invalid_request", Description: err.Error(), Status: http.StatusBadRequest})
+		return
+	}
+	user := c.MustGet("user").(model.User)
+
+	client, scope, ok := validateAuthorize(c, &req)
+	if !ok {
+		return
+	}
+	consentRequired, err := common.OAuthConsentRequired(user.ID, client, scope)
+	if err != nil {
+		oauthFail(c, err)
+		return
+	}
+
+	dto := AuthorizeDto{
+		ClientID:        client.ClientID,
+		ClientName:      client.Name,
+		Scopes:          common.OAuthScopes(scope),
+		ConsentRequired: consentRequired,
+	}
+	if !consentRequired {
+		code, err := common.ApproveAuthorization(user.ID, client, &req, scope)
+		if err != nil {
+			oauthFail(c, err)
+			return
+		}
+		dto.RedirectTo = common.BuildRedirectURI(req.RedirectURI, map[string]string{"code": code, "state": req.State})
+	}
+	response.Success(c, dto, "")
+}
+
+// Consent 用户同意或拒绝授权，返回应跳转的回调地址
+func Consent(c *gin.Context) {
+	var req ConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		oauthFail(c, &common.OAuthError{Code: "invalid_request", Description: err.Error(), Status: http.StatusBadRequest})
+		return
+	}
+	user := c.MustGet("user").(model.User)
+
+	client, scope, ok := validateAuthorize(c, &req.AuthorizeRequest)
+	if !ok {
+		return
+	}
+	if !req.Approve {
+		response.Success(c, gin.H{"redirect_to": common.BuildRedirectURI(req.RedirectURI, map[string]string{
+			"error":             "access_denied",
+			"error_description": "用户拒绝授权",
+			"state":             req.State,
+		})}, "")
+		return
+	}
+
+	code, err := common.ApproveAuthorization(user.ID, client, &req.AuthorizeRequest, scope)
+	if err != nil {
+		oauthFail(c, err)
+		return
+	}
+	response.Success(c, gin.H{"redirect_to": common.BuildRedirectURI(req.RedirectURI, map[string]string{
+		"code":  code,
+		"state": req.State,
+	})}, "已授权")
+}
+
+// Token 令牌接口（RFC 6749 第 4、6 节），支持授权码、客户端凭证和刷新令牌
+func Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, ok := authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	var (
+		result *common.OAuthTokenResponse
+		err    error
+	)
+	switch grantType := c.PostForm("grant_type"); grantType {
+	case common.GrantAuthorizationCode:
+		result, err = common.ExchangeAuthorizationCode(client, c.PostForm("code"), c.PostForm("redirect_uri"), c.PostForm("code_verifier"))
+	case common.GrantClientCredentials:
+		result, err = common.ClientCredentialsToken(client, c.PostForm("scope"))
+	case common.GrantRefreshToken:
+		result, err = common.RefreshOAuthToken(client, c.PostForm("refresh_token"), c.PostForm("scope"))
+	case "":
+		err = &common.OAuthError{Code: "invalid_request", Description: "缺少 grant_type", Status: http.StatusBadRequest}
+	default:
+		err = &common.OAuthError{Code: "unsupported_grant_type", Description: "不支持的授权方式: " + grantType, Status: http.StatusBadRequest}
+	}
+	if err != nil {
+		oauthFail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// Introspect 令牌自省（RFC 7662），只允许机密客户端调用
+func Introspect(c *gin.Context) {
+	client, ok := authenticateClient(c)
+	if !ok {
+		return
+	}
+	if client.Public {
+		oauthFail(c, &common.OAuthError{Code: "unauthorized_client", Description: "公开客户端不能自省令牌", Status: http.StatusForbidden})
+		return
+	}
+	token := c.PostForm("token")
+	if token == "" {
+		oauthFail(c, &common.OAuthError{Code: "invalid_request", Description: "缺少 token", Status: http.StatusBadRequest})
+		return
+	}
+
+	result, err := common.IntrospectToken(c.Request.Context(), client, token)
+	if err != nil {
+		oauthFail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// Revoke 撤销令牌（RFC 7009），令牌不存在或不属于该客户端时同样返回 200
+func Revoke(c *gin.Context) {
+	client, ok := authenticateClient(c)
+	if !ok {
+		return
+	}
+	token := c.PostForm("token")
+	if token == "" {
+		oauthFail(c, &common.OAuthError{Code: "invalid_request", Description: "缺少 token", Status: http.StatusBadRequest})
+		return
+	}
+
+	if err := common.RevokeOAuthToken(c.Request.Context(), client, token); err != nil {
+		oauthFail(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// UserInfo 按访问令牌的授权范围返回用户信息
+func UserInfo(c *gin.Context) {
+	claims := c.MustGet("claims").(*common.Claims)
+	if claims.UserId == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient_scope", "error_description": "客户端凭证令牌不代表用户"})
+		return
+	}
+
+	info, err := common.OAuthUserInfo(claims)
+	if err != nil {
+		oauthFail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// Metadata 授权服务元数据（RFC 8414）
+func Metadata(c *gin.Context) {
+	issuer := issuerURL(c)
+	c.JSON(http.StatusOK, gin.H{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth/authorize",
+		"token_endpoint":                        issuer + "/oauth/token",
+		"introspection_endpoint":                issuer + "/oauth/introspect",
+		"revocation_endpoint":                   issuer + "/oauth/revoke",
+		"userinfo_endpoint":                     issuer + "/oauth/userinfo",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{common.GrantAuthorizationCode, common.GrantClientCredentials, common.GrantRefreshToken},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{common.OAuthScopeProfile, common.OAuthScopePhone},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+	})
+}
+
+// ListConsents 当前用户授权过的第三方应用
+func ListConsents(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+
+	consents, err := common.ListOAuthConsents(user.ID)
+	if err != nil {
+		failWithErr(c, err, "查询授权记录失败")
+		return
+	}
+	clientIDs := make([]string, 0, len(consents))
+	for _, consent := range consents {
+		clientIDs = append(clientIDs, consent.ClientID)
+	}
+	var clients []model.OAuthClient
+	if err := common.GetDB().Where("client_id IN ?", clientIDs).Find(&clients).Error; err != nil {
+		failWithErr(c, err, "查询授权记录失败")
+		return
+	}
+	names := make(map[string]string, len(clients))
+	for _, client := range clients {
+		names[client.ClientID] = client.Name
+	}
+
+	list := make([]ConsentDto, 0, len(consents))
+	for _, consent := range consents {
+		list = append(list, ToConsentDto(consent, names[consent.ClientID]))
+	}
+	response.Success(c, list, "")
+}
+
+// RevokeConsent 取消对第三方应用的授权
+func RevokeConsent(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+	if err := common.RevokeOAuthConsent(user.ID, c.Param("client_id")); err != nil {
+		failWithErr(c, err, "取消授权失败")
+		return
+	}
+	response.Success(c, nil, "已取消授权")
+}
+
+// validateAuthorize 校验授权请求，可以安全重定向的错误通过 redirect_to 返回给前端
+func validateAuthorize(c *gin.Context, req *common.AuthorizeRequest) (*model.OAuthClient, string, bool) {
+	client, scope, redirectable, err := common.ValidateAuthorizeRequest(req)
+	if err == nil {
+		return client, scope, true
+	}
+	oauthErr, isOAuthErr := err.(*common.OAuthError)
+	if !isOAuthErr || !redirectable {
+		oauthFail(c, err)
+		return nil, "", false
+	}
+	c.JSON(oauthErr.Status, gin.H{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Description,
+		"redirect_to": common.BuildRedirectURI(req.RedirectURI, map[string]string{
+			"error":             oauthErr.Code,
+			"error_description": oauthErr.Description,
+			"state":             req.State,
+		}),
+	})
+	return nil, "", false
+}
+
+// authenticateClient 从 HTTP Basic 或表单参数中读取客户端凭证（RFC 6749 2.3.1）
+func authenticateClient(c *gin.Context) (*model.OAuthClient, bool) {
+	clientID, secret, hasBasic := c.Request.BasicAuth()
+	if hasBasic {
+		// Basic 认证中的凭证经过了 form 编码
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = c.PostForm("client_id")
+		secret = c.PostForm("client_secret")
+	}
+
+	client, err := common.AuthenticateOAuthClient(clientID, secret)
+	if err != nil {
+		if hasBasic {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		oauthFail(c, err)
+		return nil, false
+	}
+	return client, true
+}
+
+// oauthFail 按 RFC 6749 格式返回错误，非协议错误记录日志后返回 server_error
+func oauthFail(c *gin.Context, err error) {
+	if oauthErr, ok := err.(*common.OAuthError); ok {
+		c.JSON(oauthErr.Status, oauthErr)
+		return
+	}
+	log.Printf("OAuth 请求处理失败: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+}
+
+// failWithErr 面向本站前端的接口使用统一响应格式
+func failWithErr(c *gin.Context, err error, msg string) {
+	if appErr, ok := err.(*common.AppError); ok {
+		response.FailWithError(c, appErr)
+		return
+	}
+	response.FailWithError(c, common.NewAppError(common.CodeDatabaseError, msg, err.Error()))
+}
+
+// issuerURL 授权服务地址，未配置 oauth.issuer 时按请求推断
+func issuerURL(c *gin.Context) string {
+	if issuer := config.Get().OAuth.Issuer; issuer != "" {
+		return strings.TrimSuffix(issuer, "/")
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
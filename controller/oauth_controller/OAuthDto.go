@@ -0,0 +1,43 @@
+package oauth_controller
+
+import (
+	"strings"
+	"theing/gin-template/common"
+	"theing/gin-template/model"
+	"time"
+)
+
+// ConsentRequest 用户在授权确认页的选择
+type ConsentRequest struct {
+	common.AuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+// AuthorizeDto 授权确认页需要展示的信息
+type AuthorizeDto struct {
+	ClientID        string              `json:"client_id"`
+	ClientName      string              `json:"client_name"`
+	Scopes          []common.OAuthScope `json:"scopes"`
+	ConsentRequired bool                `json:"consent_required"`
+	RedirectTo      string              `json:"redirect_to,omitempty"` // 无需确认时直接跳转的地址
+}
+
+// ConsentDto 用户授权过的应用
+type ConsentDto struct {
+	ClientID   string              `json:"client_id"`
+	ClientName string              `json:"client_name"`
+	Scopes     []common.OAuthScope `json:"scopes"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+// ToConsentDto 将 model.OAuthConsent 转换为 ConsentDto
+func ToConsentDto(consent model.OAuthConsent, clientName string) ConsentDto {
+	return ConsentDto{
+		ClientID:   consent.ClientID,
+		ClientName: clientName,
+		Scopes:     common.OAuthScopes(strings.TrimSpace(consent.Scope)),
+		CreatedAt:  consent.CreatedAt,
+		UpdatedAt:  consent.UpdatedAt,
+	}
+}
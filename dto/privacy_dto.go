@@ -0,0 +1,34 @@
+package dto
+
+import (
+	"theing/gin-template/model"
+	"time"
+)
+
+// PrivacyRequestDto 个人数据导出或删除申请，不包含导出文件在服务器上的路径
+type PrivacyRequestDto struct {
+	ID          uint       `json:"id"`
+	UserID      uint       `json:"user_id"`
+	Type        string     `json:"type"`
+	Status      string     `json:"status"`
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ToPrivacyRequestDto 将 model.PrivacyRequest 转换为 PrivacyRequestDto
+func ToPrivacyRequestDto(req model.PrivacyRequest) PrivacyRequestDto {
+	return PrivacyRequestDto{
+		ID:          req.ID,
+		UserID:      req.UserID,
+		Type:        req.Type,
+		Status:      req.Status,
+		ScheduledAt: req.ScheduledAt,
+		ExpiresAt:   req.ExpiresAt,
+		Error:       req.Error,
+		CompletedAt: req.CompletedAt,
+		CreatedAt:   req.CreatedAt,
+	}
+}
@@ -1,11 +1,16 @@
 package dto
 
-import "theing/gin-template/model"
+import (
+	"theing/gin-template/model"
+	"time"
+)
 
 // UserDTO 用户数据传输对象，相当于是一个格式的定义和转换。
 type UserDto struct {
 	Name      string `json:"name"`
 	Telephone string `json:"telephone"`
+	Avatar    string `json:"avatar"`
+	Bio       string `json:"bio"`
 }
 
 // 转换的函数 将model.User转换为UserDto
@@ -13,5 +18,51 @@ func ToUserDto(user model.User) UserDto {
 	return UserDto{ // 返回一个新的UserDto格式
 		Name:      user.Username,
 		Telephone: user.Telephone,
+		Avatar:    user.Avatar,
+		Bio:       user.Bio,
+	}
+}
+
+// AdminUserDto 管理后台使用的用户信息，不包含密码等敏感字段
+type AdminUserDto struct {
+	ID                    uint       `json:"id"`
+	Name                  string     `json:"name"`
+	Telephone             string     `json:"telephone"`
+	Avatar                string     `json:"avatar"`
+	Bio                   string     `json:"bio"`
+	Status                string     `json:"status"`
+	Roles                 []string   `json:"roles"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	PhoneVerifiedAt       *time.Time `json:"phone_verified_at"`
+	PasswordChangedAt     *time.Time `json:"password_changed_at"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+	DeletedAt             *time.Time `json:"deleted_at,omitempty"`
+}
+
+// ToAdminUserDto 将 model.User 转换为 AdminUserDto，角色需要预加载
+func ToAdminUserDto(user model.User) AdminUserDto {
+	roles := make([]string, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		roles = append(roles, role.Name)
+	}
+	var deletedAt *time.Time
+	if user.DeletedAt.Valid {
+		deletedAt = &user.DeletedAt.Time
+	}
+	return AdminUserDto{
+		ID:                    user.ID,
+		Name:                  user.Username,
+		Telephone:             user.Telephone,
+		Avatar:                user.Avatar,
+		Bio:                   user.Bio,
+		Status:                user.Status,
+		Roles:                 roles,
+		PasswordResetRequired: user.PasswordResetRequired,
+		PhoneVerifiedAt:       user.PhoneVerifiedAt,
+		PasswordChangedAt:     user.PasswordChangedAt,
+		CreatedAt:             user.CreatedAt,
+		UpdatedAt:             user.UpdatedAt,
+		DeletedAt:             deletedAt,
 	}
 }
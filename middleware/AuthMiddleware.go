@@ -3,7 +3,9 @@ package middleware
 // 认证中间件
 
 import (
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"theing/gin-template/common"
 	"theing/gin-template/model"
@@ -17,6 +19,15 @@ func AuthMiddleware() gin.HandlerFunc {
 		// 获取 authorization header
 		tokenString := c.GetHeader("Authorization") // ! 这里应该也可以获取header中的其他的字段
 
+		// 机器客户端可以使用 API 密钥：Authorization: ApiKey xxx 或 X-API-Key: xxx
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" || strings.HasPrefix(tokenString, "ApiKey ") {
+			if apiKey == "" {
+				apiKey = tokenString[len("ApiKey "):]
+			}
+			apiKeyAuth(c, apiKey)
+			return
+		}
+
 		// validate token formate
 		// 如果为空或者不是以Bearer开头，那就是没有token，或者说是错误的token，返回权限不足
 		if tokenString == "" || !strings.HasPrefix(tokenString, "Bearer ") {
@@ -30,15 +41,39 @@ func AuthMiddleware() gin.HandlerFunc {
 		// 解析token ，函数卸载jwt中
 		token, claims, err := common.ParseToken(tokenString) // 通过包进行引用这个方法。
 		if err != nil || !token.Valid {                      // 如果解析失败，或者解析后的token无效， || !token.Valid 表示或者token是无效的。
+			common.GetMetrics().RecordJWTValidationError("invalid")
 			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "权限不足"})
 			c.Abort() // 抛弃这一次的请求。
 			return
 		}
+
+		// 两步验证临时令牌等特殊用途的令牌不能访问普通接口
+		if claims.TokenType != "" {
+			common.GetMetrics().RecordJWTValidationError("wrong_type")
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "权限不足"})
+			c.Abort()
+			return
+		}
+
+		// 检查token是否已被撤销（退出登录或退出所有设备）
+		revoked, err := common.IsTokenRevoked(c.Request.Context(), claims)
+		if err != nil {
+			log.Printf("查询token撤销状态失败: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"code": int(common.CodeServiceUnavailable), "msg": "认证服务暂不可用"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			common.GetMetrics().RecordJWTValidationError("revoked")
+			c.JSON(http.StatusUnauthorized, gin.H{"code": int(common.CodeTokenRevoked), "msg": "Token已被撤销"})
+			c.Abort()
+			return
+		}
 		// 验证通过,获取token中的userid
 		userId := claims.UserId
 		DB := common.GetDB()
 		var user model.User
-		DB.First(&user, userId)
+		DB.Unscoped().First(&user, userId) // 包含已注销的用户，以便返回具体原因
 
 		// 验证用户，如果用户不存在
 		if user.ID == 0 {
@@ -46,12 +81,158 @@ func AuthMiddleware() gin.HandlerFunc {
 			c.Abort() // 抛弃这一次的请求。
 			return
 		}
+		// 待激活、已停用或已注销的用户不能访问
+		if rejectInactiveUser(c, user) {
+			return
+		}
+
+		// 代登录令牌要求发起的管理员仍为正常状态。代登录使用的是管理员自己的凭据，不受用户密码过期的限制
+		impersonated := common.IsImpersonation(claims)
+		if impersonated && !startImpersonatedRequest(c, claims) {
+			return
+		}
+		if !impersonated && rejectExpiredPassword(c, user) {
+			return
+		}
+
+		// 记录会话最近活跃时间
+		if claims.SessionID != "" {
+			common.TouchSession(claims.SessionID)
+		}
 
 		// 如果用户存在，将user的信息写入上下文。
 		c.Set("user", user) // 自己理解为相当于写入缓存中，为登录状态了。
+		c.Set("claims", claims)
 		c.Next()
 
+		if impersonated {
+			auditImpersonatedRequest(c, claims)
+		}
+
 		// 接下来就要创建一个用户获取用户信息的路由
 	}
 
 }
+
+// apiKeyAuth 使用 API 密钥认证，通过后与 Bearer 令牌一样写入 user 和 claims
+func apiKeyAuth(c *gin.Context, rawKey string) {
+	key, err := common.AuthenticateAPIKey(strings.TrimSpace(rawKey), c.ClientIP())
+	if err != nil {
+		if err == common.ErrAPIKeyInvalid {
+			common.GetMetrics().RecordJWTValidationError("api_key_invalid")
+			c.JSON(http.StatusUnauthorized, gin.H{"code": int(common.CodeAPIKeyInvalid), "msg": "API密钥无效"})
+		} else {
+			log.Printf("校验API密钥失败: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"code": int(common.CodeServiceUnavailable), "msg": "认证服务暂不可用"})
+		}
+		c.Abort()
+		return
+	}
+
+	var user model.User
+	common.GetDB().Unscoped().Preload("Roles").First(&user, key.UserID)
+	if user.ID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "权限不足"})
+		c.Abort()
+		return
+	}
+	if rejectInactiveUser(c, user) || rejectExpiredPassword(c, user) {
+		return
+	}
+
+	c.Set("user", user)
+	c.Set("claims", common.APIKeyClaims(user, key))
+	c.Next()
+}
+
+// rejectInactiveUser 账号不是正常状态时终止请求，data.status 为账号状态
+func rejectInactiveUser(c *gin.Context, user model.User) bool {
+	err := common.CheckUserActive(user)
+	if err == nil {
+		return false
+	}
+	appErr := err.(*common.AppError)
+	c.JSON(http.StatusForbidden, gin.H{"code": int(appErr.Code), "msg": appErr.Message, "data": appErr.Data})
+	c.Abort()
+	return true
+}
+
+// passwordChangeRoutes 密码过期或被要求重置时仍然可以访问的接口：修改密码和退出登录
+var passwordChangeRoutes = map[string]bool{
+	"/api/auth/password":   true,
+	"/api/auth/logout":     true,
+	"/api/auth/logout-all": true,
+}
+
+// rejectExpiredPassword 密码超过有效期或被管理员要求重置时，除修改密码和退出登录外的请求一律拒绝
+func rejectExpiredPassword(c *gin.Context, user model.User) bool {
+	if !common.PasswordExpired(user) || passwordChangeRoutes[c.FullPath()] {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"code": int(common.CodePasswordExpired), "msg": "密码已过期，请先修改密码"})
+	c.Abort()
+	return true
+}
+
+// startImpersonatedRequest 校验代登录的发起人仍然有代登录权限，并在响应头和上下文中标明代登录
+func startImpersonatedRequest(c *gin.Context, claims *common.Claims) bool {
+	if err := common.CheckImpersonator(claims.ActorID); err != nil {
+		if _, ok := err.(*common.AppError); !ok {
+			log.Printf("校验代登录发起人失败: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"code": int(common.CodeServiceUnavailable), "msg": "权限服务暂不可用"})
+			c.Abort()
+			return false
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "权限不足"})
+		c.Abort()
+		return false
+	}
+	c.Set("impersonator_id", claims.ActorID)
+	c.Header("X-Impersonator-ID", strconv.FormatUint(uint64(claims.ActorID), 10))
+	c.Header("X-Impersonated-User-ID", strconv.FormatUint(uint64(claims.UserId), 10))
+	return true
+}
+
+// auditImpersonatedRequest 代登录期间的每个请求都写入审计日志
+func auditImpersonatedRequest(c *gin.Context, claims *common.Claims) {
+	err := common.RecordAudit(common.AuditEntry{
+		ActorID: claims.ActorID,
+		UserID:  claims.UserId,
+		Action:  common.AuditImpersonationRequest,
+		Detail: map[string]interface{}{
+			"method": c.Request.Method,
+			"path":   c.Request.URL.Path,
+			"status": c.Writer.Status(),
+			"jti":    claims.Id,
+		},
+		Client:    common.ClientInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()},
+		RequestID: c.GetString("request_id"),
+	})
+	if err != nil {
+		log.Printf("写入代登录审计日志失败: %v", err)
+	}
+}
+
+// DenyImpersonation 拒绝代登录令牌访问，用于修改密码、两步验证、API 密钥等敏感操作，需要放在 AuthMiddleware 之后
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, ok := currentClaims(c); ok && common.IsImpersonation(claims) {
+			c.JSON(http.StatusForbidden, gin.H{"code": int(common.CodeForbidden), "msg": "代登录时不能执行该操作"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// DenyAPIKey 拒绝使用 API 密钥访问，用于密钥管理、两步验证等只允许交互式登录的接口，需要放在 AuthMiddleware 之后
+func DenyAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, ok := currentClaims(c); ok && claims.APIKeyID != 0 {
+			c.JSON(http.StatusForbidden, gin.H{"code": int(common.CodeForbidden), "msg": "该接口不支持使用API密钥访问"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"theing/gin-template/common"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDenyImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	request := func(claims *common.Claims) int {
+		router := gin.New()
+		router.POST("/api/auth/password", func(c *gin.Context) {
+			c.Set("claims", claims)
+			c.Next()
+		}, DenyImpersonation(), func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/auth/password", nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, request(&common.Claims{UserId: 7}))
+	// 代登录令牌不能执行敏感操作
+	assert.Equal(t, http.StatusForbidden, request(&common.Claims{UserId: 7, ActorID: 1}))
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"runtime/debug"
 	"theing/gin-template/common"
@@ -28,6 +29,8 @@ type ErrorResponseStruct struct {
 	RequestID string `json:"request_id"`         // 请求ID
 	Timestamp int64  `json:"timestamp"`          // 时间戳
 	TraceID   string `json:"trace_id,omitempty"` // 追踪ID
+
+	Data interface{} `json:"data,omitempty"` // 结构化的错误数据
 }
 
 // ErrorHandlingMiddleware 全局错误处理中间件
@@ -83,23 +86,40 @@ func RequestIDMiddleware() gin.HandlerFunc {
 	}
 }
 
-// LoggingMiddleware 日志中间件
+// LoggingMiddleware 访问日志，按 logging 配置的格式输出，5xx 响应记为 error，4xx 记为 warn
 func LoggingMiddleware() gin.HandlerFunc {
-	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		requestID, _ := param.Keys["request_id"].(string)
-		return fmt.Sprintf("[%s] %s - [%s] \"%s %s %s %d %s \"%s\" %s\"\n",
-			time.Now().Format("2006-01-02 15:04:05"),
-			requestID,
-			param.ClientIP,
-			param.Method,
-			param.Path,
-			param.Request.Proto,
-			param.StatusCode,
-			param.Latency,
-			param.Request.UserAgent(),
-			param.ErrorMessage,
-		)
-	})
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		attrs := []slog.Attr{
+			slog.String("request_id", c.GetString("request_id")),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("method", c.Request.Method),
+			slog.String("path", path),
+			slog.String("proto", c.Request.Proto),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+		if message := c.Errors.ByType(gin.ErrorTypePrivate).String(); message != "" {
+			attrs = append(attrs, slog.String("error", message))
+		}
+		// 代登录的请求标明发起的管理员
+		if actorID, ok := c.Get("impersonator_id"); ok {
+			attrs = append(attrs, slog.Any("impersonated_by", actorID))
+		}
+		slog.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
 }
 
 // SuccessResponse 成功响应
@@ -131,6 +151,12 @@ func ErrorResponse(c *gin.Context, appErr *common.AppError) {
 		RequestID: requestID.(string),
 		Timestamp: time.Now().Unix(),
 		TraceID:   generateTraceID(),
+		Data:      appErr.Data,
+	}
+
+	// 限流类错误的 Details 为需要等待的秒数
+	if appErr.HTTPStatus == http.StatusTooManyRequests && appErr.Details != "" {
+		c.Header("Retry-After", appErr.Details)
 	}
 
 	c.JSON(appErr.HTTPStatus, response)
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"log"
 	"runtime"
 	"time"
 
@@ -50,6 +51,12 @@ func MetricsMiddleware() gin.HandlerFunc {
 // MetricsHandler Prometheus 指标处理器
 func MetricsHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// 活跃会话数在采集时从会话表统计
+		if common.DB != nil {
+			if err := common.UpdateSessionMetrics(); err != nil {
+				log.Printf("统计活跃会话失败: %v", err)
+			}
+		}
 		promhttp.Handler().ServeHTTP(c.Writer, c.Request)
 	}
 }
@@ -0,0 +1,52 @@
+package middleware
+
+// 基于属性的授权中间件，需要放在 AuthMiddleware 之后
+
+import (
+	"log"
+	"net/http"
+	"theing/gin-template/common"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePolicy 按策略判断当前用户能否对资源执行动作，路由中的路径参数会作为资源属性
+func RequirePolicy(action, resourceType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resource := map[string]interface{}{"type": resourceType}
+		for _, param := range c.Params {
+			resource[param.Key] = param.Value
+		}
+
+		req, ok := BuildAccessRequest(c, action, resource)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"code": int(common.CodeForbidden), "msg": "权限不足"})
+			c.Abort()
+			return
+		}
+
+		decision := common.Authorize(req)
+		if !decision.Allowed {
+			log.Printf("策略拒绝: user=%v action=%s resource=%v reason=%s", req.Subject["id"], action, resource, decision.Reason)
+			c.JSON(http.StatusForbidden, gin.H{"code": int(common.CodeForbidden), "msg": "权限不足"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// BuildAccessRequest 由当前请求构造授权请求，供控制器调用 common.Authorize 使用
+func BuildAccessRequest(c *gin.Context, action string, resource map[string]interface{}) (common.AccessRequest, bool) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return common.AccessRequest{}, false
+	}
+	return common.AccessRequest{
+		Subject:  common.SubjectFromClaims(claims),
+		Resource: resource,
+		Action:   action,
+		Context:  common.DefaultPolicyContext(time.Now(), c.ClientIP()),
+	}, true
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"theing/gin-template/common"
+	"theing/gin-template/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useConfig 临时修改全局配置，测试结束后恢复
+func useConfig(t *testing.T, update func(cfg *config.Config)) {
+	previous := config.Get()
+	cfg := *previous
+	update(&cfg)
+	config.Set(&cfg)
+	t.Cleanup(func() { config.Set(previous) })
+}
+
+// serveWithClaims 以指定 claims 经过中间件请求一次，返回状态码
+func serveWithClaims(claims *common.Claims, guard gin.HandlerFunc) int {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/admin/users", func(c *gin.Context) {
+		if claims != nil {
+			c.Set("claims", claims)
+		}
+		c.Next()
+	}, guard, func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/users", nil))
+	return w.Code
+}
+
+func TestRequireMFA_FollowsReload(t *testing.T) {
+	guard := RequireMFA()
+	password := &common.Claims{UserId: 1, AMR: []string{common.AMRPassword}}
+	otp := &common.Claims{UserId: 1, AMR: []string{common.AMRPassword, common.AMROTP}}
+
+	useConfig(t, func(cfg *config.Config) { cfg.MFA.RequireAdmin = false })
+	assert.Equal(t, http.StatusNoContent, serveWithClaims(password, guard))
+
+	// 同一个中间件实例在配置热更新后立即生效
+	useConfig(t, func(cfg *config.Config) { cfg.MFA.RequireAdmin = true })
+	assert.Equal(t, http.StatusForbidden, serveWithClaims(password, guard))
+	assert.Equal(t, http.StatusNoContent, serveWithClaims(otp, guard))
+}
+
+func TestRequireRole(t *testing.T) {
+	useTestDB(t)
+	admin := &common.Claims{UserId: 1, Roles: []string{common.RoleAdmin}}
+	user := &common.Claims{UserId: 2, Roles: []string{common.RoleUser}}
+
+	assert.Equal(t, http.StatusNoContent, serveWithClaims(admin, RequireRole(common.RoleAdmin)))
+	assert.Equal(t, http.StatusForbidden, serveWithClaims(user, RequireRole(common.RoleAdmin)))
+	// 拥有任一角色即可
+	assert.Equal(t, http.StatusNoContent, serveWithClaims(user, RequireRole(common.RoleAdmin, common.RoleUser)))
+	// 未经过 AuthMiddleware 的请求一律拒绝
+	assert.Equal(t, http.StatusForbidden, serveWithClaims(nil, RequireRole(common.RoleUser)))
+
+	// API 密钥只有授权范围覆盖角色全部权限时才算拥有该角色
+	apiKey := &common.Claims{UserId: 1, Roles: []string{common.RoleAdmin}, APIKeyID: 5, Scopes: []string{common.PermUserRead}}
+	assert.Equal(t, http.StatusForbidden, serveWithClaims(apiKey, RequireRole(common.RoleAdmin)))
+}
+
+func TestRequirePermission(t *testing.T) {
+	db := useTestDB(t)
+	admin := &common.Claims{UserId: 1, Roles: []string{common.RoleAdmin}}
+	user := &common.Claims{UserId: 2, Roles: []string{common.RoleUser}}
+
+	assert.Equal(t, http.StatusNoContent, serveWithClaims(admin, RequirePermission(common.PermUserRead, common.PermUserWrite)))
+	assert.Equal(t, http.StatusForbidden, serveWithClaims(user, RequirePermission(common.PermUserRead)))
+	// 内置的管理员角色默认没有代登录权限
+	assert.Equal(t, http.StatusForbidden, serveWithClaims(admin, RequirePermission(common.PermUserImpersonate)))
+	assert.Equal(t, http.StatusForbidden, serveWithClaims(nil, RequirePermission(common.PermUserRead)))
+
+	// 自定义角色按授予的权限判断
+	_, err := common.CreateRole("auditor", "", []string{common.PermPolicyRead})
+	require.NoError(t, err)
+	auditor := &common.Claims{UserId: 3, Roles: []string{"auditor"}}
+	assert.Equal(t, http.StatusNoContent, serveWithClaims(auditor, RequirePermission(common.PermPolicyRead)))
+	assert.Equal(t, http.StatusForbidden, serveWithClaims(auditor, RequirePermission(common.PermPolicyRead, common.PermUserRead)))
+
+	// API 密钥的权限是角色权限与授权范围的交集
+	apiKey := &common.Claims{UserId: 1, Roles: []string{common.RoleAdmin}, APIKeyID: 5, Scopes: []string{common.PermUserRead}}
+	assert.Equal(t, http.StatusNoContent, serveWithClaims(apiKey, RequirePermission(common.PermUserRead)))
+	assert.Equal(t, http.StatusForbidden, serveWithClaims(apiKey, RequirePermission(common.PermUserWrite)))
+
+	// 查询权限失败时返回 503，而不是当作没有权限
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+	assert.Equal(t, http.StatusServiceUnavailable, serveWithClaims(admin, RequirePermission(common.PermUserRead)))
+	assert.Equal(t, http.StatusServiceUnavailable, serveWithClaims(apiKey, RequireRole(common.RoleAdmin)))
+}
@@ -4,92 +4,115 @@ import (
 	"context"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"theing/gin-template/common"
+	"theing/gin-template/config"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
 )
 
 // SecurityConfig 安全配置
 type SecurityConfig struct {
-	EnableCORS          bool          `json:"enable_cors"`
-	EnableRateLimit     bool          `json:"enable_rate_limit"`
-	MaxRequestsPerMin   int           `json:"max_requests_per_min"`
-	EnableCSRF          bool          `json:"enable_csrf"`
-	EnableSecureHeaders bool          `json:"enable_secure_headers"`
-	TrustedProxies      []string      `json:"trusted_proxies"`
-	RequestTimeout      time.Duration `json:"request_timeout"`
+	EnableCORS            bool          `json:"enable_cors"`
+	EnableRateLimit       bool          `json:"enable_rate_limit"`
+	MaxRequestsPerMin     int           `json:"max_requests_per_min"`
+	EnableSecureHeaders   bool          `json:"enable_secure_headers"`
+	TrustedProxies        []string      `json:"trusted_proxies"`
+	RequestTimeout        time.Duration `json:"request_timeout"`
+	ContentSecurityPolicy string        `json:"content_security_policy"`
+
+	CORS config.CORSConfig `json:"cors"`
 }
 
-// SecurityMiddleware 安全中间件
-func SecurityMiddleware(config SecurityConfig) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 设置安全头部
-		if config.EnableSecureHeaders {
-			setSecurityHeaders(c)
-		}
+// NewSecurityConfig 由应用配置中的 security 部分生成安全中间件配置。
+// security.trusted_proxies 是反向代理列表，在启动时交给 gin，这里不作为客户端白名单
+func NewSecurityConfig(cfg *config.Config) SecurityConfig {
+	security := cfg.Security
+	return SecurityConfig{
+		EnableCORS:            len(security.CORS.AllowedOrigins) > 0,
+		EnableRateLimit:       security.RateLimit.Enabled,
+		MaxRequestsPerMin:     security.RateLimit.RequestsPerMinute,
+		EnableSecureHeaders:   true,
+		ContentSecurityPolicy: security.ContentSecurityPolicy,
+		CORS:                  security.CORS,
+	}
+}
 
-		// CORS 处理
-		if config.EnableCORS {
-			handleCORS(c)
-		}
+// SecurityMiddleware 安全中间件，按应用配置设置安全头部和跨域，配置重新加载后自动生效
+func SecurityMiddleware() gin.HandlerFunc {
+	var current atomic.Pointer[SecurityConfig]
+	apply := func(cfg *config.Config) {
+		securityConfig := NewSecurityConfig(cfg)
+		current.Store(&securityConfig)
+	}
+	apply(config.Get())
+	config.Subscribe("security", func(_, cfg *config.Config) { apply(cfg) })
 
-		// 请求超时处理
-		if config.RequestTimeout > 0 {
-			ctx, cancel := context.WithTimeout(c.Request.Context(), config.RequestTimeout)
-			defer cancel()
-			c.Request = c.Request.WithContext(ctx)
-		}
+	return func(c *gin.Context) {
+		applySecurity(c, current.Load())
+	}
+}
 
-		// IP 白名单检查
-		if len(config.TrustedProxies) > 0 {
-			clientIP := c.ClientIP()
-			if !isTrustedIP(clientIP, config.TrustedProxies) {
-				appErr := common.NewAppError(common.CodeUnauthorized, "IP地址不被信任", "")
-				c.JSON(http.StatusForbidden, appErr)
-				c.Abort()
-				return
-			}
-		}
+// applySecurity 按安全配置处理请求
+func applySecurity(c *gin.Context, security *SecurityConfig) {
+	// 设置安全头部
+	if security.EnableSecureHeaders {
+		setSecurityHeaders(c, security.ContentSecurityPolicy)
+	}
 
-		c.Next()
+	// CORS 处理，预检请求在这里结束
+	if security.EnableCORS && handleCORS(c, security.CORS) {
+		return
 	}
-}
 
-// RateLimitMiddleware 限流中间件
-func RateLimitMiddleware(maxRequests int, window time.Duration) gin.HandlerFunc {
-	// 简化的限流实现，生产环境建议使用 Redis 或专门的服务
-	clients := make(map[string][]time.Time)
+	// 请求超时处理
+	if security.RequestTimeout > 0 {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), security.RequestTimeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+	}
 
-	return func(c *gin.Context) {
+	// IP 白名单检查
+	if len(security.TrustedProxies) > 0 {
 		clientIP := c.ClientIP()
-		now := time.Now()
-
-		// 清理过期记录
-		if requests, exists := clients[clientIP]; exists {
-			var validRequests []time.Time
-			for _, reqTime := range requests {
-				if now.Sub(reqTime) < window {
-					validRequests = append(validRequests, reqTime)
-				}
-			}
-			clients[clientIP] = validRequests
-		}
-
-		// 检查是否超过限制
-		if requests, exists := clients[clientIP]; exists && len(requests) >= maxRequests {
-			appErr := common.NewAppError(common.CodeTooManyRequests, "请求过于频繁，请稍后再试", "")
-			c.JSON(http.StatusTooManyRequests, appErr)
+		if !isTrustedIP(clientIP, security.TrustedProxies) {
+			appErr := common.NewAppError(common.CodeUnauthorized, "IP地址不被信任", "")
+			c.JSON(http.StatusForbidden, appErr)
 			c.Abort()
 			return
 		}
+	}
 
-		// 记录当前请求
-		clients[clientIP] = append(clients[clientIP], now)
+	c.Next()
+}
 
-		c.Next()
+// RateLimitMiddleware 按客户端 IP 限流，速率和突发量取自 security.rate_limit，配置重新加载后自动生效。
+// 计数保存在进程内，多实例部署时每个实例分别计数
+func RateLimitMiddleware() gin.HandlerFunc {
+	var enabled atomic.Bool
+	settings := config.Get().Security.RateLimit
+	enabled.Store(settings.Enabled)
+	limiter := NewRateLimiter(settings.RequestsPerMinute, settings.Burst)
+	config.Subscribe("rate_limit", func(_, cfg *config.Config) {
+		settings := cfg.Security.RateLimit
+		enabled.Store(settings.Enabled)
+		limiter.Configure(settings.RequestsPerMinute, settings.Burst)
+	})
+
+	return func(c *gin.Context) {
+		if !enabled.Load() || limiter.Allow(c.ClientIP()) {
+			c.Next()
+			return
+		}
+		appErr := common.NewAppError(common.CodeTooManyRequests, "请求过于频繁，请稍后再试", "")
+		c.JSON(http.StatusTooManyRequests, appErr)
+		c.Abort()
 	}
 }
 
@@ -116,34 +139,8 @@ func InputValidationMiddleware() gin.HandlerFunc {
 	}
 }
 
-// CSRFProtectionMiddleware CSRF 保护中间件
-func CSRFProtectionMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 对于 GET 请求跳过 CSRF 检查
-		if c.Request.Method == "GET" {
-			c.Next()
-			return
-		}
-
-		// 检查 CSRF Token
-		token := c.GetHeader("X-CSRF-Token")
-		if token == "" {
-			token = c.PostForm("csrf_token")
-		}
-
-		if token == "" || !validateCSRFToken(token, c) {
-			appErr := common.NewAppError(common.CodeUnauthorized, "CSRF token 无效", "")
-			c.JSON(http.StatusForbidden, appErr)
-			c.Abort()
-			return
-		}
-
-		c.Next()
-	}
-}
-
 // setSecurityHeaders 设置安全头部
-func setSecurityHeaders(c *gin.Context) {
+func setSecurityHeaders(c *gin.Context, contentSecurityPolicy string) {
 	// 防止点击劫持
 	c.Header("X-Frame-Options", "DENY")
 	c.Header("X-Content-Type-Options", "nosniff")
@@ -157,36 +154,69 @@ func setSecurityHeaders(c *gin.Context) {
 	}
 
 	// 内容安全策略
-	c.Header("Content-Security-Policy", "default-src 'self'")
+	if contentSecurityPolicy != "" {
+		c.Header("Content-Security-Policy", contentSecurityPolicy)
+	}
 
 	// 隐藏服务器信息
 	c.Header("Server", "")
-
-	// 限制跨域请求
-	c.Header("Access-Control-Allow-Credentials", "false")
 }
 
-// handleCORS 处理 CORS
-func handleCORS(c *gin.Context) {
+// handleCORS 按跨域配置处理请求，不在白名单中的来源不返回跨域头部。预检请求直接结束时返回 true
+func handleCORS(c *gin.Context, cors config.CORSConfig) bool {
 	origin := c.Request.Header.Get("Origin")
+	if origin == "" || !isAllowedOrigin(origin, cors.AllowedOrigins) {
+		return false
+	}
 
-	// 在开发环境允许所有源
-	if gin.Mode() == gin.DebugMode {
+	c.Header("Vary", "Origin")
+	if containsString(cors.AllowedOrigins, "*") && !cors.AllowCredentials {
 		c.Header("Access-Control-Allow-Origin", "*")
-	} else if origin != "" {
-		// 生产环境应该检查白名单
+	} else {
+		// 允许携带凭据时不能使用通配符，回显请求的来源
 		c.Header("Access-Control-Allow-Origin", origin)
 	}
-
-	c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-CSRF-Token")
-	c.Header("Access-Control-Max-Age", "86400")
+	if cors.AllowCredentials {
+		c.Header("Access-Control-Allow-Credentials", "true")
+	}
+	if len(cors.ExposeHeaders) > 0 {
+		c.Header("Access-Control-Expose-Headers", strings.Join(cors.ExposeHeaders, ", "))
+	}
 
 	// 处理预检请求
-	if c.Request.Method == "OPTIONS" {
+	if c.Request.Method == http.MethodOptions && c.Request.Header.Get("Access-Control-Request-Method") != "" {
+		c.Header("Access-Control-Allow-Methods", strings.Join(cors.AllowedMethods, ", "))
+		if containsString(cors.AllowedHeaders, "*") {
+			c.Header("Access-Control-Allow-Headers", c.Request.Header.Get("Access-Control-Request-Headers"))
+		} else {
+			c.Header("Access-Control-Allow-Headers", strings.Join(cors.AllowedHeaders, ", "))
+		}
+		if cors.MaxAge > 0 {
+			c.Header("Access-Control-Max-Age", strconv.Itoa(cors.MaxAge))
+		}
 		c.AbortWithStatus(http.StatusNoContent)
-		return
+		return true
 	}
+	return false
+}
+
+// isAllowedOrigin 来源是否在跨域白名单中，* 表示允许所有来源
+func isAllowedOrigin(origin string, allowed []string) bool {
+	for _, item := range allowed {
+		if item == "*" || strings.EqualFold(item, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
 }
 
 // isTrustedIP 检查IP是否在信任列表中
@@ -245,25 +275,6 @@ func containsXSS(input string) bool {
 	return false
 }
 
-// validateCSRFToken 验证CSRF Token
-func validateCSRFToken(token string, c *gin.Context) bool {
-	// 这里应该实现真正的 CSRF token 验证逻辑
-	// 简化实现，生产环境需要更复杂的逻辑
-	if token == "" {
-		return false
-	}
-
-	// 可以从 session 中获取期望的 token
-	// 或者使用双重提交 Cookie 等方法
-	return len(token) > 10 // 简化的长度检查
-}
-
-// generateCSRFToken 生成CSRF Token
-func generateCSRFToken(c *gin.Context) string {
-	// 简化的 token 生成，生产环境应该使用更安全的方法
-	return "csrf_token_" + time.Now().Format("20060102150405")
-}
-
 // SanitizeInput 清理输入
 func SanitizeInput(input string) string {
 	input = strings.ReplaceAll(input, "&", "&amp;")
@@ -285,83 +296,67 @@ func ValidateEmail(email string) bool {
 	return emailRegex.MatchString(email)
 }
 
-// ValidatePassword 验证密码强度
-func ValidatePassword(password string) error {
-	if len(password) < 8 {
-		return common.NewAppError(common.CodeValidationFailed, "密码长度至少8位", "")
-	}
-
-	if len(password) > 128 {
-		return common.NewAppError(common.CodeValidationFailed, "密码长度不能超过128位", "")
-	}
+// 限流器清理长时间没有请求的客户端的间隔
+const rateLimiterCleanupInterval = 10 * time.Minute
 
-	// 检查是否包含数字
-	hasNumber := regexp.MustCompile(`\d`).MatchString(password)
-	// 检查是否包含大写字母
-	hasUpper := regexp.MustCompile(`[A-Z]`).MatchString(password)
-	// 检查是否包含小写字母
-	hasLower := regexp.MustCompile(`[a-z]`).MatchString(password)
-	// 检查是否包含特殊字符
-	hasSpecial := regexp.MustCompile(`[!@#$%^&*(),.?":{}|<>]`).MatchString(password)
-
-	count := 0
-	if hasNumber {
-		count++
-	}
-	if hasUpper {
-		count++
-	}
-	if hasLower {
-		count++
-	}
-	if hasSpecial {
-		count++
-	}
-
-	if count < 3 {
-		return common.NewAppError(common.CodeValidationFailed, "密码必须包含数字、大小写字母、特殊字符中的至少3种", "")
-	}
+// RateLimiter 按键限流的内存令牌桶，速率和突发量可以在运行中调整
+type RateLimiter struct {
+	mu          sync.Mutex
+	limit       rate.Limit
+	burst       int
+	visitors    map[string]*rateVisitor
+	lastCleanup time.Time
+}
 
-	return nil
+type rateVisitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
 }
 
-// RateLimiter 简单的内存限流器
-type RateLimiter struct {
-	requests map[string][]time.Time
-	window   time.Duration
-	max      int
+// NewRateLimiter 创建限流器，每个键每分钟最多 requestsPerMinute 次，允许 burst 次突发
+func NewRateLimiter(requestsPerMinute, burst int) *RateLimiter {
+	rl := &RateLimiter{visitors: make(map[string]*rateVisitor), lastCleanup: time.Now()}
+	rl.Configure(requestsPerMinute, burst)
+	return rl
 }
 
-// NewRateLimiter 创建限流器
-func NewRateLimiter(max int, window time.Duration) *RateLimiter {
-	return &RateLimiter{
-		requests: make(map[string][]time.Time),
-		window:   window,
-		max:      max,
+// Configure 调整速率和突发量，已有客户端的令牌桶同时生效
+func (rl *RateLimiter) Configure(requestsPerMinute, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.limit = rate.Limit(float64(requestsPerMinute) / 60)
+	rl.burst = burst
+	now := time.Now()
+	for _, v := range rl.visitors {
+		v.limiter.SetLimitAt(now, rl.limit)
+		v.limiter.SetBurstAt(now, rl.burst)
 	}
 }
 
 // Allow 检查是否允许请求
 func (rl *RateLimiter) Allow(key string) bool {
 	now := time.Now()
-
-	// 清理过期请求
-	if requests, exists := rl.requests[key]; exists {
-		var validRequests []time.Time
-		for _, reqTime := range requests {
-			if now.Sub(reqTime) < rl.window {
-				validRequests = append(validRequests, reqTime)
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	// 清理长时间没有请求的客户端，此时它们的令牌桶早已装满
+	if now.Sub(rl.lastCleanup) > rateLimiterCleanupInterval {
+		for k, v := range rl.visitors {
+			if now.Sub(v.lastSeen) > rateLimiterCleanupInterval {
+				delete(rl.visitors, k)
 			}
 		}
-		rl.requests[key] = validRequests
+		rl.lastCleanup = now
 	}
 
-	// 检查是否超过限制
-	if requests, exists := rl.requests[key]; exists && len(requests) >= rl.max {
-		return false
+	v, ok := rl.visitors[key]
+	if !ok {
+		v = &rateVisitor{limiter: rate.NewLimiter(rl.limit, rl.burst)}
+		rl.visitors[key] = v
 	}
-
-	// 记录当前请求
-	rl.requests[key] = append(rl.requests[key], now)
-	return true
+	v.lastSeen = now
+	return v.limiter.AllowN(now, 1)
 }
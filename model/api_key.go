@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// APIKey 个人 API 密钥，供脚本和 CI 等机器客户端调用接口，数据库中只保存密钥的哈希值
+type APIKey struct {
+	ID         uint       `gorm:"primarykey"`
+	UserID     uint       `gorm:"not null;index"`
+	Name       string     `gorm:"type:varchar(64);not null"`
+	Prefix     string     `gorm:"type:varchar(16);not null;uniqueIndex"` // 明文前缀，用于在列表中辨认密钥
+	KeyHash    string     `gorm:"type:varchar(64);not null;uniqueIndex"` // 完整密钥的 SHA-256 摘要
+	Scopes     string     `gorm:"type:varchar(512)"`                     // 授权范围（权限名），逗号分隔
+	ExpiresAt  *time.Time // 过期时间，为空表示长期有效
+	LastUsedAt *time.Time
+	LastUsedIP string     `gorm:"type:varchar(64)"`
+	RevokedAt  *time.Time // 撤销时间
+	CreatedAt  time.Time
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// AuditLog 审计日志，记录管理员代登录等需要追溯的操作
+type AuditLog struct {
+	ID        uint      `gorm:"primarykey"`
+	ActorID   uint      `gorm:"not null;index"`                  // 实际操作的用户
+	UserID    uint      `gorm:"index"`                           // 被操作的用户，不涉及时为 0
+	Action    string    `gorm:"type:varchar(64);not null;index"` // 操作类型，如 impersonation.start
+	Detail    string    `gorm:"type:text"`                       // JSON 格式的操作详情
+	IP        string    `gorm:"type:varchar(64)"`
+	UserAgent string    `gorm:"type:varchar(255)"`
+	RequestID string    `gorm:"type:varchar(64)"`
+	CreatedAt time.Time `gorm:"index"`
+}
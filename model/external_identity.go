@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// ExternalIdentity 第三方（OIDC）账号与本地用户的绑定关系
+type ExternalIdentity struct {
+	ID          uint   `gorm:"primarykey"`
+	UserID      uint   `gorm:"not null;index"`
+	Provider    string `gorm:"type:varchar(64);not null;uniqueIndex:idx_external_identity"`  // 配置中的提供方名称
+	Subject     string `gorm:"type:varchar(255);not null;uniqueIndex:idx_external_identity"` // 提供方的用户标识（sub）
+	Email       string `gorm:"type:varchar(255)"`
+	Name        string `gorm:"type:varchar(255)"`
+	LastLoginAt *time.Time
+	CreatedAt   time.Time
+}
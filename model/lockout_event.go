@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// LockoutEvent 登录失败次数过多导致的临时锁定记录
+type LockoutEvent struct {
+	ID          uint      `gorm:"primarykey"`
+	Scope       string    `gorm:"type:varchar(20);not null;index"`  // account 或 ip
+	Identifier  string    `gorm:"type:varchar(128);not null;index"` // 手机号或 IP
+	IP          string    `gorm:"type:varchar(64)"`                 // 触发锁定的请求 IP
+	Failures    int       `gorm:"not null"`                         // 锁定时累计的失败次数
+	LockedUntil time.Time `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"index"`
+}
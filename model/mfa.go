@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// UserMFA 用户的两步验证（TOTP）设置
+type UserMFA struct {
+	ID          uint   `gorm:"primarykey"`
+	UserID      uint   `gorm:"not null;uniqueIndex"`
+	Secret      string `gorm:"type:varchar(64);not null"` // Base32 编码的 TOTP 密钥
+	Enabled     bool   `gorm:"not null;default:false"`    // 首次验证通过后才启用
+	LastCounter int64  `gorm:"not null;default:0"`        // 最近一次通过验证的时间步，防止验证码重放
+	EnabledAt   *time.Time
+	CreatedAt   time.Time
+}
+
+// RecoveryCode 一次性恢复码，数据库中只保存哈希值
+type RecoveryCode struct {
+	ID        uint       `gorm:"primarykey"`
+	UserID    uint       `gorm:"not null;index"`
+	CodeHash  string     `gorm:"type:varchar(64);not null"`
+	UsedAt    *time.Time // 使用时间，使用后即失效
+	CreatedAt time.Time
+}
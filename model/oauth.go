@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+// OAuthClient 在本服务登记的第三方应用
+type OAuthClient struct {
+	ID           uint   `gorm:"primarykey"`
+	ClientID     string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	SecretHash   string `gorm:"type:varchar(64)"` // 客户端密钥的 SHA-256 摘要，公开客户端为空
+	Name         string `gorm:"type:varchar(100);not null"`
+	RedirectURIs string `gorm:"type:text"`              // 允许的回调地址，换行分隔，必须完全匹配
+	GrantTypes   string `gorm:"type:varchar(255)"`      // 允许的授权方式，逗号分隔
+	Scopes       string `gorm:"type:varchar(512)"`      // 允许申请的范围，空格分隔
+	Public       bool   `gorm:"not null;default:false"` // 公开客户端（SPA、移动端）不持有密钥，必须使用 PKCE
+	SkipConsent  bool   `gorm:"not null;default:false"` // 内部应用可跳过用户授权确认
+	CreatedBy    uint
+	RevokedAt    *time.Time
+	CreatedAt    time.Time
+}
+
+// OAuthAuthorizationCode 授权码，数据库中只保存哈希值，只能使用一次
+type OAuthAuthorizationCode struct {
+	ID                  uint   `gorm:"primarykey"`
+	CodeHash            string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ClientID            string `gorm:"type:varchar(64);not null;index"`
+	UserID              uint   `gorm:"not null"`
+	RedirectURI         string `gorm:"type:varchar(512);not null"`
+	Scope               string `gorm:"type:varchar(512)"`
+	CodeChallenge       string `gorm:"type:varchar(128)"`
+	CodeChallengeMethod string `gorm:"type:varchar(16)"`
+	FamilyID            string `gorm:"type:varchar(64)"` // 兑换后发放的刷新令牌族，授权码被重放时据此撤销
+	ExpiresAt           time.Time
+	UsedAt              *time.Time
+	CreatedAt           time.Time
+}
+
+// OAuthRefreshToken 发放给第三方应用的刷新令牌，与用户登录的刷新令牌分开保存
+type OAuthRefreshToken struct {
+	ID        uint   `gorm:"primarykey"`
+	TokenHash string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	FamilyID  string `gorm:"type:varchar(64);not null;index"`
+	ClientID  string `gorm:"type:varchar(64);not null;index"`
+	UserID    uint   `gorm:"not null;index"`
+	Scope     string `gorm:"type:varchar(512)"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// OAuthConsent 用户对第三方应用的授权记录
+type OAuthConsent struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_oauth_consent"`
+	ClientID  string `gorm:"type:varchar(64);not null;uniqueIndex:idx_oauth_consent"`
+	Scope     string `gorm:"type:varchar(512)"` // 已同意的范围，空格分隔
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
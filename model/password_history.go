@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// PasswordHistory 用户用过的密码哈希，用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	ID           uint      `gorm:"primarykey"`
+	UserID       uint      `gorm:"not null;index"`
+	PasswordHash string    `gorm:"size:255;not null"`
+	CreatedAt    time.Time `gorm:"index"`
+}
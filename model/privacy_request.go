@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// PrivacyRequest 用户的个人数据导出或删除申请，由后台任务异步处理
+type PrivacyRequest struct {
+	ID          uint       `gorm:"primarykey"`
+	UserID      uint       `gorm:"not null;index"`
+	Type        string     `gorm:"type:varchar(16);not null;index"` // export 或 erasure
+	Status      string     `gorm:"type:varchar(16);not null;index"` // pending、processing、completed、failed、cancelled
+	ScheduledAt time.Time  `gorm:"not null;index"`                  // 计划处理时间，删除申请为宽限期结束的时间
+	FilePath    string     `gorm:"type:varchar(255)"`               // 导出文件路径
+	ExpiresAt   *time.Time // 导出文件的下载截止时间
+	Error       string     `gorm:"type:varchar(512)"` // 处理失败的原因
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
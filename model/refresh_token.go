@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// RefreshToken 刷新令牌，数据库中只保存令牌的哈希值
+type RefreshToken struct {
+	ID        uint       `gorm:"primarykey"`
+	UserID    uint       `gorm:"not null;index"`
+	FamilyID  string     `gorm:"type:varchar(64);not null;index"`       // 令牌族，同一次登录轮换出的令牌属于同一族
+	TokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex"` // 令牌的 SHA-256 摘要
+	AMR       string     `gorm:"type:varchar(64)"`                      // 登录时使用的认证方式，逗号分隔，轮换时沿用
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // 轮换时被使用的时间，再次出现即视为重放
+	RevokedAt *time.Time // 撤销时间
+	CreatedAt time.Time
+}
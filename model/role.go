@@ -0,0 +1,16 @@
+package model
+
+// Role 角色，通过 user_roles 与用户多对多关联
+type Role struct {
+	ID          uint         `gorm:"primarykey"`
+	Name        string       `gorm:"type:varchar(50);not null;uniqueIndex"`
+	Description string       `gorm:"type:varchar(255)"`
+	Permissions []Permission `gorm:"many2many:role_permissions"`
+}
+
+// Permission 权限，名称形如 user:read
+type Permission struct {
+	ID          uint   `gorm:"primarykey"`
+	Name        string `gorm:"type:varchar(100);not null;uniqueIndex"`
+	Description string `gorm:"type:varchar(255)"`
+}
@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+// SchemaMigration 已执行的数据库迁移
+type SchemaMigration struct {
+	ID        string    `gorm:"primarykey;type:varchar(64)"` // 迁移 ID，如 0001_create_tables
+	AppliedAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Session 登录会话，一次登录对应一条记录，ID 与该次登录的刷新令牌族相同
+type Session struct {
+	ID         string     `gorm:"type:varchar(64);primarykey"`
+	UserID     uint       `gorm:"not null;index"`
+	UserAgent  string     `gorm:"type:varchar(512)"`
+	IP         string     `gorm:"type:varchar(64)"`
+	LastSeenAt time.Time  `gorm:"not null"` // 最近一次使用访问令牌或刷新令牌的时间
+	ExpiresAt  time.Time  `gorm:"not null"` // 随刷新令牌轮换顺延
+	RevokedAt  *time.Time // 撤销时间
+	CreatedAt  time.Time
+}
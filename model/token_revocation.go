@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// TokenRevocation 令牌撤销记录，Redis 不可用时作为撤销存储
+type TokenRevocation struct {
+	ID        uint      `gorm:"primarykey"`
+	TokenKey  string    `gorm:"type:varchar(128);not null;uniqueIndex"` // jti:<jti> 或 user:<id>
+	RevokedAt time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"` // 过期后记录即可清理
+}
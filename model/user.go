@@ -1,11 +1,30 @@
 package model
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 // 字段表的定义，数据库相关
 
 type User struct { //定义数据类型和字段，一直没有明白数据库表 是 users，也不是user
 	ID        uint   `gorm:"primarykey"`
 	Username  string `gorm:"type:varchar(20);not null"`
 	Telephone string `gorm:"varchar(110);not null;unique"`
-	Tel       string `gorm:"varchar(110);not null;unique"`
 	Password  string `gorm:"size:255;not null"`
+	Roles     []Role `gorm:"many2many:user_roles"`
+
+	Avatar string `gorm:"type:varchar(255)"` // 头像地址
+	Bio    string `gorm:"type:varchar(255)"` // 个人简介
+
+	PhoneVerifiedAt   *time.Time // 手机号通过短信验证的时间
+	PasswordChangedAt *time.Time // 最近一次设置密码的时间，用于密码有效期
+
+	Status                string `gorm:"type:varchar(16);not null;default:active;index"` // 账号状态
+	PasswordResetRequired bool   `gorm:"not null;default:false"`                         // 管理员要求下次登录后修改密码
+
+	CreatedAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"` // 软删除，注销或被删除的账号保留记录
 }
@@ -6,6 +6,7 @@ import (
 	"theing/gin-template/common"
 	controller "theing/gin-template/controller"
 	"theing/gin-template/controller/admin_controller"
+	"theing/gin-template/controller/oauth_controller"
 	option_controller "theing/gin-template/controller/options_controller"
 	errorMiddleware "theing/gin-template/middleware"
 
@@ -24,6 +25,28 @@ func CollectRoute(r *gin.Engine) *gin.Engine {
 	r.Use(errorMiddleware.ErrorHandlerMiddleware())    // 统一错误处理中间件
 	r.Use(errorMiddleware.MetricsMiddleware())         // 性能监控中间件
 	r.Use(errorMiddleware.DatabaseMetricsMiddleware()) // 数据库监控中间件
+	r.Use(errorMiddleware.SecurityMiddleware())        // 安全头部与跨域
+	r.Use(errorMiddleware.RateLimitMiddleware())       // 按客户端 IP 限流
+
+	// 公开的 JWT 验证公钥
+	r.GET("/.well-known/jwks.json", controller.JWKS)
+
+	// OAuth2 授权服务，供第三方应用使用
+	r.GET("/.well-known/oauth-authorization-server", oauth_controller.Metadata) // 授权服务元数据
+	oauth := r.Group("/oauth")
+	{
+		oauth.POST("/token", oauth_controller.Token)                                               // 获取令牌
+		oauth.POST("/introspect", oauth_controller.Introspect)                                     // 令牌自省
+		oauth.POST("/revoke", oauth_controller.Revoke)                                             // 撤销令牌
+		oauth.GET("/userinfo", errorMiddleware.OAuthBearerMiddleware(), oauth_controller.UserInfo) // 用户信息
+
+		// 授权确认页由前端渲染，需要用户已登录
+		consent := oauth.Group("/authorize", errorMiddleware.AuthMiddleware(), errorMiddleware.DenyAPIKey(), errorMiddleware.DenyImpersonation())
+		{
+			consent.GET("", oauth_controller.Authorize) // 校验授权请求
+			consent.POST("", oauth_controller.Consent)  // 同意或拒绝授权
+		}
+	}
 
 	// API 路由组
 	api := r.Group("/api")
@@ -31,9 +54,81 @@ func CollectRoute(r *gin.Engine) *gin.Engine {
 		// 认证相关路由
 		auth := api.Group("/auth")
 		{
-			auth.POST("/register", controller.Register)                          // 用户注册
-			auth.POST("/login", controller.UserLogin)                            // 用户登录
-			auth.GET("/info", errorMiddleware.AuthMiddleware(), controller.Info) // 获取用户信息（需要认证）
+			auth.POST("/register", controller.Register)                                                                           // 用户注册
+			auth.POST("/login", controller.UserLogin)                                                                             // 用户登录
+			auth.POST("/refresh", controller.RefreshToken)                                                                        // 刷新令牌
+			auth.GET("/info", errorMiddleware.AuthMiddleware(), controller.Info)                                                  // 获取用户信息（需要认证）
+			auth.POST("/logout", errorMiddleware.AuthMiddleware(), controller.Logout)                                             // 退出登录
+			auth.POST("/logout-all", errorMiddleware.AuthMiddleware(), errorMiddleware.DenyImpersonation(), controller.LogoutAll) // 退出所有设备
+
+			// 个人资料与账号安全，只允许交互式登录后操作
+			account := auth.Group("", errorMiddleware.AuthMiddleware(), errorMiddleware.DenyAPIKey(), errorMiddleware.DenyImpersonation())
+			{
+				account.PATCH("/profile", controller.UpdateProfile)           // 修改个人资料
+				account.POST("/password", controller.ChangePassword)          // 修改密码
+				account.POST("/phone", controller.RequestPhoneChange)         // 更换手机号：发送验证码
+				account.POST("/phone/confirm", controller.ConfirmPhoneChange) // 更换手机号：提交验证码
+				account.POST("/deactivate", controller.DeactivateAccount)     // 注销账号
+			}
+
+			// 短信验证码与找回密码
+			auth.POST("/verification-code", controller.SendVerificationCode) // 发送短信验证码
+			auth.POST("/password/reset", controller.ResetPassword)           // 通过验证码重置密码
+
+			// 两步验证
+			auth.POST("/mfa/verify", controller.MFAVerify) // 两步登录：提交验证码
+			mfa := auth.Group("/mfa", errorMiddleware.AuthMiddleware(), errorMiddleware.DenyAPIKey(), errorMiddleware.DenyImpersonation())
+			{
+				mfa.POST("/enroll", controller.MFAEnroll)                // 生成密钥和二维码
+				mfa.POST("/activate", controller.MFAActivate)            // 启用两步验证
+				mfa.POST("/disable", controller.MFADisable)              // 关闭两步验证
+				mfa.POST("/recovery-codes", controller.MFARecoveryCodes) // 重新生成恢复码
+			}
+
+			// 第三方登录（OIDC）
+			auth.GET("/oidc/providers", controller.OIDCProviders)         // 可用的第三方登录方式
+			auth.GET("/oidc/:provider/login", controller.OIDCLogin)       // 跳转到第三方登录
+			auth.GET("/oidc/:provider/callback", controller.OIDCCallback) // 第三方登录回调
+			identities := auth.Group("/oidc", errorMiddleware.AuthMiddleware(), errorMiddleware.DenyAPIKey(), errorMiddleware.DenyImpersonation())
+			{
+				identities.POST("/:provider/link", controller.OIDCLink)                 // 绑定第三方账号
+				identities.GET("/identities", controller.ListExternalIdentities)        // 已绑定的账号
+				identities.DELETE("/identities/:id", controller.UnlinkExternalIdentity) // 解除绑定
+			}
+
+			// 登录会话与设备
+			sessions := auth.Group("/sessions", errorMiddleware.AuthMiddleware(), errorMiddleware.DenyAPIKey(), errorMiddleware.DenyImpersonation())
+			{
+				sessions.GET("", controller.ListSessions)         // 活跃会话列表
+				sessions.DELETE("", controller.RevokeAllSessions) // 撤销全部会话
+				sessions.DELETE("/:id", controller.RevokeSession) // 撤销某个会话
+			}
+
+			// 个人 API 密钥，只能在交互式登录后管理
+			apiKeys := auth.Group("/api-keys", errorMiddleware.AuthMiddleware(), errorMiddleware.DenyAPIKey(), errorMiddleware.DenyImpersonation())
+			{
+				apiKeys.GET("", controller.ListAPIKeys)         // 密钥列表
+				apiKeys.POST("", controller.CreateAPIKey)       // 创建密钥
+				apiKeys.PATCH("/:id", controller.UpdateAPIKey)  // 修改名称或授权范围
+				apiKeys.DELETE("/:id", controller.RevokeAPIKey) // 撤销密钥
+			}
+
+			// 已授权的第三方应用
+			consents := auth.Group("/oauth/consents", errorMiddleware.AuthMiddleware(), errorMiddleware.DenyAPIKey(), errorMiddleware.DenyImpersonation())
+			{
+				consents.GET("", oauth_controller.ListConsents)                // 授权列表
+				consents.DELETE("/:client_id", oauth_controller.RevokeConsent) // 取消授权
+			}
+
+			// 个人数据导出与删除
+			privacy := auth.Group("/privacy", errorMiddleware.AuthMiddleware(), errorMiddleware.DenyAPIKey(), errorMiddleware.DenyImpersonation())
+			{
+				privacy.GET("/requests", controller.ListPrivacyRequests)            // 我的申请
+				privacy.POST("/exports", controller.RequestDataExport)              // 申请导出
+				privacy.GET("/exports/:id/download", controller.DownloadDataExport) // 下载导出文件
+				privacy.POST("/erasure", controller.RequestErasure)                 // 申请删除账号
+				privacy.DELETE("/erasure", controller.CancelErasure)                // 撤回删除申请
+			}
 		}
 
 		// 选项相关路由
@@ -47,12 +142,70 @@ func CollectRoute(r *gin.Engine) *gin.Engine {
 		admin := api.Group("/admin")
 		{
 			admin.POST("/login", admin_controller.AdminLogin) // 管理员登录
+
+			// 需要登录的管理接口，mfa.require_admin 开启时还要求完成两步验证
+			protected := admin.Group("", errorMiddleware.AuthMiddleware(), errorMiddleware.RequireMFA())
+
+			// 角色与授权管理
+			rbac := protected.Group("", errorMiddleware.RequirePermission(common.PermRoleManage))
+			{
+				rbac.GET("/roles", admin_controller.ListRoles)                         // 角色列表
+				rbac.POST("/roles", admin_controller.CreateRole)                       // 创建角色
+				rbac.GET("/permissions", admin_controller.ListPermissions)             // 权限列表
+				rbac.GET("/users/:id/roles", admin_controller.GetUserRoles)            // 用户角色
+				rbac.POST("/users/:id/roles", admin_controller.AssignUserRole)         // 授予角色
+				rbac.DELETE("/users/:id/roles/:role", admin_controller.RemoveUserRole) // 撤销角色
+			}
+
+			// 用户管理，仅管理员可用
+			users := protected.Group("/users", errorMiddleware.RequireRole(common.RoleAdmin))
+			{
+				users.GET("", errorMiddleware.RequirePermission(common.PermUserRead), admin_controller.ListUsers)          // 用户列表
+				users.GET("/export", errorMiddleware.RequirePermission(common.PermUserRead), admin_controller.ExportUsers) // 导出用户
+				users.GET("/:id", errorMiddleware.RequirePermission(common.PermUserRead), admin_controller.GetUser)        // 用户详情
+
+				userWrite := users.Group("", errorMiddleware.RequirePermission(common.PermUserWrite))
+				userWrite.POST("/import", admin_controller.ImportUsers)                                                             // 批量导入用户
+				userWrite.POST("/:id/disable", errorMiddleware.RequirePolicy("user:disable", "user"), admin_controller.DisableUser) // 停用用户
+				userWrite.POST("/:id/enable", admin_controller.EnableUser)                                                          // 启用用户
+				userWrite.POST("/:id/reset-password", admin_controller.ForcePasswordReset)                                          // 强制重置密码
+				userWrite.DELETE("/:id", errorMiddleware.RequirePolicy("user:delete", "user"), admin_controller.DeleteUser)         // 删除用户
+
+				// 代登录需要单独的权限，只能在交互式登录后申请
+				users.POST("/:id/impersonate", errorMiddleware.DenyAPIKey(), errorMiddleware.RequirePermission(common.PermUserImpersonate), admin_controller.ImpersonateUser) // 代登录用户
+			}
+
+			// 审计日志
+			protected.GET("/audit-logs", errorMiddleware.RequirePermission(common.PermUserRead), admin_controller.ListAuditLogs) // 审计日志
+
+			// 个人数据申请
+			protected.GET("/privacy/requests", errorMiddleware.RequirePermission(common.PermUserRead), admin_controller.ListPrivacyRequests) // 待处理的导出与删除申请
+
+			// 登录锁定
+			protected.GET("/lockouts", errorMiddleware.RequirePermission(common.PermUserRead), admin_controller.ListLockoutEvents)    // 锁定记录
+			protected.POST("/lockouts/unlock", errorMiddleware.RequirePermission(common.PermUserWrite), admin_controller.UnlockLogin) // 解除锁定
+
+			// 授权策略
+			policies := protected.Group("/policies", errorMiddleware.RequirePermission(common.PermPolicyRead))
+			{
+				policies.GET("", admin_controller.ListPolicies)             // 策略列表
+				policies.POST("/evaluate", admin_controller.EvaluatePolicy) // 策略试算
+			}
+
+			// 第三方应用
+			oauthClients := protected.Group("/oauth/clients", errorMiddleware.RequirePermission(common.PermOAuthClientManage))
+			{
+				oauthClients.GET("", admin_controller.ListOAuthClients)                // 应用列表
+				oauthClients.POST("", admin_controller.CreateOAuthClient)              // 登记应用
+				oauthClients.DELETE("/:client_id", admin_controller.RevokeOAuthClient) // 停用应用
+			}
 		}
 
 		// 健康检查路由
 		health := api.Group("/health")
 		{
 			health.GET("/", controller.HealthCheck)            // 系统健康检查
+			health.GET("/ready", controller.Readiness)         // 就绪检查
 			health.GET("/database", controller.DatabaseHealth) // 数据库健康检查
 			health.GET("/stats", controller.DatabaseStats)     // 数据库统计信息
 			health.GET("/info", controller.SystemInfo)         // 系统信息
@@ -65,7 +218,6 @@ func CollectRoute(r *gin.Engine) *gin.Engine {
 		api.GET("/auth/info2", option_controller.Login)
 		api.GET("/GetIndustryList", option_controller.GetIndustryList)     // 获取行业领域列表json格式
 		api.GET("/GetProfessionList", option_controller.GetProfessionList) // 获取专业选项分类
-		api.GET("/admin/AdminLogin", admin_controller.AdminLogin)          // 获取专业选项分类
 	}
 
 	return r
@@ -161,8 +161,10 @@ install_dependencies() {
 run_migrations() {
     log_info "运行数据库迁移..."
     
-    # 这里可以添加数据库迁移逻辑
-    # 例如: go run cmd/migrate/main.go up
+    if ! go run . migrate up; then
+        log_error "数据库迁移失败"
+        exit 1
+    fi
     
     log_success "数据库迁移完成"
 }
@@ -1,9 +1,22 @@
 package utils
 
 import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"math/big"
 )
 
 // GenerateSecureKey 生成安全的随机密钥
@@ -20,3 +33,104 @@ func GenerateSecureKey(length int) (string, error) {
 func GenerateJWTKey() (string, error) {
 	return GenerateSecureKey(32)
 }
+
+// GenerateOpaqueToken 生成不透明的随机令牌（URL 安全，无填充）
+func GenerateOpaqueToken(length int) (string, error) {
+	bytes := make([]byte, length)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("生成随机令牌失败: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// GenerateNumericCode 生成指定位数的随机数字验证码
+func GenerateNumericCode(digits int) (string, error) {
+	code := make([]byte, digits)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", fmt.Errorf("生成验证码失败: %v", err)
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+	return string(code), nil
+}
+
+// HashToken 计算令牌的 SHA-256 摘要，数据库中只保存摘要
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// EncryptAESGCM 使用 AES-GCM 加密，返回随机 nonce 与密文拼接后的 base64。key 长度为 16、24 或 32 字节
+func EncryptAESGCM(key, plaintext []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// DecryptAESGCM 解密 EncryptAESGCM 的结果，密钥不匹配或密文被篡改时返回错误
+func DecryptAESGCM(key []byte, encoded string) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("密文格式错误: %v", err)
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("密文长度不足")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("解密失败，密钥不匹配或密文已损坏")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("无效的加密密钥: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// GenerateSigningKeyPair 生成 JWT 非对称签名密钥对，返回 PKCS#8 私钥和 PKIX 公钥的 PEM
+func GenerateSigningKeyPair(algorithm string) (privatePEM []byte, publicPEM []byte, err error) {
+	var privateKey crypto.Signer
+	switch algorithm {
+	case "RS256":
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, nil, fmt.Errorf("不支持的算法: %s", algorithm)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("生成密钥失败: %v", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privatePEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})
+	publicPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	return privatePEM, publicPEM, nil
+}
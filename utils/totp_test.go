@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试向量，密钥为 ASCII "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateTOTPCode_RFC6238Vectors(t *testing.T) {
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for unix, expected := range vectors {
+		code, err := GenerateTOTPCode(rfcSecret, TOTPCounter(time.Unix(unix, 0)), 8)
+		require.NoError(t, err)
+		assert.Equal(t, expected, code, "T=%d", unix)
+	}
+}
+
+func TestValidateTOTPCode_Skew(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+
+	previous, err := GenerateTOTPCode(secret, TOTPCounter(now)-1, TOTPDigits)
+	require.NoError(t, err)
+
+	counter, ok := ValidateTOTPCode(secret, previous, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, TOTPCounter(now)-1, counter)
+
+	_, ok = ValidateTOTPCode(secret, previous, now, 0)
+	assert.False(t, ok)
+	_, ok = ValidateTOTPCode(secret, "12345", now, 1)
+	assert.False(t, ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("gin-template", "13800000000", rfcSecret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/gin-template:13800000000?"))
+	assert.Contains(t, uri, "secret="+rfcSecret)
+	assert.Contains(t, uri, "issuer=gin-template")
+}
@@ -0,0 +1,365 @@
+package utils
+
+// 最小化的 xlsx 读写：只支持单个工作表的纯文本单元格，满足导入导出表格数据的需要
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidXLSX 文件不是有效的 xlsx
+	ErrInvalidXLSX = errors.New("无效的 xlsx 文件")
+	// ErrXLSXTooLarge 文件内容超过 XLSXLimits 的限制
+	ErrXLSXTooLarge = errors.New("xlsx 文件内容超过限制")
+)
+
+// XLSXMaxColumns 工作表的最大列数，对应列名 XFD
+const XLSXMaxColumns = 16384
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+// XLSXWriter 流式写入只有一个工作表的 xlsx，单元格均为内联字符串
+type XLSXWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	rows  int
+}
+
+// NewXLSXWriter 创建写入器并写好工作簿结构，之后逐行调用 WriteRow，最后必须调用 Close
+func NewXLSXWriter(w io.Writer, sheetName string) (*XLSXWriter, error) {
+	zw := zip.NewWriter(w)
+	var escaped strings.Builder
+	if err := xml.EscapeText(&escaped, []byte(sheetName)); err != nil {
+		return nil, err
+	}
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, escaped.String())},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		fw, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(fw, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n"+
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+	return &XLSXWriter{zw: zw, sheet: sheet}, nil
+}
+
+// WriteRow 写入一行
+func (x *XLSXWriter) WriteRow(values []string) error {
+	x.rows++
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, x.rows)
+	for i, value := range values {
+		fmt.Fprintf(&b, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">`, XLSXColumnName(i), x.rows)
+		if err := xml.EscapeText(&b, []byte(value)); err != nil {
+			return err
+		}
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+	_, err := io.WriteString(x.sheet, b.String())
+	return err
+}
+
+// Close 结束工作表并写入 zip 目录
+func (x *XLSXWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
+
+// XLSXColumnName 列序号（从 0 开始）转换为列名：0 -> A，26 -> AA
+func XLSXColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xlsxColumnIndex 单元格引用中的列序号，如 "AB12" -> 27，列超过 XFD 时返回 false
+func xlsxColumnIndex(ref string) (int, bool) {
+	index := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+		if index > XLSXMaxColumns {
+			return 0, false
+		}
+	}
+	return index - 1, index > 0
+}
+
+// xlsxRowXML 工作表中的一行
+type xlsxRowXML struct {
+	Cells []struct {
+		Ref    string `xml:"r,attr"`
+		Type   string `xml:"t,attr"`
+		Value  string `xml:"v"`
+		Inline struct {
+			Text string `xml:"t"`
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"is"`
+	} `xml:"c"`
+}
+
+// xlsxSharedStringsXML 共享字符串表，富文本由多个 r 组成
+type xlsxSharedStringsXML struct {
+	Items []struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"si"`
+}
+
+// xlsxRelsXML 关系文件
+type xlsxRelsXML struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxWorkbookXML 工作簿中的工作表列表
+type xlsxWorkbookXML struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// XLSXLimits 读取上传文件时的限制，避免压缩炸弹或稀疏的单元格引用耗尽内存，为 0 的项不限制
+type XLSXLimits struct {
+	MaxPartSize int64 // zip 中单个文件解压后的最大字节数
+	MaxRows     int   // 工作表的最大行数（含表头）
+	MaxCells    int   // 全部行的单元格总数，包括为稀疏单元格补齐的空单元格
+}
+
+// ReadXLSX 读取第一个工作表的全部行，空单元格为空字符串，行尾的空单元格会被省略
+func ReadXLSX(r io.ReaderAt, size int64, limits XLSXLimits) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrInvalidXLSX
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := xlsxFirstSheetPath(files, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst xlsxSharedStringsXML
+		if err := decodeZipXML(f, &sst, limits); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			text := item.Text
+			for _, run := range item.Runs {
+				text += run.Text
+			}
+			shared = append(shared, text)
+		}
+	}
+
+	var rows [][]string
+	cells := 0
+	err = readZipXML(files[sheetPath], limits, func(d *xml.Decoder) error {
+		// 逐行解析，超过行数限制时不再继续读取
+		for {
+			token, err := d.Token()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			start, ok := token.(xml.StartElement)
+			if !ok || start.Name.Local != "row" {
+				continue
+			}
+			if limits.MaxRows > 0 && len(rows) >= limits.MaxRows {
+				return fmt.Errorf("%w: 超过 %d 行", ErrXLSXTooLarge, limits.MaxRows)
+			}
+			var row xlsxRowXML
+			if err := d.DecodeElement(&row, &start); err != nil {
+				return err
+			}
+			maxColumns := XLSXMaxColumns
+			if limits.MaxCells > 0 && limits.MaxCells-cells < maxColumns {
+				maxColumns = limits.MaxCells - cells
+			}
+			values, err := xlsxRowValues(row, shared, maxColumns)
+			if err != nil {
+				return err
+			}
+			cells += len(values)
+			rows = append(rows, values)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// xlsxRowValues 把一行的单元格按列号排列，列数超过 maxColumns 时在补齐空单元格之前返回错误
+func xlsxRowValues(row xlsxRowXML, shared []string, maxColumns int) ([]string, error) {
+	values := make([]string, 0, len(row.Cells))
+	for i, cell := range row.Cells {
+		column := i
+		if cell.Ref != "" {
+			var ok bool
+			if column, ok = xlsxColumnIndex(cell.Ref); !ok {
+				return nil, fmt.Errorf("%w: 无效的单元格引用 %.16s", ErrInvalidXLSX, cell.Ref)
+			}
+		}
+		if column >= maxColumns {
+			return nil, fmt.Errorf("%w: 单元格过多", ErrXLSXTooLarge)
+		}
+		for len(values) < column {
+			values = append(values, "")
+		}
+		var value string
+		switch cell.Type {
+		case "s":
+			idx, err := strconv.Atoi(cell.Value)
+			if err != nil || idx < 0 || idx >= len(shared) {
+				return nil, ErrInvalidXLSX
+			}
+			value = shared[idx]
+		case "inlineStr":
+			value = cell.Inline.Text
+			for _, run := range cell.Inline.Runs {
+				value += run.Text
+			}
+		default:
+			value = cell.Value
+		}
+		if column < len(values) {
+			values[column] = value
+		} else {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// xlsxFirstSheetPath 按工作簿中的顺序找到第一个工作表的路径
+func xlsxFirstSheetPath(files map[string]*zip.File, limits XLSXLimits) (string, error) {
+	workbook, ok := files["xl/workbook.xml"]
+	rels, relsOK := files["xl/_rels/workbook.xml.rels"]
+	if !ok || !relsOK {
+		return "", ErrInvalidXLSX
+	}
+	var wb xlsxWorkbookXML
+	if err := decodeZipXML(workbook, &wb, limits); err != nil {
+		return "", err
+	}
+	var relations xlsxRelsXML
+	if err := decodeZipXML(rels, &relations, limits); err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		return "", ErrInvalidXLSX
+	}
+	for _, rel := range relations.Relationships {
+		if rel.ID != wb.Sheets[0].RelID {
+			continue
+		}
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = path.Join("xl", target)
+		}
+		if _, ok := files[target]; ok {
+			return target, nil
+		}
+	}
+	return "", ErrInvalidXLSX
+}
+
+// decodeZipXML 解析 zip 中的 XML 文件
+func decodeZipXML(f *zip.File, v interface{}, limits XLSXLimits) error {
+	return readZipXML(f, limits, func(d *xml.Decoder) error {
+		return d.Decode(v)
+	})
+}
+
+// readZipXML 读取 zip 中的 XML 文件，解压后的内容超过 limits.MaxPartSize 时返回 ErrXLSXTooLarge
+func readZipXML(f *zip.File, limits XLSXLimits, read func(d *xml.Decoder) error) error {
+	if f == nil {
+		return ErrInvalidXLSX
+	}
+	tooLarge := fmt.Errorf("%w: %s 解压后超过 %d 字节", ErrXLSXTooLarge, f.Name, limits.MaxPartSize)
+	if limits.MaxPartSize > 0 && f.UncompressedSize64 > uint64(limits.MaxPartSize) {
+		return tooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	// zip 头中记录的大小不可信，实际读取时同样限制
+	var r io.Reader = rc
+	limited := &io.LimitedReader{R: rc, N: limits.MaxPartSize + 1}
+	if limits.MaxPartSize > 0 {
+		r = limited
+	}
+	if err := read(xml.NewDecoder(r)); err != nil {
+		if limits.MaxPartSize > 0 && limited.N <= 0 {
+			return tooLarge
+		}
+		if errors.Is(err, ErrXLSXTooLarge) || errors.Is(err, ErrInvalidXLSX) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidXLSX, err)
+	}
+	if limits.MaxPartSize > 0 && limited.N <= 0 {
+		return tooLarge
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXLSX_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewXLSXWriter(&buf, "用户")
+	require.NoError(t, err)
+	require.NoError(t, w.WriteRow([]string{"telephone", "name"}))
+	require.NoError(t, w.WriteRow([]string{"13800000000", "张三 <&>"}))
+	require.NoError(t, w.Close())
+
+	rows, err := ReadXLSX(bytes.NewReader(buf.Bytes()), int64(buf.Len()), XLSXLimits{})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"telephone", "name"}, {"13800000000", "张三 <&>"}}, rows)
+}
+
+func TestReadXLSX_SharedStringsAndSparseCells(t *testing.T) {
+	// 模拟 Excel 保存的文件：共享字符串、数字单元格、跳过空单元格
+	files := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId3"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId3" Target="/xl/worksheets/data.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>telephone</t></si><si><t>name</t></si><si><r><t>Ali</t></r><r><t>ce</t></r></si></sst>`,
+		"xl/worksheets/data.xml": `<worksheet><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>` +
+			`<row r="2"><c r="A2"><v>13800000000</v></c><c r="C2" t="s"><v>2</v></c></row>` +
+			`</sheetData></worksheet>`,
+	}
+	buf := buildXLSX(t, files)
+
+	rows, err := ReadXLSX(bytes.NewReader(buf.Bytes()), int64(buf.Len()), XLSXLimits{})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"telephone", "", "name"}, {"13800000000", "", "Alice"}}, rows)
+}
+
+func TestReadXLSX_Limits(t *testing.T) {
+	sheet := func(rows string) map[string]string {
+		return map[string]string{
+			"xl/workbook.xml": `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+				`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+			"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+			"xl/worksheets/sheet1.xml":   `<worksheet><sheetData>` + rows + `</sheetData></worksheet>`,
+		}
+	}
+	read := func(files map[string]string, limits XLSXLimits) error {
+		buf := buildXLSX(t, files)
+		_, err := ReadXLSX(bytes.NewReader(buf.Bytes()), int64(buf.Len()), limits)
+		return err
+	}
+
+	// 超出 XFD 或溢出的列号在补齐空单元格之前拒绝
+	err := read(sheet(`<row><c r="ZZZZZZZZZZ1"><v>1</v></c></row>`), XLSXLimits{})
+	assert.ErrorIs(t, err, ErrInvalidXLSX)
+	err = read(sheet(`<row><c r="XFE1"><v>1</v></c></row>`), XLSXLimits{})
+	assert.ErrorIs(t, err, ErrInvalidXLSX)
+	assert.NoError(t, read(sheet(`<row><c r="XFD1"><v>1</v></c></row>`), XLSXLimits{}))
+	err = read(sheet(`<row><c r="XFD1"><v>1</v></c></row>`), XLSXLimits{MaxCells: 100})
+	assert.ErrorIs(t, err, ErrXLSXTooLarge)
+
+	rows := strings.Repeat(`<row><c r="A1"><v>1</v></c></row>`, 3)
+	assert.NoError(t, read(sheet(rows), XLSXLimits{MaxRows: 3}))
+	assert.ErrorIs(t, read(sheet(rows), XLSXLimits{MaxRows: 2}), ErrXLSXTooLarge)
+
+	// 高压缩率的大文件按解压后的大小限制
+	large := sheet(`<row><c r="A1"><v>` + strings.Repeat("0", 1<<20) + `</v></c></row>`)
+	assert.ErrorIs(t, read(large, XLSXLimits{MaxPartSize: 64 << 10}), ErrXLSXTooLarge)
+	assert.NoError(t, read(large, XLSXLimits{MaxPartSize: 2 << 20}))
+}
+
+func TestReadXLSX_Invalid(t *testing.T) {
+	_, err := ReadXLSX(bytes.NewReader([]byte("not a zip")), 9, XLSXLimits{})
+	assert.ErrorIs(t, err, ErrInvalidXLSX)
+}
+
+func TestXLSXColumnName(t *testing.T) {
+	assert.Equal(t, "A", XLSXColumnName(0))
+	assert.Equal(t, "Z", XLSXColumnName(25))
+	assert.Equal(t, "AA", XLSXColumnName(26))
+	index, ok := xlsxColumnIndex("AB12")
+	assert.True(t, ok)
+	assert.Equal(t, 27, index)
+}
+
+// buildXLSX 把 files 打包为 zip
+func buildXLSX(t *testing.T, files map[string]string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		fw, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = io.WriteString(fw, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return &buf
+}